@@ -1,15 +1,28 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
 	"github.com/aifoundry-org/turtlenekko/internal/config"
+	"github.com/aifoundry-org/turtlenekko/internal/driver"
 	"github.com/aifoundry-org/turtlenekko/internal/formatter"
+	"github.com/aifoundry-org/turtlenekko/internal/server"
+	"github.com/aifoundry-org/turtlenekko/internal/terminal"
+	"github.com/aifoundry-org/turtlenekko/internal/thresholds"
+	"github.com/aifoundry-org/turtlenekko/internal/types"
 	"github.com/spf13/cobra"
 )
 
@@ -19,6 +32,13 @@ var (
 	BuildTime = "unknown"
 )
 
+// versionInfo is the output of version --json
+type versionInfo struct {
+	Version   string `json:"version"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
 // parseLogLevel converts a string log level to slog.Level
 func parseLogLevel(level string) slog.Level {
 	switch strings.ToLower(level) {
@@ -36,25 +56,198 @@ func parseLogLevel(level string) slog.Level {
 }
 
 // setupLogger configures the global slog logger
-func setupLogger(level string, output io.Writer) {
+func setupLogger(level string, format string, output io.Writer) {
 	logLevel := parseLogLevel(level)
 
-	handler := slog.NewTextHandler(output, &slog.HandlerOptions{
+	options := &slog.HandlerOptions{
 		Level: logLevel,
-	})
+	}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "json":
+		handler = slog.NewJSONHandler(output, options)
+	default:
+		handler = slog.NewTextHandler(output, options)
+	}
 
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
 
-	slog.Debug("Logger initialized", "level", level)
+	slog.Debug("Logger initialized", "level", level, "format", format)
+}
+
+// createOutputFile creates a file for writing results, creating parent directories as needed
+func createOutputFile(path string) (*os.File, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	return os.Create(path)
+}
+
+// configHasParam reports whether the matrix or any combination of cfg sets param
+func configHasParam(cfg *config.Config, param string) bool {
+	if _, ok := cfg.Matrix[param]; ok {
+		return true
+	}
+	for _, combination := range cfg.Combinations {
+		if _, ok := combination[param]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// replayResults fits and returns the results captured in a raw-csv file
+func replayResults(path string) ([]benchmark.MatrixResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return benchmark.ReplayRawCSV(file, nil)
+}
+
+// pushInflux writes line protocol points to an InfluxDB write endpoint. The
+// INFLUX_TOKEN environment variable, if set, is sent as the API token.
+func pushInflux(url string, body []byte) error {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if token := os.Getenv("INFLUX_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// notify posts the summary of a matrix run to a webhook. Failures are only logged, as
+// the results are written regardless.
+func notify(url string, format string, notification formatter.Notification) {
+	var body bytes.Buffer
+	if err := formatter.FormatNotification(&body, notification, format); err != nil {
+		slog.Error("Error formatting notification", "error", err)
+		return
+	}
+	req, err := http.NewRequest("POST", url, &body)
+	if err != nil {
+		slog.Error("Error sending notification", "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		slog.Error("Error sending notification", "error", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		slog.Error("Error sending notification", "status_code", resp.StatusCode, "response", strings.TrimSpace(string(message)))
+		return
+	}
+	slog.Info("Notification has been sent", "status", notification.Status)
+}
+
+// thresholdFailures describes the failed threshold checks, e.g. for a notification
+func thresholdFailures(matrixResults []benchmark.MatrixResult, limits map[string]types.Threshold) []string {
+	results, err := thresholds.Check(matrixResults, limits)
+	if err != nil {
+		return []string{fmt.Sprintf("error checking thresholds: %v", err)}
+	}
+	var failures []string
+	for _, result := range results {
+		if !result.Passed {
+			failures = append(failures, result.String())
+		}
+	}
+	return failures
+}
+
+// checkThresholds evaluates the configured thresholds, prints each check to stderr and
+// reports whether all of them passed
+func checkThresholds(matrixResults []benchmark.MatrixResult, limits map[string]types.Threshold) bool {
+	results, err := thresholds.Check(matrixResults, limits)
+	if err != nil {
+		slog.Error("Error checking thresholds", "error", err)
+		return false
+	}
+
+	failed := 0
+	fmt.Fprintln(os.Stderr, terminal.BoldText("Threshold checks:"))
+	for _, result := range results {
+		status := terminal.GreenText("PASS")
+		if !result.Passed {
+			status = terminal.RedText("FAIL")
+			failed++
+		}
+		fmt.Fprintf(os.Stderr, "  %s  %s\n", status, result)
+	}
+
+	if failed > 0 {
+		slog.Error("Threshold checks failed", "failed", failed, "total", len(results))
+		return false
+	}
+	slog.Info("All threshold checks passed", "total", len(results))
+	return true
 }
 
 func main() {
-	var configPath string
+	var configPaths []string
 	var resultsLogPath string
 	var outputFormat string
 	var logLevel string
+	var logFormat string
+	var quiet bool
 	var showLocalScore bool
+	var failOnError bool
+	var reportPath string
+	var summaryPath string
+	var outputPath string
+	var streamOutputPath string
+	var tui bool
+	var seed int
+	var maxIterations int
+	var minRSquared float64
+	var aggregation string
+	var noWarmup bool
+	var noClamp bool
+	var warmupPromptLength int
+	var warmupMaxTokens int
+	var onlyFilters []string
+	var skipFilters []string
+	var influxURL string
+	var endpointPath string
+	var systemInfo bool
+	var extraBody string
+	var shuffle string
+	var captureDir string
+	var maxCombinations int
+	var notifyURL string
+	var selectBest string
+	var notifyFormat string
+	var pivot string
+	var replayPath string
+	var precision int
+	var raw bool
 
 	rootCmd := &cobra.Command{
 		Use:   "turtlenekko",
@@ -91,18 +284,51 @@ func main() {
 		Use:   "benchmark",
 		Short: "Run a benchmark against an LLM",
 		Run: func(cmd *cobra.Command, args []string) {
-			// Load configuration from YAML file
-			cfg, err := config.Load(configPath)
-			if err != nil {
-				if os.IsNotExist(err) {
-					slog.Info("You can create a new config file with the example above")
-					slog.Info("Or run: turtlenekko init to create a default config file")
+			// Load configuration from YAML file; replayed results need none
+			cfg := &config.Config{}
+			if replayPath == "" {
+				loaded, err := config.LoadFiles(configPaths)
+				if err != nil {
+					if os.IsNotExist(err) {
+						slog.Info("You can create a new config file with the example above")
+						slog.Info("Or run: turtlenekko init to create a default config file")
+						os.Exit(1)
+					}
+					slog.Error("Error loading configuration", "error", err)
 					os.Exit(1)
 				}
-				slog.Error("Error loading configuration", "error", err)
+				cfg = loaded
+			}
+			if err := thresholds.Validate(cfg.Thresholds); err != nil {
+				slog.Error("Invalid thresholds", "error", err)
+				os.Exit(1)
+			}
+			if notifyFormat != formatter.NotifyFormatJSON && notifyFormat != formatter.NotifyFormatSlack {
+				slog.Error("Invalid --notify-format, must be json or slack", "format", notifyFormat)
+				os.Exit(1)
+			}
+			if selectBest != "" {
+				metric, _ := formatter.ParseSelectMetric(selectBest)
+				if !slices.Contains(thresholds.Metrics(), metric) {
+					slog.Error("Unknown --select-best metric", "metric", metric, "available", strings.Join(thresholds.Metrics(), ", "))
+					os.Exit(1)
+				}
+			}
+			if pivot != "" && replayPath == "" && !configHasParam(cfg, pivot) {
+				slog.Error("Unknown --pivot parameter, it must be set by the matrix or combinations", "param", pivot)
 				os.Exit(1)
 			}
 
+			// Rounding of the numbers in the JSON, CSV and InfluxDB output
+			if precision < 0 {
+				slog.Error("Precision must not be negative, use --raw for unrounded numbers", "precision", precision)
+				os.Exit(1)
+			}
+			formatOpts := formatter.Options{ShowLocalScore: showLocalScore, Precision: precision, Version: Version}
+			if raw {
+				formatOpts.Precision = formatter.RawPrecision
+			}
+
 			// Create results log file
 			resultsFile, err := os.Create(resultsLogPath)
 			if err != nil {
@@ -111,60 +337,678 @@ func main() {
 			}
 			defer resultsFile.Close()
 
+			// Write formatted results to a file instead of stdout if requested
+			var output io.Writer = os.Stdout
+			if outputPath != "" {
+				outputFile, err := createOutputFile(outputPath)
+				if err != nil {
+					slog.Error("Error creating output file", "error", err, "path", outputPath)
+					os.Exit(1)
+				}
+				defer outputFile.Close()
+				output = outputFile
+
+				// No terminal colors in files
+				terminal.SetColor(false)
+			}
+
+			// Parse combination filters
+			var filter benchmark.CombinationFilter
+			for _, expr := range onlyFilters {
+				f, err := benchmark.ParseFilter(expr)
+				if err != nil {
+					slog.Error("Invalid --only filter", "error", err)
+					os.Exit(1)
+				}
+				filter.Only = append(filter.Only, f)
+			}
+			for _, expr := range skipFilters {
+				f, err := benchmark.ParseFilter(expr)
+				if err != nil {
+					slog.Error("Invalid --skip filter", "error", err)
+					os.Exit(1)
+				}
+				filter.Skip = append(filter.Skip, f)
+			}
+
+			// Parameters set on the command line apply to every combination
+			// unless the matrix sets them itself
+			baseParams := make(map[string]interface{})
+			if cmd.Flags().Changed("seed") {
+				baseParams["seed"] = seed
+				baseParams["completion_seed"] = seed
+			}
+			if cmd.Flags().Changed("max-iterations") {
+				baseParams["max_iterations"] = maxIterations
+			}
+			if cmd.Flags().Changed("min-r-squared") {
+				baseParams["min_r_squared"] = minRSquared
+			}
+			if cmd.Flags().Changed("aggregation") {
+				baseParams["aggregation"] = aggregation
+			}
+			// So does the warmup section of the configuration
+			if cfg.Warmup != nil {
+				for k, v := range cfg.Warmup.Params() {
+					baseParams[k] = v
+				}
+			}
+			if noWarmup {
+				baseParams["warmup"] = false
+			}
+			if noClamp {
+				baseParams["clamp_rates"] = false
+			}
+			if cmd.Flags().Changed("warmup-prompt-length") {
+				baseParams["warmup_prompt_length"] = warmupPromptLength
+			}
+			if cmd.Flags().Changed("warmup-max-tokens") {
+				baseParams["warmup_max_tokens"] = warmupMaxTokens
+			}
+			if cmd.Flags().Changed("endpoint-path") {
+				baseParams["endpoint_path"] = endpointPath
+			}
+			if systemInfo {
+				baseParams["system_info"] = true
+			}
+			if extraBody != "" {
+				baseParams["extra_body"] = extraBody
+			}
+			if shuffle != "" {
+				baseParams["shuffle"] = shuffle
+			}
+			if captureDir != "" {
+				baseParams["capture_dir"] = captureDir
+			}
+			if cmd.Flags().Changed("max-combinations") {
+				baseParams["max_combinations"] = maxCombinations
+			}
+
+			// Functions called with the result of each combination as soon as it completes
+			var resultHandlers []func(benchmark.MatrixResult)
+			var handlers benchmark.Handlers
+
+			// Show a progress line below the logs on interactive terminals, or the live
+			// dashboard if requested
+			var status *terminal.StatusLine
+			var dash *dashboard
+			if tui && !terminal.IsTerminal(os.Stderr) {
+				slog.Warn("The dashboard needs a terminal, ignoring --tui")
+				tui = false
+			}
+			if tui {
+				dash = newDashboard(os.Stderr, terminal.Width(os.Stderr), formatOpts)
+				// Only warnings and errors scroll above the dashboard
+				if parseLogLevel(logLevel) < slog.LevelWarn {
+					setupLogger("warn", logFormat, dash)
+				} else {
+					setupLogger(logLevel, logFormat, dash)
+				}
+				handlers.Progress = dash.setProgress
+				handlers.Request = dash.addRequest
+				resultHandlers = append(resultHandlers, dash.addResult)
+			} else if !quiet && terminal.IsTerminal(os.Stderr) {
+				status = terminal.NewStatusLine(os.Stderr)
+				setupLogger(logLevel, logFormat, status)
+				handlers.Progress = func(p benchmark.Progress) {
+					status.Set(p.String())
+				}
+			}
+
+			// Stream each result as a JSON line as soon as its combination completes
+			if streamOutputPath != "" {
+				var stream io.Writer = os.Stdout
+				if streamOutputPath != "-" {
+					streamFile, err := createOutputFile(streamOutputPath)
+					if err != nil {
+						slog.Error("Error creating stream output file", "error", err, "path", streamOutputPath)
+						os.Exit(1)
+					}
+					defer streamFile.Close()
+					stream = streamFile
+				} else if outputPath == "" {
+					// Keep stdout valid JSON lines, the formatted results are only written with --output
+					output = io.Discard
+				}
+				resultHandlers = append(resultHandlers, func(matrixResult benchmark.MatrixResult) {
+					if err := formatter.FormatJSONLine(stream, matrixResult, formatOpts); err != nil {
+						slog.Error("Error streaming result", "error", err, "path", streamOutputPath)
+					}
+				})
+			}
+			handlers.Result = func(matrixResult benchmark.MatrixResult) {
+				for _, handler := range resultHandlers {
+					handler(matrixResult)
+				}
+			}
+
 			// Run matrix benchmarks
-			matrixResults, err := benchmark.RunMatrix(cfg.Driver, nil, cfg.Matrix)
+			runStart := time.Now()
+			var matrixResults []benchmark.MatrixResult
+			if replayPath != "" {
+				matrixResults, err = replayResults(replayPath)
+				if err != nil {
+					slog.Error("Error replaying results", "error", err, "path", replayPath)
+					os.Exit(1)
+				}
+				for _, matrixResult := range matrixResults {
+					for _, handler := range resultHandlers {
+						handler(matrixResult)
+					}
+				}
+			} else {
+				matrixResults, err = benchmark.RunMatrix(cfg.Driver, baseParams, cfg.Matrix, cfg.Combinations, filter, nil, handlers)
+			}
+			if status != nil {
+				status.Clear()
+				setupLogger(logLevel, logFormat, os.Stderr)
+			}
+			if dash != nil {
+				// Leave the final table on the terminal
+				dash.stop()
+				setupLogger(logLevel, logFormat, os.Stderr)
+			}
+			if err == nil && len(matrixResults) == 0 {
+				// E.g. a replayed file without results, which would leave nothing to report
+				err = fmt.Errorf("no matrix combinations were run")
+			}
 			if err != nil {
 				slog.Error("Matrix benchmark failed", "error", err)
 				fmt.Fprintf(resultsFile, "Matrix benchmark failed: %v\n", err)
+				if notifyURL != "" {
+					notify(notifyURL, notifyFormat, formatter.NewNotification(matrixResults, formatOpts, time.Since(runStart), err, nil))
+				}
 				os.Exit(1)
 			}
 
 			// Format and print results based on the selected format
-			switch outputFormat {
-			case "json":
-				if err := formatter.FormatJSON(matrixResults, showLocalScore); err != nil {
-					slog.Error("Error formatting JSON", "error", err)
-				}
-			case "text":
-				formatter.FormatText(matrixResults, showLocalScore)
-			case "csv":
-				formatter.FormatCSV(matrixResults, showLocalScore)
+			var formatErr error
+			formatResults := matrixResults
+			var best *formatter.Best
+			if selectBest != "" {
+				best, formatErr = formatter.SelectBest(matrixResults, selectBest)
+				if formatErr == nil {
+					slog.Info("Selected the best combination", "combination", best.Index+1, "metric", best.Metric, "value", best.Value)
+					formatResults = matrixResults[best.Index : best.Index+1]
+				}
+			}
+			if pivot != "" && outputFormat != "text" && outputFormat != "csv" {
+				slog.Warn("--pivot only applies to the text and csv formats, ignoring it", "format", outputFormat)
+			}
+			switch {
+			case formatErr != nil:
+				// No combination could be selected, there is nothing to write
+			case best != nil && outputFormat == "text":
+				formatErr = formatter.FormatBest(output, matrixResults, best, formatOpts)
+			case outputFormat == "json":
+				formatErr = formatter.FormatJSON(output, formatResults, formatOpts)
+			case outputFormat == "text":
+				if pivot != "" {
+					formatErr = formatter.FormatPivot(output, formatResults, pivot, formatOpts)
+				} else {
+					formatErr = formatter.FormatText(output, formatResults, formatOpts)
+				}
+			case outputFormat == "csv":
+				if pivot != "" {
+					formatErr = formatter.FormatPivotCSV(output, formatResults, pivot, formatOpts)
+				} else {
+					formatErr = formatter.FormatCSV(output, formatResults, formatOpts)
+				}
+			case outputFormat == "influx":
+				formatErr = formatter.FormatInflux(output, formatResults, formatOpts, runStart)
+			case outputFormat == "raw-csv":
+				formatErr = formatter.FormatRawCSV(output, formatResults)
+			case outputFormat == "sweep-csv":
+				formatErr = formatter.FormatSweepCSV(output, formatResults)
 			default:
 				slog.Warn("Unknown format, using text format", "format", outputFormat)
-				formatter.FormatText(matrixResults, showLocalScore)
+				formatErr = formatter.FormatText(output, formatResults, formatOpts)
+			}
+			if formatErr != nil {
+				slog.Error("Error writing results", "error", formatErr, "format", outputFormat)
+			}
+
+			if outputPath != "" {
+				slog.Info("Results have been written", "path", outputPath, "format", outputFormat)
 			}
 
 			// Always write detailed results to the log file
-			formatter.WriteToFile(resultsFile, matrixResults, showLocalScore)
+			if err := formatter.WriteToFile(resultsFile, matrixResults, formatOpts); err != nil {
+				slog.Error("Error writing results log file", "error", err, "path", resultsLogPath)
+			} else {
+				slog.Info("Results have been saved", "path", resultsLogPath)
+			}
 
-			slog.Info("Results have been saved", "path", resultsLogPath)
+			// Push the results to InfluxDB if requested
+			if influxURL != "" {
+				var points bytes.Buffer
+				if err := formatter.FormatInflux(&points, matrixResults, formatOpts, runStart); err != nil {
+					slog.Error("Error formatting results for InfluxDB", "error", err)
+					formatErr = err
+				} else if err := pushInflux(influxURL, points.Bytes()); err != nil {
+					slog.Error("Error pushing results to InfluxDB", "error", err)
+				} else {
+					slog.Info("Results have been pushed to InfluxDB")
+				}
+			}
+
+			// Write HTML report if requested
+			if reportPath != "" {
+				reportFile, err := os.Create(reportPath)
+				if err != nil {
+					slog.Error("Error creating report file", "error", err, "path", reportPath)
+				} else {
+					if err := formatter.WriteHTMLReport(reportFile, matrixResults, formatOpts); err != nil {
+						slog.Error("Error writing HTML report", "error", err, "path", reportPath)
+					} else {
+						slog.Info("HTML report has been saved", "path", reportPath)
+					}
+					reportFile.Close()
+				}
+			}
+
+			// Append a Markdown summary if requested, e.g. to $GITHUB_STEP_SUMMARY
+			if summaryPath != "" {
+				summaryFile, err := os.OpenFile(summaryPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+				if err != nil {
+					slog.Error("Error opening summary file", "error", err, "path", summaryPath)
+				} else {
+					if err := formatter.WriteMarkdownSummary(summaryFile, matrixResults, formatOpts); err != nil {
+						slog.Error("Error writing summary", "error", err, "path", summaryPath)
+					} else {
+						slog.Info("Summary has been written", "path", summaryPath)
+					}
+					summaryFile.Close()
+				}
+			}
+
+			// Tell the webhook the run is over, before any non-zero exit below
+			if notifyURL != "" {
+				var failures []string
+				if len(cfg.Thresholds) > 0 {
+					failures = thresholdFailures(matrixResults, cfg.Thresholds)
+				}
+				notify(notifyURL, notifyFormat, formatter.NewNotification(matrixResults, formatOpts, time.Since(runStart), nil, failures))
+			}
+
+			// Exit non-zero if the results could not be written
+			if formatErr != nil {
+				os.Exit(1)
+			}
+
+			// Exit non-zero if every combination failed (or any, in strict mode)
+			failedCount := 0
+			for _, matrixResult := range matrixResults {
+				if matrixResult.Error != nil {
+					failedCount++
+				}
+			}
+			if len(matrixResults) > 0 && failedCount == len(matrixResults) {
+				slog.Error("All matrix combinations failed", "failed", failedCount)
+				os.Exit(1)
+			}
+			if failOnError && failedCount > 0 {
+				slog.Error("Some matrix combinations failed", "failed", failedCount, "total", len(matrixResults))
+				os.Exit(1)
+			}
+
+			// Exit non-zero if any threshold check failed
+			if len(cfg.Thresholds) > 0 && !checkThresholds(matrixResults, cfg.Thresholds) {
+				os.Exit(1)
+			}
 		},
 	}
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVarP(&logLevel, "log-level", "l", "info", "Log level (debug, info, warn, error)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format (text, json)")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Suppress progress logging, only print results and errors")
 
 	// Benchmark command flags
-	benchmarkCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to configuration file")
+	benchmarkCmd.Flags().StringArrayVarP(&configPaths, "config", "c", []string{"config.yaml"}, "Path to configuration file (repeatable, later files override earlier ones)")
 	benchmarkCmd.Flags().StringVarP(&resultsLogPath, "results", "r", "results.log", "Path to results log file")
-	benchmarkCmd.Flags().StringVarP(&outputFormat, "format", "f", "json", "Output format (csv, text, json)")
+	benchmarkCmd.Flags().StringVarP(&outputFormat, "format", "f", "json", "Output format (csv, text, json, influx, raw-csv, sweep-csv)")
+	benchmarkCmd.Flags().IntVar(&precision, "precision", formatter.DefaultPrecision, "Decimals of the numbers in the json, csv and influx output")
+	benchmarkCmd.Flags().BoolVar(&raw, "raw", false, "Don't round the numbers in the json, csv and influx output")
+	benchmarkCmd.Flags().StringVarP(&outputPath, "output", "o", "", "Write formatted results to this file instead of stdout")
+	benchmarkCmd.Flags().StringVar(&streamOutputPath, "stream-output", "", "Write each result as a JSON line as soon as its combination completes, to this file or stdout if no file is given")
+	benchmarkCmd.Flags().Lookup("stream-output").NoOptDefVal = "-"
+	benchmarkCmd.Flags().BoolVar(&tui, "tui", false, "Show a live dashboard of the combinations instead of the progress logs")
 	benchmarkCmd.Flags().BoolVar(&showLocalScore, "localscore", true, "Include estimated LocalScore in output")
+	benchmarkCmd.Flags().StringVar(&reportPath, "report", "", "Path to write a self-contained HTML report with charts")
+	benchmarkCmd.Flags().StringVar(&summaryPath, "summary-file", "", "Append a Markdown summary of the results to this file, e.g. $GITHUB_STEP_SUMMARY")
+	benchmarkCmd.Flags().StringVar(&influxURL, "influx-url", "", "Push results as line protocol to this InfluxDB write URL (token from INFLUX_TOKEN)")
+	benchmarkCmd.Flags().StringArrayVar(&onlyFilters, "only", nil, "Only run combinations matching key=value[,key2=value2] (repeatable)")
+	benchmarkCmd.Flags().StringArrayVar(&skipFilters, "skip", nil, "Skip combinations matching key=value[,key2=value2] (repeatable)")
+	benchmarkCmd.Flags().IntVar(&seed, "seed", 0, "Seed for prompt generation and completion sampling, for reproducible runs")
+	benchmarkCmd.Flags().IntVar(&maxIterations, "max-iterations", benchmark.MaxBenchmarkIterations, "Maximum refinement iterations per context (max_iterations parameter)")
+	benchmarkCmd.Flags().Float64Var(&minRSquared, "min-r-squared", benchmark.MinAcceptableRSquared, "Adjusted R² at which a context stops early (min_r_squared parameter)")
+	benchmarkCmd.Flags().StringVar(&aggregation, "aggregation", benchmark.AggregationBest, "How repeated measurements of the same token counts are combined: best, median or mean (aggregation parameter)")
+	benchmarkCmd.Flags().BoolVar(&noClamp, "no-clamp", false, "Report the raw fitted rates instead of raising implausibly low ones to a floor (clamp_rates parameter)")
+	benchmarkCmd.Flags().BoolVar(&noWarmup, "no-warmup", false, "Skip the warmup request before the measurements (warmup parameter)")
+	benchmarkCmd.Flags().IntVar(&warmupPromptLength, "warmup-prompt-length", benchmark.DefaultWarmupPromptLength, "Prompt length of the warmup request in characters (warmup_prompt_length parameter)")
+	benchmarkCmd.Flags().IntVar(&warmupMaxTokens, "warmup-max-tokens", benchmark.DefaultWarmupMaxTokens, "Completion tokens of the warmup request (warmup_max_tokens parameter)")
+	benchmarkCmd.Flags().StringVar(&endpointPath, "endpoint-path", "", "Path of the chat endpoint joined to base_url or the driver URL's host, e.g. /api/v1/chat/completions (endpoint_path parameter)")
+	benchmarkCmd.Flags().BoolVar(&systemInfo, "system-info", false, "Record the hostname, OS, CPU and GPUs of this machine with the results (system_info parameter)")
+	benchmarkCmd.Flags().StringVar(&extraBody, "extra-body", "", "JSON object of extra fields added to every request body, e.g. '{\"repeat_penalty\": 1.1}' (extra_body parameter)")
+	benchmarkCmd.Flags().StringVar(&shuffle, "shuffle", "", "Run the combinations in a random order, optionally with a seed (--shuffle=42); results are still reported in matrix order (shuffle parameter)")
+	benchmarkCmd.Flags().Lookup("shuffle").NoOptDefVal = "true"
+	benchmarkCmd.Flags().StringVar(&selectBest, "select-best", "", "Only output the combination with the highest value of this metric (a JSON output key, e.g. localscore_estimate), or the lowest with a leading -")
+	benchmarkCmd.Flags().StringVar(&notifyURL, "notify", "", "POST a summary of the run to this webhook URL when the matrix finishes or fails")
+	benchmarkCmd.Flags().StringVar(&notifyFormat, "notify-format", formatter.NotifyFormatJSON, "Payload of --notify: json (summary and results) or slack (a Slack incoming webhook message)")
+	benchmarkCmd.Flags().IntVar(&maxCombinations, "max-combinations", benchmark.DefaultMaxCombinations, "Refuse to run matrices with more combinations than this, after --only and --skip; 0 for no limit (max_combinations parameter)")
+	benchmarkCmd.Flags().StringVar(&captureDir, "capture-dir", "", "Write each request and response body with its timing to a file in this directory, for debugging (capture_dir parameter)")
+	benchmarkCmd.Flags().StringVar(&pivot, "pivot", "", "With the text or csv format, compare the combinations in a table per group with a row per value of this parameter, e.g. ctx_size")
+	benchmarkCmd.Flags().StringVar(&replayPath, "replay", "", "Fit and format results captured with -f raw-csv instead of running the benchmark")
+	benchmarkCmd.Flags().BoolVar(&failOnError, "fail-on-error", false, "Exit with non-zero status if any matrix combination fails")
+
+	var printSchema bool
+
+	validateCmd := &cobra.Command{
+		Use:   "validate [config...]",
+		Short: "Validate configuration files without running them",
+		Long: "Validate configuration files without running them. Several files are validated\n" +
+			"as they would be merged by benchmark -c file1 -c file2.",
+		Run: func(cmd *cobra.Command, args []string) {
+			if printSchema {
+				fmt.Println(config.Schema)
+				return
+			}
+
+			configFiles := args
+			if len(configFiles) == 0 {
+				configFiles = []string{"config.yaml"}
+			}
+
+			validationErrors, err := config.ValidateFiles(configFiles)
+			if err != nil {
+				slog.Error("Error loading configuration", "error", err)
+				os.Exit(1)
+			}
+
+			if len(validationErrors) > 0 {
+				for _, validationError := range validationErrors {
+					if validationError.Line > 0 {
+						fmt.Printf("%s:%d: %s\n", validationError.File, validationError.Line, validationError.Message)
+					} else {
+						fmt.Printf("%s: %s\n", validationError.File, validationError.Message)
+					}
+				}
+				os.Exit(1)
+			}
+
+			fmt.Printf("%s: configuration is valid\n", strings.Join(configFiles, ", "))
+		},
+	}
+
+	validateCmd.Flags().BoolVar(&printSchema, "schema", false, "Print the JSON schema of the configuration file")
+
+	doctorCmd := &cobra.Command{
+		Use:   "doctor [config...]",
+		Short: "Check the prerequisites of a configuration without running it",
+		Long: "Check the prerequisites of every combination without running the benchmark: the\n" +
+			"configuration is valid, the driver's binaries and files exist, the ports it starts servers\n" +
+			"on are free, already running servers are reachable and the programs of command parameters\n" +
+			"are installed. Exits with a non-zero status if any check fails.",
+		Run: func(cmd *cobra.Command, args []string) {
+			configFiles := args
+			if len(configFiles) == 0 {
+				configFiles = []string{"config.yaml"}
+			}
+
+			failed := false
+			validationErrors, err := config.ValidateFiles(configFiles)
+			if err != nil {
+				slog.Error("Error loading configuration", "error", err)
+				os.Exit(1)
+			}
+			for _, validationError := range validationErrors {
+				location := validationError.File
+				if validationError.Line > 0 {
+					location = fmt.Sprintf("%s:%d", validationError.File, validationError.Line)
+				}
+				fmt.Printf("%s configuration: %s: %s\n", terminal.RedText("FAIL"), location, validationError.Message)
+				failed = true
+			}
+			if failed {
+				os.Exit(1)
+			}
+			fmt.Printf("%s configuration: %s\n", terminal.GreenText("PASS"), strings.Join(configFiles, ", "))
+
+			cfg, err := config.LoadFiles(configFiles)
+			if err != nil {
+				slog.Error("Error loading configuration", "error", err)
+				os.Exit(1)
+			}
+			checks, err := benchmark.Doctor(cfg.Driver, nil, cfg.Matrix, cfg.Combinations)
+			if err != nil {
+				slog.Error("Error checking prerequisites", "error", err)
+				os.Exit(1)
+			}
+
+			if len(checks) == 0 {
+				fmt.Printf("No prerequisites to check for the %s driver\n", cfg.Driver)
+			}
+			for _, check := range checks {
+				if check.Err == nil {
+					fmt.Printf("%s %s: %s\n", terminal.GreenText("PASS"), check.Name, check.Detail)
+					continue
+				}
+				failed = true
+				fmt.Printf("%s %s: %v\n", terminal.RedText("FAIL"), check.Name, check.Err)
+				if params := formatDashboardParams(check.Params[0], nil); params != "" {
+					if len(check.Params) > 1 {
+						params += fmt.Sprintf(" (+%d more)", len(check.Params)-1)
+					}
+					fmt.Printf("     for %s\n", params)
+				}
+			}
+
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+
+	var plotOut string
+
+	plotCmd := &cobra.Command{
+		Use:   "plot <results>",
+		Short: "Chart saved results as SVG without running the benchmark",
+		Long: "Chart the tokens/sec of each combination of results saved with -f json or --stream-output\n" +
+			"as an SVG. Results captured with -f raw-csv (a .csv file) are fitted again, and the\n" +
+			"measured against the predicted response time of every request is charted as well.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			path := args[0]
+			file, err := os.Open(path)
+			if err != nil {
+				slog.Error("Error opening results", "error", err, "path", path)
+				os.Exit(1)
+			}
+			defer file.Close()
+
+			var svg bytes.Buffer
+			if strings.EqualFold(filepath.Ext(path), ".csv") {
+				var matrixResults []benchmark.MatrixResult
+				matrixResults, err = benchmark.ReplayRawCSV(file, nil)
+				if err == nil {
+					err = formatter.WriteRawPlot(&svg, matrixResults)
+				}
+			} else {
+				var results []formatter.JsonResult
+				results, err = formatter.ReadJSONResults(file)
+				if version := formatter.NewerSchemaVersion(results); version > 0 {
+					slog.Warn("Results were written by a newer turtlenekko, fields it added are ignored",
+						"schema_version", version, "supported_schema_version", formatter.SchemaVersion)
+				}
+				if err == nil {
+					err = formatter.WritePlot(&svg, results)
+				}
+			}
+			if err != nil {
+				slog.Error("Error plotting results", "error", err, "path", path)
+				os.Exit(1)
+			}
+
+			if plotOut == "" {
+				os.Stdout.Write(svg.Bytes())
+				return
+			}
+			outFile, err := createOutputFile(plotOut)
+			if err == nil {
+				_, err = outFile.Write(svg.Bytes())
+				if closeErr := outFile.Close(); err == nil {
+					err = closeErr
+				}
+			}
+			if err != nil {
+				slog.Error("Error writing plot", "error", err, "path", plotOut)
+				os.Exit(1)
+			}
+			slog.Info("Plot has been written", "path", plotOut)
+		},
+	}
+
+	plotCmd.Flags().StringVarP(&plotOut, "out", "o", "", "Write the SVG to this file instead of stdout")
+
+	var serveAddr string
+	var serveMaxCombinations int
+	var serveAllowCommands bool
+
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve an HTTP API for running benchmarks",
+		Long: "Serve an HTTP API for running benchmarks: POST /benchmark with a configuration body runs\n" +
+			"the matrix and returns the JSON results, GET /health returns ok. Only one benchmark runs at a time.\n" +
+			"The API has no authentication: configurations running commands (the local_cmd and ssh drivers,\n" +
+			"launch_cmd, binary_path, extra_args, *_cmd parameters, capture_dir) or reading local files\n" +
+			"(a prompt_corpus that isn't built in) are rejected unless --allow-commands is given, and the\n" +
+			"server only listens on localhost by default.",
+		Run: func(cmd *cobra.Command, args []string) {
+			srv := server.New()
+			srv.MaxCombinations = serveMaxCombinations
+			srv.AllowCommands = serveAllowCommands
+			srv.Version = Version
+			if err := srv.ListenAndServe(serveAddr); err != nil {
+				slog.Error("Server failed", "error", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	serveCmd.Flags().StringVar(&serveAddr, "addr", server.DefaultAddr, "Address to listen on")
+	serveCmd.Flags().IntVar(&serveMaxCombinations, "max-combinations", benchmark.DefaultMaxCombinations, "Reject matrices with more combinations than this; 0 for no limit")
+	serveCmd.Flags().BoolVar(&serveAllowCommands, "allow-commands", false, "Accept configurations that run commands; anyone who can reach the server can then run commands as its user")
+
+	driversCmd := &cobra.Command{
+		Use:   "drivers [driver]",
+		Short: "List the available drivers and the parameters they consume",
+		Run: func(cmd *cobra.Command, args []string) {
+			found := false
+			for _, info := range driver.Drivers() {
+				if len(args) > 0 && args[0] != info.Name {
+					continue
+				}
+				found = true
+
+				fmt.Printf("%s - %s\n", terminal.BoldText(info.Name), info.Description)
+				tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+				for _, param := range info.Params {
+					description := param.Description
+					if param.Required {
+						description += " (required)"
+					} else if param.Default != "" {
+						description += fmt.Sprintf(" (default: %s)", param.Default)
+					}
+					fmt.Fprintf(tw, "  %s\t%s\n", param.Name, description)
+				}
+				tw.Flush()
+				fmt.Println()
+			}
+
+			if !found {
+				slog.Error("Unknown driver", "driver", args[0])
+				os.Exit(1)
+			}
+		},
+	}
+
+	var quickModel, quickAPIKey, quickEndpointType, quickFormat string
+	var quickMaxTokens int
+
+	benchOnceCmd := &cobra.Command{
+		Use:   "bench-once <url>",
+		Short: "Quickly measure an endpoint with a single prompt",
+		Long: "Quickly measure a running endpoint without a configuration: a request with one completion\n" +
+			"token approximates the time to first token, and a second one with --max-tokens gives the\n" +
+			"completion speed. Takes seconds, e.g. to smoke-test an endpoint before a full benchmark.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			b := benchmark.NewBenchmark(args[0], quickModel, "", benchmark.DefaultRequestTimeout)
+			b.APIKey = quickAPIKey
+			b.EndpointType = quickEndpointType
+
+			result, err := b.RunQuick(benchmark.QuickPromptLength, quickMaxTokens, benchmark.DefaultPostfix)
+			if err != nil {
+				slog.Error("Quick measurement failed", "error", err, "url", args[0])
+				os.Exit(1)
+			}
+
+			if quickFormat == "json" {
+				err = formatter.FormatQuickJSON(os.Stdout, args[0], result)
+			} else {
+				err = formatter.FormatQuickText(os.Stdout, args[0], result)
+			}
+			if err != nil {
+				slog.Error("Error writing results", "error", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	benchOnceCmd.Flags().StringVar(&quickModel, "model", "", "Model name sent with the requests")
+	benchOnceCmd.Flags().StringVar(&quickAPIKey, "api-key", "", "API key sent with the requests")
+	benchOnceCmd.Flags().StringVar(&quickEndpointType, "endpoint-type", benchmark.EndpointTypeOpenAI, "API schema of the endpoint (openai, anthropic, azure)")
+	benchOnceCmd.Flags().IntVar(&quickMaxTokens, "max-tokens", benchmark.QuickMaxTokens, "Completion tokens of the second request")
+	benchOnceCmd.Flags().StringVarP(&quickFormat, "format", "f", "text", "Output format (text, json)")
+
+	var versionJSON bool
 
 	versionCmd := &cobra.Command{
 		Use:   "version",
 		Short: "Print the version information",
 		Run: func(cmd *cobra.Command, args []string) {
+			if versionJSON {
+				data, err := json.Marshal(versionInfo{Version: Version, BuildTime: BuildTime, GoVersion: runtime.Version()})
+				if err != nil {
+					slog.Error("Error encoding version", "error", err)
+					os.Exit(1)
+				}
+				fmt.Println(string(data))
+				return
+			}
 			fmt.Printf("Turtlenekko version %s (built at %s)\n", Version, BuildTime)
 		},
 	}
+	versionCmd.Flags().BoolVar(&versionJSON, "json", false, "Print the version information as JSON")
 
 	rootCmd.AddCommand(benchmarkCmd)
+	rootCmd.AddCommand(benchOnceCmd)
 	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(plotCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(driversCmd)
 	rootCmd.AddCommand(versionCmd)
 
 	// Initialize the logger before executing commands
 	cobra.OnInitialize(func() {
-		setupLogger(logLevel, os.Stderr)
+		if quiet {
+			logLevel = "error"
+		}
+		setupLogger(logLevel, logFormat, os.Stderr)
 	})
 
 	if err := rootCmd.Execute(); err != nil {
@@ -172,4 +1016,3 @@ func main() {
 		os.Exit(1)
 	}
 }
-
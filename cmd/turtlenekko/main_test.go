@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
+	"github.com/aifoundry-org/turtlenekko/internal/types"
+)
+
+func TestCheckThresholds(t *testing.T) {
+	score := func(s float64) *float64 { return &s }
+	limits := map[string]types.Threshold{"localscore_estimate": {Min: score(20)}}
+
+	// The benchmark command exits with status 1 if checkThresholds reports a failure
+	for _, tt := range []struct {
+		name          string
+		matrixResults []benchmark.MatrixResult
+		passed        bool
+	}{
+		{"all above the min", []benchmark.MatrixResult{{LocalScore: score(21)}, {LocalScore: score(30)}}, true},
+		{"one below the min", []benchmark.MatrixResult{{LocalScore: score(21)}, {LocalScore: score(19)}}, false},
+		{"metric not reported", []benchmark.MatrixResult{{LocalScore: score(21)}, {}}, false},
+		{"failed combination", []benchmark.MatrixResult{{LocalScore: score(21), Error: fmt.Errorf("setup failed")}}, false},
+	} {
+		if got := checkThresholds(tt.matrixResults, limits); got != tt.passed {
+			t.Errorf("%s: checkThresholds = %v, want %v", tt.name, got, tt.passed)
+		}
+		if failures := thresholdFailures(tt.matrixResults, limits); (len(failures) == 0) != tt.passed {
+			t.Errorf("%s: failures = %v", tt.name, failures)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
+	"github.com/aifoundry-org/turtlenekko/internal/formatter"
+	"github.com/aifoundry-org/turtlenekko/internal/terminal"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// dashboardResponseTimes is the number of recent response times in the sparkline
+const dashboardResponseTimes = 40
+
+// dashboardParamsWidth is the maximum width of the parameters column
+const dashboardParamsWidth = 40
+
+// dashboardRow is a completed combination in the dashboard table
+type dashboardRow struct {
+	params string
+	result formatter.JsonResult
+}
+
+// dashboardDoneMsg tells the dashboard that the run is over
+type dashboardDoneMsg struct{}
+
+// dashboard is the live view of a matrix run shown with --tui: a table of the completed
+// combinations, the running one and a sparkline of recent response times. It is a
+// Bubble Tea program drawn below the logs, which are written through the dashboard.
+type dashboard struct {
+	program *tea.Program
+	out     io.Writer
+	done    chan struct{} // closed when the program has exited
+
+	mu      sync.Mutex  // held while printing a log line, which blocks once the program exited
+	stopped atomic.Bool // the run is over, log lines are written to out
+}
+
+// newDashboard starts a dashboard drawn on out, a terminal of the given width. Interrupting
+// it, e.g. with ctrl+c, exits turtlenekko as it would without the dashboard.
+func newDashboard(out io.Writer, width int, formatOpts formatter.Options) *dashboard {
+	d := &dashboard{out: out, done: make(chan struct{})}
+	model := dashboardModel{formatOpts: formatOpts, width: width, live: true}
+	// Without input, stdin stays available for a configuration read from it
+	d.program = tea.NewProgram(model, tea.WithOutput(out), tea.WithInput(nil))
+
+	go func() {
+		defer close(d.done)
+		if _, err := d.program.Run(); err != nil {
+			fmt.Fprintf(out, "Dashboard failed: %v\n", err)
+		}
+		if !d.stopped.Load() {
+			os.Exit(130)
+		}
+	}()
+	return d
+}
+
+// setProgress updates the running combination
+func (d *dashboard) setProgress(p benchmark.Progress) {
+	d.program.Send(p)
+}
+
+// addRequest adds the response time of a request to the sparkline
+func (d *dashboard) addRequest(result benchmark.CompletionResult) {
+	d.program.Send(result)
+}
+
+// addResult adds a completed combination to the table
+func (d *dashboard) addResult(matrixResult benchmark.MatrixResult) {
+	d.program.Send(matrixResult)
+}
+
+// Write prints log lines above the dashboard, or to its output once it has stopped
+func (d *dashboard) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped.Load() {
+		return d.out.Write(p)
+	}
+	d.program.Println(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+// stop leaves the final table of all combinations on the terminal and waits for the
+// dashboard to exit
+func (d *dashboard) stop() {
+	d.mu.Lock()
+	d.stopped.Store(true)
+	d.mu.Unlock()
+	d.program.Send(dashboardDoneMsg{})
+	<-d.done
+}
+
+// dashboardModel is the state of the dashboard program
+type dashboardModel struct {
+	formatOpts    formatter.Options
+	width         int
+	live          bool // the run is in progress
+	rows          []dashboardRow
+	progress      benchmark.Progress
+	responseTimes []float64
+}
+
+// Init implements tea.Model
+func (m dashboardModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model, applying the progress of the run
+func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+	case benchmark.Progress:
+		m.progress = msg
+	case benchmark.CompletionResult:
+		m.responseTimes = append(m.responseTimes, float64(msg.ResponseTime.Microseconds())/1000)
+		if len(m.responseTimes) > dashboardResponseTimes {
+			m.responseTimes = m.responseTimes[len(m.responseTimes)-dashboardResponseTimes:]
+		}
+	case benchmark.MatrixResult:
+		m.rows = append(m.rows, dashboardRow{
+			params: formatDashboardParams(msg.Params, msg.OutputFlags),
+			result: formatter.BuildJSONResults([]benchmark.MatrixResult{msg}, m.formatOpts)[0],
+		})
+	case dashboardDoneMsg:
+		m.live = false
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// View implements tea.Model, rendering the dashboard, with the running combination and
+// recent response times while the run is in progress
+func (m dashboardModel) View() string {
+	paramsWidth := len("Parameters")
+	for _, row := range m.rows {
+		paramsWidth = max(paramsWidth, len(row.params))
+	}
+	running := ""
+	if m.live && m.progress.Combination > len(m.rows) {
+		running = formatDashboardParams(m.progress.Params, nil)
+		paramsWidth = max(paramsWidth, len(running))
+	}
+	paramsWidth = min(paramsWidth, dashboardParamsWidth)
+
+	row := func(index string, params string, status string, values ...string) string {
+		line := fmt.Sprintf("%3s  %-*s  %-8s", index, paramsWidth, truncate(params, paramsWidth), status)
+		for _, value := range values {
+			line += fmt.Sprintf("  %10s", value)
+		}
+		return truncate(strings.TrimRight(line, " "), m.width-1)
+	}
+
+	var lines []string
+	if m.live {
+		lines = append(lines, terminal.BoldText(terminal.CyanText(truncate("Turtlenekko: "+m.progress.String(), m.width-1))))
+	}
+	lines = append(lines, terminal.BoldText(row("#", "Parameters", "Status", "Prompt t/s", "Gen t/s", "R²", "Score")))
+
+	for i, r := range m.rows {
+		index := fmt.Sprint(i + 1)
+		if r.result.Error != "" {
+			lines = append(lines, terminal.RedText(row(index, r.params, "failed", r.result.Error)))
+			continue
+		}
+		score := "-"
+		if r.result.LocalScore != nil {
+			score = fmt.Sprintf("%.2f", *r.result.LocalScore)
+		}
+		lines = append(lines, terminal.GreenText(row(index, r.params, "done",
+			fmt.Sprintf("%.2f", r.result.ShortContextPromptTokensPerSec),
+			fmt.Sprintf("%.2f", r.result.ShortContextCompletionTokensPerSec),
+			fmt.Sprintf("%.2f", r.result.ShortContextAdjustedRSquared),
+			score)))
+	}
+
+	if !m.live {
+		// The last line is erased when the program exits
+		return strings.Join(lines, "\n") + "\n"
+	}
+
+	if m.progress.Combination > len(m.rows) {
+		lines = append(lines, terminal.YellowText(row(fmt.Sprint(m.progress.Combination), running, "running")))
+	}
+
+	if len(m.responseTimes) > 0 {
+		low, high := m.responseTimes[0], m.responseTimes[0]
+		for _, v := range m.responseTimes {
+			low = min(low, v)
+			high = max(high, v)
+		}
+		label := fmt.Sprintf("Response times (last %d, %.0f-%.0f ms): ", len(m.responseTimes), low, high)
+		lines = append(lines, truncate(label+terminal.Sparkline(m.responseTimes), m.width-1))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// formatDashboardParams formats the parameters shown in the output as "key=value" pairs
+// sorted by key; with no output flags all parameters are shown
+func formatDashboardParams(params map[string]interface{}, outputFlags map[string]bool) string {
+	var keys []string
+	for k := range params {
+		if outputFlags == nil || outputFlags[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", k, params[k]))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// truncate shortens s to at most width characters
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if width <= 0 || len(runes) <= width {
+		return s
+	}
+	return string(runes[:width])
+}
@@ -0,0 +1,67 @@
+package benchmark
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Ways of combining repeated measurements of the same token counts into one data point
+const (
+	AggregationBest   = "best"   // the fastest measurement
+	AggregationMedian = "median" // the median response time
+	AggregationMean   = "mean"   // the mean response time
+)
+
+// validateAggregation checks that the aggregation mode is supported
+func validateAggregation(mode string) error {
+	switch mode {
+	case AggregationBest, AggregationMedian, AggregationMean:
+		return nil
+	default:
+		return fmt.Errorf("unknown aggregation: %s (supported: %s, %s, %s)", mode, AggregationBest, AggregationMedian, AggregationMean)
+	}
+}
+
+// aggregateSamples combines repeated measurements of the same token counts into the data
+// point the completion time model is fitted to. The median of an even number of samples
+// and the mean are new results with the averaged response time and energy.
+func aggregateSamples(samples []*CompletionResult, mode string) *CompletionResult {
+	sorted := make([]*CompletionResult, len(samples))
+	copy(sorted, samples)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ResponseTime < sorted[j].ResponseTime
+	})
+
+	switch mode {
+	case AggregationMedian:
+		mid := len(sorted) / 2
+		if len(sorted)%2 == 1 {
+			return sorted[mid]
+		}
+		return averageSamples(sorted[mid-1 : mid+1])
+	case AggregationMean:
+		return averageSamples(sorted)
+	default:
+		return sorted[0]
+	}
+}
+
+// averageSamples returns a copy of the first sample with the mean response time and
+// energy of all samples
+func averageSamples(samples []*CompletionResult) *CompletionResult {
+	if len(samples) == 1 {
+		return samples[0]
+	}
+
+	average := *samples[0]
+	var responseTime time.Duration
+	energyJoules := 0.0
+	for _, sample := range samples {
+		responseTime += sample.ResponseTime
+		energyJoules += sample.EnergyJoules
+	}
+	average.ResponseTime = responseTime / time.Duration(len(samples))
+	average.EnergyJoules = energyJoules / float64(len(samples))
+	return &average
+}
@@ -0,0 +1,32 @@
+package benchmark
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Options configures BenchmarkURL
+type Options struct {
+	// Params are benchmark parameters as in the parameter matrix, e.g. "endpoint_type",
+	// "api_key" or "max_iterations"; nil uses the defaults
+	Params map[string]interface{}
+	// Logger receives the progress logs; nil logs to the default logger
+	Logger *slog.Logger
+}
+
+// BenchmarkURL runs the scaling benchmark against an already running chat completion
+// endpoint, without a driver or parameter matrix. The result holds the short and long
+// context fits and the estimated LocalScore. Cancelling ctx cancels the requests.
+func BenchmarkURL(ctx context.Context, url string, model string, opts Options) (*MatrixResult, error) {
+	matrixResult := &MatrixResult{Params: opts.Params}
+
+	err := runBenchmark(ctx, url, model, opts.Params, DefaultRequestTimeout, loggerOrDefault(opts.Logger), nil, matrixResult)
+	if ctx.Err() != nil {
+		// Requests failing because of the cancellation are logged, not returned
+		err = ctx.Err()
+	}
+
+	matrixResult.LocalScore = localScore(matrixResult)
+	matrixResult.Error = err
+	return matrixResult, err
+}
@@ -0,0 +1,43 @@
+package benchmark
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultAzureAPIVersion is the api-version query parameter sent to Azure OpenAI endpoints
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// azureURL returns the chat completions URL of an Azure OpenAI deployment. With a
+// deployment, it is built from base_url, or from the resource name if no base_url is
+// given; otherwise the driver URL is used. The api-version is added unless the URL
+// already has one.
+func azureURL(driverURL string, params map[string]interface{}) (string, error) {
+	endpoint := driverURL
+	if deployment := paramString(params, "deployment", ""); deployment != "" {
+		baseURL := paramString(params, "base_url", "")
+		if baseURL == "" {
+			resource := paramString(params, "resource", "")
+			if resource == "" {
+				return "", fmt.Errorf("azure deployment requires a resource or a base_url")
+			}
+			baseURL = "https://" + resource + ".openai.azure.com"
+		}
+		endpoint = strings.TrimSuffix(baseURL, "/") + "/openai/deployments/" + url.PathEscape(deployment) + "/chat/completions"
+	}
+	if endpoint == "" {
+		return "", fmt.Errorf("azure endpoint requires a deployment or a driver url")
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid azure url: %v", err)
+	}
+	query := u.Query()
+	if query.Get("api-version") == "" {
+		query.Set("api-version", paramString(params, "api_version", DefaultAzureAPIVersion))
+		u.RawQuery = query.Encode()
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,148 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// BatchPromptLength is the prompt size of each prompt of a batch request
+const BatchPromptLength = 500
+
+// BatchCompletionRequest is a completions request with several prompts, which servers
+// supporting batch prefill process together and answer with a choice per prompt
+type BatchCompletionRequest struct {
+	Model       string   `json:"model"`
+	Prompt      []string `json:"prompt"`
+	Temperature float64  `json:"temperature"`
+	MaxTokens   int      `json:"max_tokens"`
+	Seed        int      `json:"seed,omitempty"`
+}
+
+// BatchCompletionResponse is the subset of the completions response used to account
+// for a batch; usage covers all prompts of the request
+type BatchCompletionResponse struct {
+	Choices []struct {
+		Index        int    `json:"index"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// BatchResult contains the prompt processing throughput of a request with several prompts
+type BatchResult struct {
+	BatchSize                int
+	BatchPromptTokensPerSec  float64 // aggregate prompt tokens/sec of the batch request
+	SinglePromptTokensPerSec float64 // prompt tokens/sec of a request with a single prompt
+	BatchLatencyMs           float64 // response time of the batch request
+	BatchSpeedup             float64 // BatchPromptTokensPerSec / SinglePromptTokensPerSec
+}
+
+// completionsURL returns the URL of the completions endpoint batch requests are sent to:
+// batchURL if set, otherwise the chat completions URL with its last path element dropped
+func completionsURL(chatURL string, batchURL string) (string, error) {
+	if batchURL != "" {
+		return batchURL, nil
+	}
+	u, err := url.Parse(chatURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %v", err)
+	}
+	if !strings.HasSuffix(u.Path, "/chat/completions") {
+		return "", fmt.Errorf("cannot derive the completions url from %s, set batch_url", chatURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/chat/completions") + "/completions"
+	return u.String(), nil
+}
+
+// batchCompletion sends prompts in one completions request with a single completion token
+// per prompt and returns the prompt tokens the server counted and the response time
+func (b *Benchmark) batchCompletion(completionsURL string, prompts []string) (int, time.Duration, error) {
+	request := BatchCompletionRequest{
+		Model:       b.Model,
+		Prompt:      prompts,
+		Temperature: b.Sampling.Temperature,
+		MaxTokens:   1,
+		Seed:        b.CompletionSeed,
+	}
+	jsonData, err := json.Marshal(request)
+	if err == nil {
+		jsonData, err = b.applyExtraBody(jsonData)
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("error marshaling request: %v", err)
+	}
+
+	estimatedTokens := 0
+	for _, prompt := range prompts {
+		estimatedTokens += estimateTokens([]ChatMessage{{Content: prompt}}) + 1
+	}
+	posted, err := b.postJSON(completionsURL, jsonData, estimatedTokens, false)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var response BatchCompletionResponse
+	if err := json.Unmarshal(posted.Data, &response); err != nil {
+		return 0, 0, fmt.Errorf("error decoding response: %v: %s", err, bodySnippet(posted.Data))
+	}
+	// A server without batch support may process the prompts as one, or only the first
+	if len(response.Choices) < len(prompts) {
+		return 0, 0, fmt.Errorf("server answered %d of %d prompts, it may not support batch requests", len(response.Choices), len(prompts))
+	}
+	if response.Usage.PromptTokens == 0 {
+		return 0, 0, fmt.Errorf("response contains no token usage information")
+	}
+	return response.Usage.PromptTokens, posted.ResponseTime, nil
+}
+
+// RunBatchBenchmark sends batchSize prompts of promptLength in a single completions request
+// and measures the aggregate prompt processing throughput compared to a single prompt
+func (b *Benchmark) RunBatchBenchmark(completionsURL string, promptLength int, batchSize int, postfix string) (*BatchResult, error) {
+	b.log().Info("Running batch benchmark",
+		"component", "benchmark",
+		"prompt_length", promptLength,
+		"batch_size", batchSize,
+		"url", completionsURL)
+
+	// Measure a single prompt first as the throughput baseline
+	singleTokens, singleTime, err := b.batchCompletion(completionsURL, []string{b.generateMessages(promptLength, postfix)[0].Content})
+	if err != nil {
+		return nil, fmt.Errorf("baseline request failed: %v", err)
+	}
+
+	// Each prompt has its own unique prefix, so none is served from the cache
+	prompts := make([]string, batchSize)
+	for i := range prompts {
+		prompts[i] = b.generateMessages(promptLength, postfix)[0].Content
+	}
+	batchTokens, batchTime, err := b.batchCompletion(completionsURL, prompts)
+	if err != nil {
+		return nil, fmt.Errorf("batch request failed: %v", err)
+	}
+
+	result := &BatchResult{
+		BatchSize:                batchSize,
+		BatchPromptTokensPerSec:  float64(batchTokens) / batchTime.Seconds(),
+		SinglePromptTokensPerSec: float64(singleTokens) / singleTime.Seconds(),
+		BatchLatencyMs:           float64(batchTime.Milliseconds()),
+	}
+	if result.SinglePromptTokensPerSec > 0 {
+		result.BatchSpeedup = result.BatchPromptTokensPerSec / result.SinglePromptTokensPerSec
+	}
+
+	b.log().Info("Batch benchmark completed",
+		"component", "benchmark",
+		"batch_size", batchSize,
+		"prompt_tokens", batchTokens,
+		"latency_ms", batchTime.Milliseconds(),
+		"prompt_tokens_per_sec", result.BatchPromptTokensPerSec,
+		"speedup", result.BatchSpeedup)
+
+	return result, nil
+}
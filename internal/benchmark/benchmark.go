@@ -2,13 +2,19 @@ package benchmark
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"math"
 	"math/rand"
 	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/aifoundry-org/turtlenekko/internal/driver"
 	"github.com/aifoundry-org/turtlenekko/internal/types"
@@ -25,18 +31,26 @@ type ChatCompletionParams struct {
 	Messages            []ChatMessage
 	Temperature         float64
 	TopP                float64
+	TopK                int
+	MinP                float64
 	MaxCompletionTokens int
 	Seed                int
+	IgnoreEOS           bool
 }
 
 // ChatCompletionRequest represents the request body for chat completion
 type ChatCompletionRequest struct {
-	Model       string        `json:"model"`
-	Messages    []ChatMessage `json:"messages"`
-	Temperature float64       `json:"temperature,omitempty"`
-	TopP        float64       `json:"top_p,omitempty"`
-	MaxTokens   int           `json:"max_tokens,omitempty"`
-	Seed        int           `json:"seed,omitempty"`
+	Model         string         `json:"model"`
+	Messages      []ChatMessage  `json:"messages"`
+	Temperature   float64        `json:"temperature"`
+	TopP          float64        `json:"top_p,omitempty"`
+	TopK          int            `json:"top_k,omitempty"`
+	MinP          float64        `json:"min_p,omitempty"`
+	MaxTokens     int            `json:"max_tokens,omitempty"`
+	Seed          int            `json:"seed,omitempty"`
+	IgnoreEOS     bool           `json:"ignore_eos,omitempty"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
 }
 
 // ChatCompletionResponse represents the response from chat completion API
@@ -44,14 +58,24 @@ type ChatCompletionResponse struct {
 	ID      string `json:"id"`
 	Object  string `json:"object"`
 	Created int    `json:"created"`
+	Model   string `json:"model"`
 	Choices []struct {
-		Message ChatMessage `json:"message"`
+		Message      ChatMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
 	} `json:"choices"`
 	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
+		PromptTokens        int `json:"prompt_tokens"`
+		CompletionTokens    int `json:"completion_tokens"`
+		TotalTokens         int `json:"total_tokens"`
+		PromptTokensDetails *struct {
+			CachedTokens *int `json:"cached_tokens"`
+		} `json:"prompt_tokens_details"`
 	} `json:"usage"`
+	// llama.cpp specific cache reporting
+	TokensCached *int `json:"tokens_cached"`
+	Timings      *struct {
+		CacheN *int `json:"cache_n"`
+	} `json:"timings"`
 }
 
 // CompletionResult contains token usage information and timing from the LLM response
@@ -60,6 +84,19 @@ type CompletionResult struct {
 	CachedPromptTokens int
 	CompletionTokens   int
 	ResponseTime       time.Duration
+	EnergyJoules       float64 // energy used during the request, if power sampling is enabled
+	CacheReported      bool    // the server reported the number of cached prompt tokens
+	UsageEstimated     bool    // token counts were estimated client-side because the server did not report them
+	FinishReason       string  // why generation stopped, e.g. "length" or "stop" (Anthropic stop_reason)
+	Model              string  // model name echoed back by the server
+	EarlyStop          bool    // generation stopped before the requested completion tokens, see stoppedEarly
+	Context            string  // context benchmark the request belongs to, ContextShort or ContextLong
+
+	// Streamed responses only: the time to the first content delta and between successive ones
+	TimeToFirstToken      time.Duration
+	InterTokenLatencies   []time.Duration
+	MeanInterTokenLatency time.Duration
+	P99InterTokenLatency  time.Duration
 }
 
 // Result represents the benchmark results
@@ -71,123 +108,436 @@ type Result struct {
 	PromptLength int
 }
 
+// DefaultPostfix is appended to every prompt, asking for a long completion so requests
+// generate up to their completion limit
+const DefaultPostfix = "\nI need some filler content. Please generate as much lorem ipsum as you can."
+
+// DefaultCompletionSeed is the sampling seed sent with completion requests unless configured
+const DefaultCompletionSeed = 42
+
 // Benchmark represents a benchmark runner
 type Benchmark struct {
 	URL     string
 	Model   string
-	Timeout time.Duration
+	Timeout time.Duration // timeout of a single request, 0 for none
 	Client  *http.Client
 	Driver  driver.Driver
+
+	// Context, if set, cancels the requests when it is done
+	Context context.Context
+
+	// Rand is used to generate the unique prompt prefixes that prevent KV cache reuse
+	Rand *rand.Rand
+	// Deterministic replaces the random prompt prefix with a counter-based one,
+	// so two runs with the same seed produce byte-identical prompts
+	Deterministic bool
+	// Seed is the seed used for prompt generation
+	Seed int64
+	// CompletionSeed is the sampling seed sent with every completion request
+	CompletionSeed int
+	// PowerSampler, if set, measures the energy used by each request
+	PowerSampler *PowerSampler
+	// RateLimiter, if set, limits the request and token rate sent to the endpoint
+	RateLimiter *RateLimiter
+	// EndpointType selects the request and response schema ("openai" or "anthropic")
+	EndpointType string
+	// APIKey is sent as a bearer token (openai) or x-api-key header (anthropic)
+	APIKey string
+	// TokenCommand, if set, provides the API key instead and is refreshed when it is rejected
+	TokenCommand *TokenCommand
+	// AnthropicVersion is the anthropic-version header sent to anthropic endpoints
+	AnthropicVersion string
+	// EstimateUsage estimates token counts client-side when the server does not report usage
+	EstimateUsage bool
+	// DetectContext detects the model's context window and scales long context prompts to fit it
+	DetectContext bool
+	// ContextLimit is the configured or detected context window, nil if unknown
+	ContextLimit *ContextLimit
+	// ContextFit selects how long context prompts exceeding ContextLimit are handled ("scale" or "skip")
+	ContextFit string
+	// LongContextSkipped is the reason the long context benchmark was skipped, empty if it ran
+	LongContextSkipped string
+	// ContextRetry retries configs the server rejects as exceeding the context window with
+	// shorter prompts
+	ContextRetry bool
+	// PromptReductions lists the configs whose prompts were shortened to fit the context
+	PromptReductions []PromptReduction
+	// Corpus is the text prompts are generated from (lorem ipsum if empty)
+	Corpus string
+	// Logger receives the benchmark's logs; nil logs to the default logger
+	Logger *slog.Logger
+	// ContentEncoding compresses request bodies ("gzip"), empty sends them uncompressed
+	ContentEncoding string
+	// AcceptEncoding is sent as the Accept-Encoding header, empty leaves the client default
+	AcceptEncoding string
+	// MaxIterations is the maximum number of refinement iterations per context
+	MaxIterations int
+	// MaxConfigs limits the number of configs a refined grid may have, 0 for no limit
+	MaxConfigs int
+	// MinRSquared is the adjusted R-squared at which a context stops early
+	MinRSquared float64
+	// CachedRepeats is the number of times each prompt is repeated to measure cached prompt
+	// processing, 0 doesn't measure it
+	CachedRepeats int
+	// Repeats is the number of times each config is measured with a new prompt, giving
+	// Aggregation several uncached samples of the same token counts to combine
+	Repeats int
+	// Warmup sends a request before the measurements to load the model and prime the server
+	Warmup bool
+	// WarmupPromptLength is the prompt length of the warmup request in characters
+	WarmupPromptLength int
+	// WarmupMaxTokens is the number of completion tokens of the warmup request
+	WarmupMaxTokens int
+	// WarmupMode selects whether a fixed number of warmup requests is sent ("fixed"), or
+	// requests are repeated until their response times stabilize ("stable")
+	WarmupMode string
+	// WarmupCount is the number of warmup requests in fixed mode
+	WarmupCount int
+	// WarmupTolerance is the relative difference of consecutive warmup response times
+	// considered stable in stable mode
+	WarmupTolerance float64
+	// WarmupMax is the maximum number of warmup requests in stable mode
+	WarmupMax int
+	// WarmupResult describes the warmup requests sent, nil if the warmup was skipped
+	WarmupResult *WarmupResult
+	// ThrottlingThreshold is the slowdown of the last iteration relative to the first
+	// above which possible thermal throttling is reported
+	ThrottlingThreshold float64
+	// Aggregation selects how repeated measurements of the same token counts are combined
+	// ("best", "median" or "mean")
+	Aggregation string
+	// ClampRates raises fitted rates below the lowest plausible rate to it; disabling it
+	// reports the raw least squares rates to diagnose bad data
+	ClampRates bool
+	// Sampling holds the sampling parameters sent with every request
+	Sampling Sampling
+	// ExtraBody holds additional fields of the request body, e.g. server-specific sampling
+	// parameters; fields the request already sets are not overwritten
+	ExtraBody map[string]interface{}
+	// CalibratePromptLength scales the prompt lengths by the prompt tokens per byte observed
+	// in the warmup request, so the prompts land near their intended token counts
+	CalibratePromptLength bool
+	// PromptTokens are the prompt lengths of the scaling benchmark in tokens, resolved to
+	// prompt lengths with the server's tokenizer; empty uses the default prompt lengths
+	PromptTokens []int
+	// PromptTokenTargets are the prompt lengths the PromptTokens were resolved to
+	PromptTokenTargets []PromptTokenTarget
+	// EarlyStop selects how requests that stop before their completion limit are handled
+	// ("exclude", "ignore_eos" or "keep")
+	EarlyStop string
+	// CaptureDir receives a file per request with the request and response bodies, empty
+	// captures nothing
+	CaptureDir string
+	// Protocol is the HTTP protocol requests must use (ProtocolHTTP1 or ProtocolHTTP2),
+	// empty accepts whichever the server negotiates
+	Protocol string
+	// Stream requests the completion as server-sent events, measuring the time to first
+	// token and the latency between tokens
+	Stream bool
+	// Combination is the index of the combination in the matrix run, part of the prompt
+	// prefixes so combinations sharing a seed don't send the same prompts
+	Combination int
+
+	promptCounter     int
+	driverKey         string                                // driver parameters of the combination, see driverKey
+	promptTargets     map[promptTargetKey]PromptTokenTarget // resolved prompt_tokens targets, shared by a matrix run
+	progress          *progressTracker                      // progress of the matrix run, nil outside of one
+	promptBytes       int                                   // bytes of the prompts the server counted tokens of
+	promptTokens      int                                   // prompt tokens the server counted
+	cacheHits         int                                   // repeated prompts the server reported as cached
+	cacheMissDiscards int                                   // cached samples discarded after repeated cache misses
+
+	servedModelWarning sync.Once // warns once about a model mismatch
+	extraBodyWarning   sync.Once // warns once about ignored extra_body fields
+	protocolOnce       sync.Once // records the protocol of the first response
+	negotiatedProtocol string
 }
 
-// NewBenchmark creates a new benchmark runner
-func NewBenchmark(url string, model string, driverType string) *Benchmark {
+// NewBenchmark creates a new benchmark runner whose requests time out after timeout,
+// 0 for none
+func NewBenchmark(url string, model string, driverType string, timeout time.Duration) *Benchmark {
 	if model == "" {
 		model = "llama" // Default model if none provided
 	}
 
-	// Default timeout of 600 seconds
-	timeout := 600 * time.Second
+	seed := time.Now().UnixNano()
+
+	b := &Benchmark{
+		URL:                 url,
+		Model:               model,
+		Timeout:             timeout,
+		Client:              &http.Client{}, // requests are cancelled through their context, the client has no timeout
+		Rand:                rand.New(rand.NewSource(seed)),
+		Seed:                seed,
+		CompletionSeed:      DefaultCompletionSeed,
+		Sampling:            DefaultSampling,
+		EndpointType:        EndpointTypeOpenAI,
+		MaxIterations:       MaxBenchmarkIterations,
+		MaxConfigs:          DefaultMaxConfigs,
+		MinRSquared:         MinAcceptableRSquared,
+		Aggregation:         AggregationBest,
+		ClampRates:          true,
+		ContextRetry:        true,
+		EarlyStop:           EarlyStopExclude,
+		CachedRepeats:       DefaultCachedRepeats,
+		Repeats:             DefaultRepeats,
+		Warmup:              true,
+		ThrottlingThreshold: DefaultThrottlingThreshold,
+		WarmupPromptLength:  DefaultWarmupPromptLength,
+		WarmupMaxTokens:     DefaultWarmupMaxTokens,
+		WarmupMode:          WarmupModeFixed,
+		WarmupCount:         DefaultWarmupCount,
+		WarmupTolerance:     DefaultWarmupTolerance,
+		WarmupMax:           DefaultWarmupMax,
+	}
 
 	// Create driver if driver type is specified
-	var d driver.Driver
 	if driverType != "" {
-		var err error
-		d, err = driver.NewDriver(driverType)
+		d, err := driver.NewDriver(driverType)
 		if err != nil {
-			slog.Warn("Failed to create driver", "error", err)
+			b.log().Warn("Failed to create driver", "component", "benchmark", "error", err)
 		}
+		b.Driver = d
 	}
+	return b
+}
 
-	return &Benchmark{
-		URL:     url,
-		Model:   model,
-		Timeout: timeout,
-		Client: &http.Client{
-			Timeout: timeout,
-		},
-		Driver: d,
+// log returns the logger of the benchmark
+func (b *Benchmark) log() *slog.Logger {
+	return loggerOrDefault(b.Logger)
+}
+
+// context returns the context of the benchmark's requests
+func (b *Benchmark) context() context.Context {
+	if b.Context != nil {
+		return b.Context
+	}
+	return context.Background()
+}
+
+// loggerOrDefault returns logger, or the default logger if it is nil
+func loggerOrDefault(logger *slog.Logger) *slog.Logger {
+	if logger != nil {
+		return logger
 	}
+	return slog.Default()
+}
+
+// SetSeed reseeds the prompt generator so prompts are reproducible across runs
+func (b *Benchmark) SetSeed(seed int64) {
+	b.Seed = seed
+	b.Rand = rand.New(rand.NewSource(seed))
+	b.promptCounter = 0
 }
 
 // ChatCompletion sends a chat completion request to the LLM
 func (b *Benchmark) ChatCompletion(params ChatCompletionParams) (*CompletionResult, error) {
-	// Create request body
-	requestBody := ChatCompletionRequest{
-		Model:       b.Model,
-		Messages:    params.Messages,
-		Temperature: params.Temperature,
-		TopP:        params.TopP,
-		MaxTokens:   params.MaxCompletionTokens,
-		Seed:        params.Seed,
-	}
-
-	// Marshal request to JSON
-	jsonData, err := json.Marshal(requestBody)
+	// Create and marshal the request body for the endpoint type
+	jsonData, err := b.marshalRequest(params)
 	if err != nil {
 		return nil, fmt.Errorf("error marshaling request: %v", err)
 	}
 
-	slog.Info("Sending request", "component", "benchmark", "url", b.URL)
-
-	// Create HTTP request
-	req, err := http.NewRequest("POST", b.URL, bytes.NewBuffer(jsonData))
+	response, err := b.postJSON(b.URL, jsonData, estimateRequestTokens(params), b.Stream)
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
+		return nil, err
+	}
+
+	// Decode the response and extract usage information
+	var result *CompletionResult
+	var content string
+	if streamed := response.Streamed; streamed != nil {
+		result, content = b.chatCompletionResult(&streamed.Response)
+		result.TimeToFirstToken = streamed.TimeToFirstToken
+		result.InterTokenLatencies = streamed.InterTokenLatencies
+		result.MeanInterTokenLatency, result.P99InterTokenLatency = interTokenStats(streamed.InterTokenLatencies)
+	} else {
+		result, content, err = b.decodeResponse(bytes.NewReader(response.Data))
+		if err != nil {
+			err = fmt.Errorf("error decoding response: %v: %s", err, bodySnippet(response.Data))
+			b.log().Error("Failed to decode response", "component", "benchmark", "error", err)
+			return nil, err
+		}
 	}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
+	// Log the completion response content
+	b.log().Debug("Response content", "component", "benchmark", "content", content)
+	b.checkServedModel(result)
 
-	// Start timing right before the API call
-	startTime := time.Now()
+	// A response without token counts would imply infinite speed and poison the fit
+	if result.PromptTokens+result.CachedPromptTokens == 0 {
+		if !b.EstimateUsage {
+			return nil, fmt.Errorf("response contains no token usage information (set estimate_usage to estimate token counts client-side)")
+		}
+		estimateUsage(result, params, content)
+		b.log().Warn("Response contains no token usage information, using estimated token counts",
+			"component", "benchmark",
+			"prompt_tokens", result.PromptTokens,
+			"completion_tokens", result.CompletionTokens)
+	}
 
-	// Send request
-	resp, err := b.Client.Do(req)
+	// Include timing
+	result.ResponseTime = response.ResponseTime
+	result.EnergyJoules = response.EnergyJoules
+	result.EarlyStop = stoppedEarly(result, params.MaxCompletionTokens)
 
-	// Stop timing right after receiving the response
-	responseTime := time.Since(startTime)
+	b.log().Info("Completion successful",
+		"component", "benchmark",
+		"prompt_tokens", result.PromptTokens,
+		"completion_tokens", result.CompletionTokens,
+		"finish_reason", result.FinishReason)
 
+	b.progress.requestDone(*result)
+	return result, nil
+}
+
+// postResponse is a successful response of postJSON
+type postResponse struct {
+	Data         []byte            // decompressed body, nil if the response was streamed
+	Streamed     *streamedResponse // reassembled streamed response, nil unless streaming
+	ResponseTime time.Duration     // until the body was read completely if streamed
+	EnergyJoules float64
+}
+
+// postJSON sends a JSON request body to url, waiting for the rate limiter with the
+// estimated tokens of the request and retrying rate limited requests and expired tokens.
+// If stream is set, a successful response is read as server-sent events as it arrives.
+func (b *Benchmark) postJSON(url string, jsonData []byte, estimatedTokens int, stream bool) (*postResponse, error) {
+	// Compress the body up front so compression time isn't measured
+	body, err := b.encodeBody(jsonData)
 	if err != nil {
-		return nil, fmt.Errorf("error sending request: %v", err)
+		return nil, fmt.Errorf("error compressing request: %v", err)
+	}
+
+	var resp *http.Response
+	var streamed *streamedResponse
+	var responseTime time.Duration
+	energyJoules := 0.0
+	tokenRefreshed := false
+
+	// Each attempt has its own timeout, which also covers reading the response
+	ctx, cancel := b.requestContext()
+	defer func() { cancel() }()
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			cancel()
+			ctx, cancel = b.requestContext()
+		}
+
+		// Wait for the rate limit budget before sending
+		if b.RateLimiter != nil {
+			if err := b.RateLimiter.Wait(b.context(), estimatedTokens); err != nil {
+				return nil, fmt.Errorf("error waiting for rate limiter: %v", err)
+			}
+		}
+
+		b.log().Info("Sending request", "component", "benchmark", "url", url)
+
+		// Create HTTP request
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %v", err)
+		}
+
+		// Set headers
+		sentKey := b.apiKey()
+		b.setHeaders(req)
+		b.setEncodingHeaders(req)
+
+		// Sample power draw while the request is in flight
+		var stopPowerSampling func() float64
+		if b.PowerSampler != nil {
+			stopPowerSampling = b.PowerSampler.Start()
+		}
+
+		// Start timing right before the API call
+		startTime := time.Now()
+
+		// Send request
+		resp, err = b.Client.Do(req)
+
+		// Read a streamed response as it arrives, so its deltas are timed and the
+		// response time covers the whole generation
+		var streamErr error
+		if stream && err == nil && resp.StatusCode == http.StatusOK {
+			var raw bytes.Buffer
+			streamed, streamErr = readStreamBody(resp, &raw, startTime)
+			resp.Body.Close()
+			resp.Body = io.NopCloser(&raw)
+		}
+
+		// Stop timing right after receiving the response
+		responseTime = time.Since(startTime)
+
+		if stopPowerSampling != nil {
+			energyJoules = stopPowerSampling()
+		}
+
+		if b.CaptureDir != "" {
+			b.captureRequest(url, startTime, jsonData, resp, responseTime, err)
+		}
+
+		if err != nil {
+			return nil, b.requestError(ctx, "error sending request", err)
+		}
+		if streamErr != nil {
+			return nil, b.requestError(ctx, "error reading stream", streamErr)
+		}
+
+		// Back off and retry if the endpoint is rate limiting us
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < MaxRateLimitRetries {
+			delay := retryAfter(resp)
+			resp.Body.Close()
+			b.log().Warn("Rate limited, retrying", "component", "benchmark", "retry_after", delay, "attempt", attempt+1)
+			select {
+			case <-time.After(delay):
+			case <-b.context().Done():
+				return nil, b.requestError(b.context(), "error waiting to retry", b.context().Err())
+			}
+			continue
+		}
+
+		// Refresh an expired token once and retry
+		if resp.StatusCode == http.StatusUnauthorized && b.TokenCommand != nil && !tokenRefreshed {
+			resp.Body.Close()
+			tokenRefreshed = true
+			b.log().Warn("Token rejected, refreshing", "component", "benchmark")
+			if _, err := b.TokenCommand.Refresh(sentKey); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		break
 	}
 	defer resp.Body.Close()
 
 	// Check response status
 	if resp.StatusCode != http.StatusOK {
-		slog.Error("Received error response", "component", "benchmark", "status_code", resp.StatusCode)
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		err := statusError(resp)
+		b.log().Error("Received error response", "component", "benchmark", "status_code", resp.StatusCode, "error", err)
+		return nil, err
 	}
 
-	slog.Info("Received successful response", "component", "benchmark", "status_code", resp.StatusCode)
-
-	// Decode the response
-	var response ChatCompletionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		slog.Error("Failed to decode response", "component", "benchmark", "error", err)
-		return nil, fmt.Errorf("error decoding response: %v", err)
+	b.log().Info("Received successful response", "component", "benchmark", "status_code", resp.StatusCode)
+	if err := b.checkProtocol(resp); err != nil {
+		return nil, err
 	}
 
-	// Log the completion response content
-	if len(response.Choices) > 0 {
-		slog.Debug("Response content", "component", "benchmark", "content", response.Choices[0].Message.Content)
-	} else {
-		slog.Warn("Response contains no choices", "component", "benchmark")
+	response := &postResponse{Streamed: streamed, ResponseTime: responseTime, EnergyJoules: energyJoules}
+	if streamed != nil {
+		return response, nil
 	}
 
-	// Extract usage information and include timing
-	result := &CompletionResult{
-		PromptTokens:     response.Usage.PromptTokens,
-		CompletionTokens: response.Usage.CompletionTokens,
-		ResponseTime:     responseTime,
+	responseBody, err := decodeBody(resp)
+	if err != nil {
+		return nil, err
 	}
-
-	slog.Info("Completion successful",
-		"component", "benchmark",
-		"prompt_tokens", result.PromptTokens,
-		"completion_tokens", result.CompletionTokens)
-
-	return result, nil
+	response.Data, err = io.ReadAll(responseBody)
+	if err != nil {
+		return nil, b.requestError(ctx, "error reading response", err)
+	}
+	return response, nil
 }
 
 // Lorem ipsum text for generating realistic-looking content
@@ -195,42 +545,62 @@ func (b *Benchmark) ChatCompletion(params ChatCompletionParams) (*CompletionResu
 const loremIpsumText = `Lorem ipsum dolor sit amet, consectetur adipiscing elit. Donec risus erat, interdum id magna egestas, sodales malesuada lacus. Nullam at sagittis lacus. Aliquam erat volutpat. Suspendisse sed dolor diam. Nunc ac purus ultrices, aliquet velit et, iaculis mauris. Nullam vitae justo est. Nam id nisi nisl. Pellentesque euismod ut urna a fringilla. Donec dictum, dolor vitae sagittis sollicitudin, dui quam posuere massa, non aliquet mauris justo maximus sapien. Proin suscipit ut turpis quis blandit. Sed sit amet convallis libero. Curabitur sed scelerisque nisi. Pellentesque faucibus commodo convallis. Nulla pellentesque ut turpis eu rutrum. Fusce ligula mi, elementum et dolor sit amet, accumsan eleifend dui. Vivamus vel massa vel nibh interdum euismod et vel elit. Praesent rutrum mi eu eleifend fringilla. Cras venenatis libero ac felis faucibus, et tincidunt est dignissim. Donec condimentum libero ex, at dictum odio maximus eu. Donec at accumsan turpis, at lacinia risus. Orci varius natoque penatibus et magnis dis parturient montes, nascetur ridiculus mus. Fusce maximus orci diam, eget consequat eros laoreet in. Morbi iaculis tincidunt erat, eget maximus risus mattis a. Donec ut nunc a augue placerat gravida. Fusce vitae eros eget eros maximus cursus at ut dolor. Sed eu finibus nulla. Pellentesque id placerat felis. Mauris at risus bibendum, ultrices felis ac, viverra urna. Orci varius natoque penatibus et magnis dis parturient montes, nascetur ridiculus mus. Donec lobortis cursus feugiat. Sed fermentum est nec sapien maximus, non lobortis tortor feugiat. Phasellus in molestie risus. Etiam faucibus sapien ex, nec elementum purus faucibus nec. Ut sed massa ornare nunc condimentum tincidunt et et massa. Nam interdum mattis nulla, et interdum nisl sollicitudin vitae. Maecenas eget quam ut tellus rhoncus placerat. Praesent eu felis quis nisi faucibus porta. Maecenas eleifend ultricies faucibus. Sed tempor felis at nulla mollis dignissim. Praesent ac accumsan elit. Maecenas efficitur, nunc a feugiat tristique, urna diam facilisis odio, gravida consectetur risus ex ac dui. Sed laoreet elit et tellus efficitur, id rhoncus risus interdum. In tincidunt porta bibendum. In porta nisl porttitor nisl rutrum, at auctor arcu eleifend. Mauris ac volutpat turpis. Maecenas consequat lectus sit amet nibh posuere, vitae euismod felis tristique. Aliquam imperdiet varius sodales. Aliquam eget mauris in felis elementum facilisis. In efficitur euismod orci porttitor scelerisque. Curabitur imperdiet tellus eros, in varius tellus egestas et. Vivamus auctor ipsum in varius vulputate. In hac habitasse platea dictumst. Vivamus lacinia tellus vel mattis auctor. Vivamus quis condimentum lacus. Sed imperdiet libero ut ipsum tempor, ut consequat quam consectetur. Etiam leo ex, viverra porta diam vitae, molestie imperdiet diam. Fusce a nisl eu arcu rhoncus volutpat. Vestibulum ante ipsum primis in faucibus orci luctus et ultrices posuere cubilia curae; Aenean rutrum rhoncus sem, sed rhoncus leo imperdiet in. Proin a euismod enim. Vivamus elementum ligula quis lacus vehicula fermentum. Aenean venenatis, est ut interdum suscipit, risus nibh molestie purus, a posuere dui sem ac nibh. Donec aliquet diam nec nunc vehicula sollicitudin. Donec feugiat faucibus diam sit amet vulputate. Praesent rhoncus diam ac felis facilisis varius. Fusce vulputate nisl id suscipit venenatis. Mauris fermentum, nisl quis interdum interdum, risus purus posuere libero, quis accumsan turpis magna id tortor. In tempus malesuada est, nec aliquam urna. Suspendisse tempor et orci tempor rutrum. Curabitur sit amet mauris libero. Etiam convallis libero ipsum, eget imperdiet sapien sodales vitae. Praesent quis commodo nisl. Vestibulum accumsan eget metus ut venenatis. Sed pharetra enim gravida nunc condimentum ullamcorper. Aliquam egestas iaculis mi. Donec finibus dapibus ante, nec rutrum diam feugiat et. Etiam pellentesque, nulla et congue porttitor, magna mi efficitur elit, eget congue lorem metus ac ante. Nullam blandit ligula mi, posuere lobortis risus efficitur id. Duis pharetra convallis urna, at efficitur sem vestibulum eu. Cras aliquam, nunc non venenatis lacinia, lacus ipsum luctus mauris, et placerat nibh sapien tempor nibh. Integer aliquet mauris id scelerisque sollicitudin. Etiam ac magna ipsum. Phasellus mattis ipsum et felis maximus consectetur. Proin fringilla vel dui et tempor. Nam rhoncus eu mauris vitae feugiat. Phasellus feugiat laoreet erat sit amet imperdiet. Fusce sodales ex sapien, vitae ultrices purus pretium sed. Suspendisse nec felis consectetur urna fermentum mollis eget dapibus enim. Cras consequat mauris et cursus accumsan. Ut semper rutrum nisl sit amet congue. Mauris nisl magna, lacinia vitae faucibus in, congue et elit. Maecenas ullamcorper nisl id libero sollicitudin lacinia. Praesent ultrices, massa vitae faucibus porta, nunc nibh venenatis lorem, aliquam ultrices augue nibh vitae lorem. Vivamus faucibus augue in dapibus cursus. Sed facilisis lectus convallis mauris venenatis pulvinar. Vivamus nec nibh vitae nisi pretium tristique. Sed nec est non mauris scelerisque aliquet. Duis a est feugiat, efficitur ex rutrum, condimentum arcu. Mauris ullamcorper molestie odio a sagittis. Mauris aliquam arcu vel ipsum lobortis blandit. Integer quis semper justo. Morbi quis consectetur quam. Curabitur vehicula feugiat ligula at venenatis. In et est vitae odio euismod interdum. Cras metus nulla, volutpat a magna vitae, facilisis hendrerit libero. Donec dictum odio et tellus sagittis tristique. Mauris at arcu velit. Vestibulum eu dolor id nulla sodales finibus a et elit. Morbi ultricies et magna ut fringilla. Interdum et malesuada fames ac ante ipsum primis in faucibus. Ut maximus scelerisque nibh, at ultrices magna iaculis vel. Quisque eu est ac arcu malesuada tristique. Vestibulum vestibulum elementum tellus, nec laoreet turpis ornare quis. Donec imperdiet vulputate tincidunt. Curabitur nisl risus, faucibus ut venenatis id, porttitor sit amet augue. Integer molestie iaculis condimentum. Donec varius elit ipsum, sed vestibulum eros finibus lacinia. Vestibulum congue mollis nisi, quis pretium ligula maximus in. Ut tincidunt auctor tincidunt. Nam a convallis erat. Donec dignissim porta cursus. Nam malesuada tempor sem, et cursus tellus. Nulla commodo fringilla tellus dictum dapibus. Sed sed sapien ante. Nullam luctus, neque nec faucibus auctor, erat urna condimentum ipsum, id imperdiet metus nisl eu tellus. Praesent id ante semper, commodo mi nec, placerat ipsum. Quisque mollis porta scelerisque. Cras feugiat, est sed tristique fermentum, diam lorem porta purus, eu semper est sapien ut velit. Vivamus sapien turpis, tincidunt ac mauris vitae, dapibus aliquam urna. Nulla vestibulum egestas felis. Sed ultricies ullamcorper justo eget fermentum. Morbi. `
 
 // generateRandomContent creates a string of random alphanumeric characters of the specified length
-func generateRandomContent(length int) string {
+func generateRandomContent(rng *rand.Rand, length int) string {
 	const charset = "0123456789abcdefghijklmnopqrstuvwxyz"
 	result := make([]byte, length)
 	for i := range result {
-		result[i] = charset[rand.Intn(len(charset))]
+		result[i] = charset[rng.Intn(len(charset))]
 	}
 	return string(result)
 }
 
-// generateLoremIpsum creates a string of lorem ipsum text repeated to reach the specified length
-func generateLoremIpsum(length int) string {
-	if length <= 0 {
-		return ""
+// uniquePrefix returns a prompt prefix that differs for every generated prompt
+func (b *Benchmark) uniquePrefix() string {
+	b.promptCounter++
+	if b.Deterministic {
+		return fmt.Sprintf("seed:%d-%d-%d", b.Seed, b.Combination, b.promptCounter)
 	}
+	return fmt.Sprintf("seed:%d-%s", b.Combination, generateRandomContent(b.Rand, 10))
+}
 
-	// Calculate how many times we need to repeat the text
-	repeats := (length + len(loremIpsumText) - 1) / len(loremIpsumText)
+// generatePromptText creates a string of corpus text repeated to reach the specified length
+func generatePromptText(corpus string, length int) string {
+	if length <= 0 || corpus == "" {
+		return ""
+	}
 
-	// Build the repeated text
-	var result string
-	for i := 0; i < repeats; i++ {
-		result += loremIpsumText
+	// Build the repeated text in a single allocation
+	var sb strings.Builder
+	sb.Grow(length)
+	for sb.Len() < length {
+		remaining := length - sb.Len()
+		if remaining >= len(corpus) {
+			sb.WriteString(corpus)
+		} else {
+			sb.WriteString(corpus[:remaining])
+		}
 	}
+	result := sb.String()
 
-	// Truncate to the exact requested length
-	if len(result) > length {
-		result = result[:length]
+	// Don't end with a partial multi-byte character
+	for len(result) > 0 {
+		if r, size := utf8.DecodeLastRuneInString(result); r != utf8.RuneError || size != 1 {
+			break
+		}
+		result = result[:len(result)-1]
 	}
 
 	return result
 }
 
 // generateMessages creates an array of chat messages with random content of specified lengths
-func generateMessages(systemContentLength int, postfix string) []ChatMessage {
-	// Random prefix prevents kv cache reuse.
-	content := "seed:" + generateRandomContent(10) + "\n" + generateLoremIpsum(systemContentLength)
+func (b *Benchmark) generateMessages(systemContentLength int, postfix string) []ChatMessage {
+	// Unique prefix prevents kv cache reuse.
+	corpus := b.Corpus
+	if corpus == "" {
+		corpus = loremIpsumText
+	}
+	content := b.uniquePrefix() + "\n" + generatePromptText(corpus, systemContentLength)
 	if postfix != "" {
 		content += postfix
 	}
@@ -245,7 +615,7 @@ func generateMessages(systemContentLength int, postfix string) []ChatMessage {
 
 // RunWithPromptLength executes a benchmark with a specific prompt length and max completion tokens
 func (b *Benchmark) RunWithPromptLength(promptLength int, maxCompletionTokens int, postfix string) ([]*CompletionResult, error) {
-	slog.Info("Running benchmark",
+	b.log().Info("Running benchmark",
 		"component", "benchmark",
 		"prompt_length", promptLength,
 		"max_tokens", maxCompletionTokens,
@@ -253,16 +623,10 @@ func (b *Benchmark) RunWithPromptLength(promptLength int, maxCompletionTokens in
 
 	results := []*CompletionResult{}
 
-	messages := generateMessages(promptLength, postfix)
+	messages := b.generateMessages(promptLength, postfix)
 
 	// Parameters with specified prompt length and max tokens
-	params := ChatCompletionParams{
-		Messages:            messages,
-		Temperature:         0.0, // Use deterministic sampling
-		TopP:                1.0,
-		MaxCompletionTokens: maxCompletionTokens,
-		Seed:                42, // Fixed seed for reproducibility
-	}
+	params := b.completionParams(messages, maxCompletionTokens)
 
 	// Make the actual request to the LLM
 	completionResult, err := b.ChatCompletion(params)
@@ -271,7 +635,7 @@ func (b *Benchmark) RunWithPromptLength(promptLength int, maxCompletionTokens in
 	time.Sleep(500 * time.Millisecond)
 
 	if err != nil {
-		slog.Error("Benchmark failed",
+		b.log().Error("Benchmark failed",
 			"component", "benchmark",
 			"prompt_length", promptLength,
 			"max_tokens", maxCompletionTokens,
@@ -280,44 +644,129 @@ func (b *Benchmark) RunWithPromptLength(promptLength int, maxCompletionTokens in
 	}
 
 	// Log the detailed timing information
-	slog.Info("Benchmark completed",
+	b.log().Info("Benchmark completed",
 		"component", "benchmark",
 		"prompt_length", promptLength,
 		"max_tokens", maxCompletionTokens,
 		"response_time_ms", completionResult.ResponseTime.Milliseconds())
 
+	if isCacheHit(completionResult) {
+		b.log().Warn("Server reports a cache hit for a new prompt, it was likely sent before",
+			"component", "benchmark",
+			"prompt_length", promptLength,
+			"prompt_tokens", completionResult.PromptTokens,
+			"cached_prompt_tokens", completionResult.CachedPromptTokens)
+	}
+
 	results = append(results, completionResult)
+	b.recordPromptRatio(messages, completionResult)
 
-	// Repeat with the same messages
-	// Make the actual request to the LLM
-	cachedCompletionResult, err := b.ChatCompletion(params)
+	// Repeat with the same messages, which should be served from the KV cache
+	for repeat := 0; repeat < b.CachedRepeats; repeat++ {
+		cachedCompletionResult, err := b.runCachedRequest(params, promptLength, maxCompletionTokens)
+		if err != nil {
+			return nil, err
+		}
+		if cachedCompletionResult == nil {
+			// The cache entries are being evicted, further repeats would miss too
+			break
+		}
+		results = append(results, cachedCompletionResult)
+	}
 
-	// Small delay between requests to avoid overwhelming the server
-	time.Sleep(500 * time.Millisecond)
+	return results, nil
+}
 
-	if err != nil {
-		slog.Error("Benchmark failed",
+// runCachedRequest repeats a request whose prompt should be served from the KV cache.
+// Misses are retried, unless the server has missed before without ever hitting the
+// cache. It returns nil if the sample was discarded after repeated misses.
+func (b *Benchmark) runCachedRequest(params ChatCompletionParams, promptLength int, maxCompletionTokens int) (*CompletionResult, error) {
+	retries := CacheMissRetries
+	if b.cacheHits == 0 && b.cacheMissDiscards > 0 {
+		retries = 0
+	}
+	var cachedCompletionResult *CompletionResult
+	for attempt := 0; attempt <= retries; attempt++ {
+		var err error
+		cachedCompletionResult, err = b.ChatCompletion(params)
+
+		// Small delay between requests to avoid overwhelming the server
+		time.Sleep(500 * time.Millisecond)
+
+		if err != nil {
+			b.log().Error("Benchmark failed",
+				"component", "benchmark",
+				"prompt_length", promptLength,
+				"max_tokens", maxCompletionTokens,
+				"error", err)
+			return nil, fmt.Errorf("chat completion failed: %v", err)
+		}
+
+		if !isCacheMiss(cachedCompletionResult) {
+			break
+		}
+
+		// The cache entry was evicted, the timing is a full prefill
+		b.log().Warn("Server reports a cache miss for a repeated prompt",
+			"component", "benchmark",
+			"prompt_length", promptLength,
+			"attempt", attempt+1,
+			"prompt_tokens", cachedCompletionResult.PromptTokens,
+			"cached_prompt_tokens", cachedCompletionResult.CachedPromptTokens)
+	}
+
+	if isCacheMiss(cachedCompletionResult) {
+		b.cacheMissDiscards++
+		b.log().Warn("Discarding cached sample after repeated cache misses",
 			"component", "benchmark",
 			"prompt_length", promptLength,
 			"max_tokens", maxCompletionTokens,
-			"error", err)
-		return nil, fmt.Errorf("chat completion failed: %v", err)
+			"attempts", retries+1)
+		return nil, nil
+	}
+	if cachedCompletionResult.CacheReported {
+		b.cacheHits++
 	}
 
-	// All prompt was cached cached
-	cachedCompletionResult.CachedPromptTokens = cachedCompletionResult.PromptTokens
-	cachedCompletionResult.PromptTokens = 0
+	// Without a server-reported cache hit count, assume all prompt was cached
+	if !cachedCompletionResult.CacheReported {
+		cachedCompletionResult.CachedPromptTokens = cachedCompletionResult.PromptTokens
+		cachedCompletionResult.PromptTokens = 0
+	} else if cachedCompletionResult.PromptTokens > 0 {
+		b.log().Warn("Server reports a partial cache hit for a repeated prompt",
+			"component", "benchmark",
+			"prompt_tokens", cachedCompletionResult.PromptTokens,
+			"cached_prompt_tokens", cachedCompletionResult.CachedPromptTokens)
+	}
 
 	// Log the detailed timing information
-	slog.Info("Benchmark completed (cached)",
+	b.log().Info("Benchmark completed (cached)",
 		"component", "benchmark",
 		"prompt_length", promptLength,
 		"max_tokens", maxCompletionTokens,
-		"response_time_ms", completionResult.ResponseTime.Milliseconds())
+		"response_time_ms", cachedCompletionResult.ResponseTime.Milliseconds())
+
+	return cachedCompletionResult, nil
+}
 
-	results = append(results, cachedCompletionResult)
+// isCacheMiss reports whether the server says less than MinCacheHitRatio of a repeated
+// prompt was served from the cache. Without a reported count a hit can't be verified.
+func isCacheMiss(result *CompletionResult) bool {
+	if !result.CacheReported {
+		return false
+	}
+	total := result.PromptTokens + result.CachedPromptTokens
+	return total > 0 && float64(result.CachedPromptTokens) < MinCacheHitRatio*float64(total)
+}
 
-	return results, nil
+// isCacheHit reports whether the server says at least MinCacheHitRatio of a prompt was
+// served from the cache
+func isCacheHit(result *CompletionResult) bool {
+	if !result.CacheReported {
+		return false
+	}
+	total := result.PromptTokens + result.CachedPromptTokens
+	return total > 0 && float64(result.CachedPromptTokens) >= MinCacheHitRatio*float64(total)
 }
 
 // ModelFitResult contains the fitted parameters for the completion time model
@@ -326,27 +775,72 @@ type ModelFitResult struct {
 	CachedPromptRate float64 // ms per cached prompt token
 	CompletionRate   float64 // ms per completion token
 	RSquared         float64 // goodness of fit (0-1)
+	AdjustedRSquared float64 // R² penalized for the number of fitted rates, 0 with too few points
+	RMSE             float64 // root mean square error of the predicted response times (ms)
+	Fallback         bool    // no model could be fitted to the data, the rates are 0
+	FixedOverheadMs  float64 // fitted time of a request independent of its token counts, e.g. network (ms)
+	Model            string  // fitted predictors, FullFitModel unless the data was rank-deficient
+	NumPoints        int     // number of results the model was fitted to
+
+	// RatesBelowFloor are the token predictors whose fitted rate was below the lowest
+	// plausible rate, e.g. because of bad data, so the rate is no genuine measurement
+	RatesBelowFloor []string
+	// Clamped reports that the rates below the floor were raised to it; otherwise they
+	// are the raw least squares coefficients and may be negative
+	Clamped bool
+
+	// IterationSlowdowns is the median measured/predicted response time per iteration
+	// relative to the first, nil with a single iteration
+	IterationSlowdowns []float64
+	// Throttling reports iterations getting systematically slower, e.g. thermal throttling
+	Throttling bool
+
+	// Response time percentiles over all raw samples (ms), including those not kept for fitting
+	LatencyP50 float64
+	LatencyP90 float64
+	LatencyP99 float64
+
+	// Streaming latencies over all raw samples (ms), 0 unless requests were streamed
+	TTFTMean float64 // mean time to first token
+	ITLMean  float64 // mean inter-token latency
+	ITLP99   float64 // 99th percentile inter-token latency
+	// ITLTokensPerSec is the generation rate between the first and last token, which
+	// unlike the completion rate excludes the time to first token
+	ITLTokensPerSec float64
+}
+
+// PredictMs returns the response time the model predicts for the token counts of a result (ms)
+func (m *ModelFitResult) PredictMs(result *CompletionResult) float64 {
+	return m.FixedOverheadMs +
+		m.PromptRate*float64(result.PromptTokens) +
+		m.CachedPromptRate*float64(result.CachedPromptTokens) +
+		m.CompletionRate*float64(result.CompletionTokens)
 }
 
-// fitCompletionTimeModel fits the model: completion_time = a * prompt_tokens + b * cached_prompt_tokens + c * completion_tokens
-// to the measured data using linear regression (ordinary least squares)
-func fitCompletionTimeModel(results []*CompletionResult) *ModelFitResult {
+// fitCompletionTimeModel fits the model: completion_time = overhead + a * prompt_tokens + b * cached_prompt_tokens + c * completion_tokens
+// to the measured data using linear regression (ordinary least squares). If the data doesn't
+// determine all three rates, a reduced model is fitted and the dropped rates are 0. If it
+// doesn't determine a non-negative overhead, the model is fitted through the origin.
+// Rates below their floor are raised to it if clampRates is set.
+func fitCompletionTimeModel(logger *slog.Logger, results []*CompletionResult, clampRates bool) *ModelFitResult {
 	if len(results) < 2 {
-		slog.Warn("Not enough results for model fitting", "component", "benchmark", "count", len(results))
+		logger.Warn("Not enough results for model fitting", "component", "benchmark", "count", len(results))
 		return &ModelFitResult{
 			PromptRate:       0,
 			CachedPromptRate: 0,
 			CompletionRate:   0,
 			RSquared:         0,
+			NumPoints:        len(results),
 		}
 	}
 
 	// Count valid results and log input data
 	validResults := 0
-	slog.Info("Model fitting input data:", "component", "benchmark")
+	prefillOnly := 0
+	logger.Debug("Model fitting input data:", "component", "benchmark")
 
 	// Prepare data for linear regression
-	var X [][]float64 // Features: [prompt_tokens, cached_prompt_tokens, completion_tokens]
+	var X [][]float64 // Features: [prompt_tokens, cached_prompt_tokens, completion_tokens, 1]
 	var y []float64   // Target: response_time_ms
 
 	for i, r := range results {
@@ -354,9 +848,12 @@ func fitCompletionTimeModel(results []*CompletionResult) *ModelFitResult {
 			continue
 		}
 		validResults++
+		if r.CompletionTokens == 0 {
+			prefillOnly++
+		}
 
 		// Log data point
-		slog.Info("Data point",
+		logger.Debug("Data point",
 			"component", "benchmark",
 			"index", i,
 			"prompt_tokens", r.PromptTokens,
@@ -369,166 +866,111 @@ func fitCompletionTimeModel(results []*CompletionResult) *ModelFitResult {
 			float64(r.PromptTokens),
 			float64(r.CachedPromptTokens),
 			float64(r.CompletionTokens),
+			1,
 		})
 		y = append(y, float64(r.ResponseTime.Milliseconds()))
 	}
 
-	slog.Info("Starting linear regression", "component", "benchmark", "valid_results", validResults)
+	// Servers that only emit a stop token for the one-token requests report 0 completion
+	// tokens. Such points still determine the prompt rate, the completion rate is fitted
+	// to the others; if there are none, it's dropped by the reduced models below.
+	if prefillOnly == validResults {
+		logger.Warn("Server reported no completion tokens for any request, the completion rate can't be estimated",
+			"component", "benchmark",
+			"count", prefillOnly)
+	} else if prefillOnly > 0 {
+		logger.Debug("Fitting requests without completion tokens as prefill-only samples",
+			"component", "benchmark",
+			"count", prefillOnly)
+	}
 
-	// Calculate means
-	meanX := make([]float64, 3)
-	meanY := 0.0
+	logger.Info("Starting linear regression", "component", "benchmark", "valid_results", validResults)
 
-	for i := 0; i < len(X); i++ {
-		for j := 0; j < 3; j++ {
-			meanX[j] += X[i][j]
-		}
+	meanY := 0.0
+	for i := 0; i < len(y); i++ {
 		meanY += y[i]
 	}
-
-	for j := 0; j < 3; j++ {
-		meanX[j] /= float64(len(X))
-	}
 	meanY /= float64(len(y))
 
-	// Calculate coefficients using normal equations
-	// (X^T * X)^(-1) * X^T * y
-
-	// First, calculate X^T * X (3x3 matrix)
-	xtx := make([][]float64, 3)
-	for i := range xtx {
-		xtx[i] = make([]float64, 3)
-	}
-
-	for i := 0; i < 3; i++ {
-		for j := 0; j < 3; j++ {
-			for k := 0; k < len(X); k++ {
-				xtx[i][j] += X[k][i] * X[k][j]
+	// Fit the full model, or the first reduced model the data determines if it is
+	// rank-deficient, e.g. because the server never reported cached tokens. A negative
+	// overhead can't be real, the model is then fitted through the origin instead.
+	var coefficients []float64
+	var columns []int
+fit:
+	for _, model := range fitModels {
+		for _, candidate := range [][]int{withOverhead(model), model} {
+			var ok bool
+			if coefficients, ok = solveLeastSquares(X, y, candidate); !ok {
+				logger.Debug("Model is rank-deficient", "component", "benchmark", "model", fitModelName(candidate), "overhead", len(candidate) > len(model))
+				continue
+			}
+			if len(candidate) > len(model) && coefficients[len(model)] < 0 {
+				logger.Debug("Fitted overhead is negative, fitting through the origin", "component", "benchmark", "model", fitModelName(model), "overhead_ms", coefficients[len(model)])
+				continue
 			}
+			columns = candidate
+			break fit
 		}
 	}
-
-	// Calculate X^T * y (3x1 vector)
-	xty := make([]float64, 3)
-	for i := 0; i < 3; i++ {
-		for k := 0; k < len(X); k++ {
-			xty[i] += X[k][i] * y[k]
-		}
+	if columns == nil {
+		logger.Warn("Data is rank-deficient for every model, no rates could be fitted", "component", "benchmark")
+		return &ModelFitResult{Fallback: true, NumPoints: validResults}
+	}
+	model := fitModelName(columns)
+	if model != FullFitModel {
+		logger.Warn("Full model is rank-deficient, fitted a reduced model",
+			"component", "benchmark",
+			"model", model)
 	}
 
-	// Solve the system of equations using Gaussian elimination
-	// We're solving: xtx * [a, b, c] = xty
-
-	// Check if the matrix is invertible (non-zero determinant)
-	// For simplicity, we'll just check if any column is all zeros
-	for j := 0; j < 3; j++ {
-		allZeros := true
-		for i := 0; i < 3; i++ {
-			if math.Abs(xtx[i][j]) > 1e-10 {
-				allZeros = false
-				break
-			}
+	// Ensure the fitted rates are positive unless clamping is disabled to diagnose the
+	// data; the rates of dropped predictors stay 0
+	rates := make([]float64, len(minFittedRates))
+	overhead := 0.0
+	var belowFloor, fittedBelowFloor []string
+	for i, column := range columns {
+		if column == overheadColumn {
+			overhead = coefficients[i]
+			continue
 		}
-		if allZeros {
-			slog.Warn("Matrix is singular, using fallback values", "component", "benchmark", "column", j)
-			// Use reasonable fallback values based on the data
-			a := 3.0  // ~3ms per prompt token
-			b := 0.01 // ~0.01ms per cached token
-			c := 25.0 // ~25ms per completion token
-
-			slog.Info("Using fallback model parameters",
-				"component", "benchmark",
-				"prompt_rate_ms_per_token", a,
-				"cached_prompt_rate_ms_per_token", b,
-				"completion_rate_ms_per_token", c)
-
-			return &ModelFitResult{
-				PromptRate:       a,
-				CachedPromptRate: b,
-				CompletionRate:   c,
-				RSquared:         0.5, // Reasonable default
+		rates[column] = coefficients[i]
+		if coefficients[i] < minFittedRates[column] {
+			belowFloor = append(belowFloor, fitPredictors[column])
+			fittedBelowFloor = append(fittedBelowFloor, fmt.Sprintf("%s=%.4g", fitPredictors[column], coefficients[i]))
+			if clampRates {
+				rates[column] = minFittedRates[column]
 			}
 		}
 	}
-
-	// Augment the matrix for Gaussian elimination
-	augmented := make([][]float64, 3)
-	for i := range augmented {
-		augmented[i] = make([]float64, 4)
-		for j := 0; j < 3; j++ {
-			augmented[i][j] = xtx[i][j]
-		}
-		augmented[i][3] = xty[i]
+	fit := &ModelFitResult{
+		PromptRate:       rates[0],
+		CachedPromptRate: rates[1],
+		CompletionRate:   rates[2],
+		FixedOverheadMs:  overhead,
+		RatesBelowFloor:  belowFloor,
+		Clamped:          clampRates && len(belowFloor) > 0,
 	}
-
-	// Gaussian elimination
-	for i := 0; i < 3; i++ {
-		// Find pivot
-		maxRow := i
-		for j := i + 1; j < 3; j++ {
-			if math.Abs(augmented[j][i]) > math.Abs(augmented[maxRow][i]) {
-				maxRow = j
-			}
-		}
-
-		// Swap rows
-		augmented[i], augmented[maxRow] = augmented[maxRow], augmented[i]
-
-		// Check for numerical stability
-		if math.Abs(augmented[i][i]) < 1e-10 {
-			slog.Warn("Matrix is nearly singular, using fallback values", "component", "benchmark")
-			// Use reasonable fallback values
-			a := 3.0  // ~3ms per prompt token
-			b := 0.01 // ~0.01ms per cached token
-			c := 25.0 // ~25ms per completion token
-
-			return &ModelFitResult{
-				PromptRate:       a,
-				CachedPromptRate: b,
-				CompletionRate:   c,
-				RSquared:         0.5, // Reasonable default
-			}
-		}
-
-		// Scale row
-		pivot := augmented[i][i]
-		for j := i; j < 4; j++ {
-			augmented[i][j] /= pivot
-		}
-
-		// Eliminate other rows
-		for j := 0; j < 3; j++ {
-			if j != i {
-				factor := augmented[j][i]
-				for k := i; k < 4; k++ {
-					augmented[j][k] -= factor * augmented[i][k]
-				}
-			}
-		}
+	if len(belowFloor) > 0 {
+		logger.Warn("Fitted rates are below the lowest plausible rate, they are no genuine measurement",
+			"component", "benchmark",
+			"fitted_ms_per_token", strings.Join(fittedBelowFloor, ","),
+			"clamped", clampRates)
 	}
 
-	// Extract coefficients
-	a := augmented[0][3]
-	b := augmented[1][3]
-	c := augmented[2][3]
-
-	// Ensure coefficients are non-negative
-	a = math.Max(0.01, a)  // Minimum 0.01ms per token
-	b = math.Max(0.001, b) // Minimum 0.001ms per token
-	c = math.Max(0.1, c)   // Minimum 0.1ms per token
-
-	slog.Info("Linear regression results",
+	logger.Info("Linear regression results",
 		"component", "benchmark",
-		"prompt_rate_ms_per_token", a,
-		"cached_prompt_rate_ms_per_token", b,
-		"completion_rate_ms_per_token", c)
+		"prompt_rate_ms_per_token", fit.PromptRate,
+		"cached_prompt_rate_ms_per_token", fit.CachedPromptRate,
+		"completion_rate_ms_per_token", fit.CompletionRate,
+		"fixed_overhead_ms", fit.FixedOverheadMs)
 
 	// Calculate R-squared
 	totalSumSquares := 0.0
 	residualSumSquares := 0.0
 
 	// Log predictions vs actual values
-	slog.Info("Model predictions:", "component", "benchmark")
+	logger.Debug("Model predictions:", "component", "benchmark")
 
 	for i, r := range results {
 		if r == nil {
@@ -536,12 +978,12 @@ func fitCompletionTimeModel(results []*CompletionResult) *ModelFitResult {
 		}
 
 		y := float64(r.ResponseTime.Milliseconds())
-		yPred := a*float64(r.PromptTokens) + b*float64(r.CachedPromptTokens) + c*float64(r.CompletionTokens)
+		yPred := fit.PredictMs(r)
 
 		totalSumSquares += math.Pow(y-meanY, 2)
 		residualSumSquares += math.Pow(y-yPred, 2)
 
-		slog.Info("Prediction",
+		logger.Debug("Prediction",
 			"component", "benchmark",
 			"index", i,
 			"actual_ms", y,
@@ -557,30 +999,46 @@ func fitCompletionTimeModel(results []*CompletionResult) *ModelFitResult {
 		rSquared = 1.0 - (residualSumSquares / totalSumSquares)
 	}
 
-	slog.Info("R-squared calculation",
+	// With few points relative to the fitted coefficients R² is high by construction, so
+	// penalize it by the residual degrees of freedom
+	adjustedRSquared := 0.0
+	if n, p := float64(len(X)), float64(len(columns)); n > p {
+		adjustedRSquared = 1.0 - (1.0-rSquared)*(n-1)/(n-p)
+	}
+	rmse := math.Sqrt(residualSumSquares / float64(len(X)))
+
+	logger.Debug("R-squared calculation",
 		"component", "benchmark",
 		"total_sum_squares", totalSumSquares,
 		"residual_sum_squares", residualSumSquares,
-		"r_squared", rSquared)
-
-	// Convert rates from ms/token to tokens/sec for easier interpretation
-	promptRate := 1000.0 / a
-	cachedPromptRate := 1000.0 / b
-	completionRate := 1000.0 / c
+		"r_squared", rSquared,
+		"adjusted_r_squared", adjustedRSquared,
+		"rmse_ms", rmse)
+
+	// Convert rates from ms/token to tokens/sec for easier interpretation, 0 for the
+	// predictors a reduced model dropped
+	tokensPerSec := make([]float64, len(rates))
+	for i, rate := range rates {
+		if rate > 0 {
+			tokensPerSec[i] = 1000.0 / rate
+		}
+	}
 
-	slog.Info("Final model metrics",
+	logger.Info("Final model metrics",
 		"component", "benchmark",
-		"prompt_tokens_per_sec", promptRate,
-		"cached_prompt_tokens_per_sec", cachedPromptRate,
-		"completion_tokens_per_sec", completionRate,
-		"r_squared", rSquared)
-
-	return &ModelFitResult{
-		PromptRate:       a,
-		CachedPromptRate: b,
-		CompletionRate:   c,
-		RSquared:         rSquared,
-	}
+		"model", model,
+		"prompt_tokens_per_sec", tokensPerSec[0],
+		"cached_prompt_tokens_per_sec", tokensPerSec[1],
+		"completion_tokens_per_sec", tokensPerSec[2],
+		"r_squared", rSquared,
+		"adjusted_r_squared", adjustedRSquared)
+
+	fit.RSquared = rSquared
+	fit.AdjustedRSquared = adjustedRSquared
+	fit.RMSE = rmse
+	fit.Model = model
+	fit.NumPoints = validResults
+	return fit
 }
 
 // BenchmarkConfig represents a single benchmark configuration
@@ -591,25 +1049,96 @@ type BenchmarkConfig struct {
 
 // Constants for benchmark quality control
 const (
-	MinAcceptableRSquared  = 0.99 // Minimum acceptable R-squared value
-	MaxBenchmarkIterations = 3    // Maximum number of iterations to try
+	MinAcceptableRSquared  = 0.99 // Default minimum acceptable adjusted R-squared value
+	MaxBenchmarkIterations = 3    // Default maximum number of iterations to try
+	CacheMissRetries       = 2    // Repeats of a cached request after a reported cache miss
+	MinCacheHitRatio       = 0.5  // Cached share of the prompt below which a repeat counts as a miss
+	DefaultCachedRepeats   = 1    // Default number of cached repeats of each prompt
+	DefaultRepeats         = 1    // Default number of measurements of each config
+	DefaultMaxConfigs      = 100  // Default maximum number of configs of a refined grid
 )
 
+// Context benchmarks a result can belong to
+const (
+	ContextShort = "short"
+	ContextLong  = "long"
+)
+
+// Default size of the warmup request sent before the measurements
+const (
+	DefaultWarmupPromptLength = 100 // prompt length in characters
+	DefaultWarmupMaxTokens    = 100 // completion tokens
+)
+
+// densifyConfigs refines the grid of prompt lengths and max tokens spanned by configs by
+// inserting the midpoint between each pair of neighbouring values, and returns every
+// config of the refined grid
+func densifyConfigs(configs []BenchmarkConfig) []BenchmarkConfig {
+	refine := func(values []int) []int {
+		sort.Ints(values)
+		var refined []int
+		for i, v := range values {
+			if i > 0 && v == values[i-1] {
+				continue
+			}
+			if i > 0 && v-values[i-1] > 1 {
+				refined = append(refined, (v+values[i-1])/2)
+			}
+			refined = append(refined, v)
+		}
+		return refined
+	}
+
+	var promptLengths, maxTokens []int
+	for _, config := range configs {
+		promptLengths = append(promptLengths, config.PromptLength)
+		maxTokens = append(maxTokens, config.MaxTokens)
+	}
+
+	var densified []BenchmarkConfig
+	for _, promptLength := range refine(promptLengths) {
+		for _, tokens := range refine(maxTokens) {
+			densified = append(densified, BenchmarkConfig{PromptLength: promptLength, MaxTokens: tokens})
+		}
+	}
+	return densified
+}
+
 // runContextBenchmark runs benchmarks for a specific context size (short or long)
 func (b *Benchmark) runContextBenchmark(contextType string, configs []BenchmarkConfig, postfix string) ([]*CompletionResult, *ModelFitResult, error) {
-	slog.Info(fmt.Sprintf("Running %s context benchmarks", contextType), "component", "benchmark")
+	b.log().Info(fmt.Sprintf("Running %s context benchmarks", contextType), "component", "benchmark")
 
-	// Map to store the fastest result for each token count combination
+	// Map to store all results for each token count combination, aggregated into one
+	// data point per combination for fitting
 	// Key format: "promptTokens:cachedPromptTokens:completionTokens"
-	bestResults := make(map[string]*CompletionResult)
+	samples := make(map[string][]*CompletionResult)
+	aggregated := func() []*CompletionResult {
+		var results []*CompletionResult
+		for _, combinationSamples := range samples {
+			results = append(results, aggregateSamples(combinationSamples, b.Aggregation))
+		}
+		return results
+	}
 
 	// Track which configs have been run
 	configsRun := make(map[string]bool)
 
-	// Run up to MaxBenchmarkIterations
-	for iteration := 1; iteration <= MaxBenchmarkIterations; iteration++ {
-		slog.Info(fmt.Sprintf("Starting %s context benchmark iteration %d/%d",
-			contextType, iteration, MaxBenchmarkIterations), "component", "benchmark")
+	// All raw results, used for latency percentiles, and the raw results per iteration,
+	// used to detect throttling
+	var allResults []*CompletionResult
+	var iterationResults [][]*CompletionResult
+	finishFit := func(modelFit *ModelFitResult) {
+		b.warnEarlyStops(contextType, allResults)
+		setLatencyPercentiles(modelFit, allResults)
+		setStreamingLatencies(modelFit, allResults)
+		b.detectThrottling(contextType, modelFit, iterationResults)
+	}
+
+	// Run up to MaxIterations
+	for iteration := 1; iteration <= b.MaxIterations; iteration++ {
+		b.log().Info(fmt.Sprintf("Starting %s context benchmark iteration %d/%d",
+			contextType, iteration, b.MaxIterations), "component", "benchmark")
+		iterationResults = append(iterationResults, nil)
 
 		// Run benchmarks for configurations that haven't been run yet
 		for _, config := range configs {
@@ -617,27 +1146,44 @@ func (b *Benchmark) runContextBenchmark(contextType string, configs []BenchmarkC
 			configKey := fmt.Sprintf("%d:%d", config.PromptLength, config.MaxTokens)
 
 			// Skip if we've already run this config in a previous iteration
-			if iteration > 1 && configsRun[configKey] {
+			if configsRun[configKey] {
 				continue
 			}
 
+			// Stop if the run was cancelled
+			if err := b.context().Err(); err != nil {
+				return aggregated(), nil, err
+			}
+
 			// Mark this config as run
 			configsRun[configKey] = true
 
-			results, err := b.RunWithPromptLength(config.PromptLength, config.MaxTokens, postfix)
+			// Measure the config Repeats times with new prompts, so the aggregation has
+			// uncached samples to combine
+			var results []*CompletionResult
+			var err error
+			for repeat := 0; repeat < b.Repeats && err == nil; repeat++ {
+				var repeatResults []*CompletionResult
+				repeatResults, err = b.runWithContextRetry(config, postfix)
+				results = append(results, repeatResults...)
+			}
+			b.progress.configsDone(1)
 
 			if err != nil {
-				slog.Error(fmt.Sprintf("%s context benchmark failed", contextType),
+				b.log().Error(fmt.Sprintf("%s context benchmark failed", contextType),
 					"component", "benchmark",
 					"prompt_length", config.PromptLength,
 					"max_tokens", config.MaxTokens,
 					"error", err)
 			} else {
-				// Process each result and keep only the fastest for each token combination
+				// Process each result and group it by token combination
 				for _, result := range results {
 					if result == nil {
 						continue
 					}
+					result.Context = contextType
+					allResults = append(allResults, result)
+					iterationResults[iteration-1] = append(iterationResults[iteration-1], result)
 
 					// Create a key based on token counts
 					key := fmt.Sprintf("%d:%d:%d",
@@ -645,113 +1191,123 @@ func (b *Benchmark) runContextBenchmark(contextType string, configs []BenchmarkC
 						result.CachedPromptTokens,
 						result.CompletionTokens)
 
-					// Check if we already have a result for this token combination
-					existing, exists := bestResults[key]
-					if !exists || result.ResponseTime < existing.ResponseTime {
-						// This is either the first result for this combination or faster than the previous one
-						bestResults[key] = result
-						slog.Info("New best result for token combination",
-							"component", "benchmark",
-							"iteration", iteration,
-							"context_type", contextType,
-							"prompt_tokens", result.PromptTokens,
-							"cached_prompt_tokens", result.CachedPromptTokens,
-							"completion_tokens", result.CompletionTokens,
-							"response_time_ms", result.ResponseTime.Milliseconds())
-					}
+					samples[key] = append(samples[key], result)
+					b.log().Debug("New result for token combination",
+						"component", "benchmark",
+						"iteration", iteration,
+						"context_type", contextType,
+						"prompt_tokens", result.PromptTokens,
+						"cached_prompt_tokens", result.CachedPromptTokens,
+						"completion_tokens", result.CompletionTokens,
+						"response_time_ms", result.ResponseTime.Milliseconds(),
+						"samples", len(samples[key]))
 				}
 			}
 
 			// After each config, check if we have enough data for a good fit
-			if len(bestResults) >= 8 { // Need at least 8 data points for a meaningful fit
-				// Convert map to slice for model fitting
-				var currentResults []*CompletionResult
-				for _, result := range bestResults {
-					currentResults = append(currentResults, result)
-				}
+			if len(samples) >= 8 { // Need at least 8 data points for a meaningful fit
+				// Aggregate the samples for model fitting
+				currentResults := aggregated()
 
 				// Try to fit the model with current results
-				currentFit := fitCompletionTimeModel(currentResults)
+				currentFit := fitCompletionTimeModel(b.log(), b.fitResults(currentResults), b.ClampRates)
 
-				slog.Info(fmt.Sprintf("Intermediate %s model fit after %d configs", contextType, len(configsRun)),
+				b.log().Info(fmt.Sprintf("Intermediate %s model fit after %d configs", contextType, len(configsRun)),
 					"component", "benchmark",
 					"iteration", iteration,
 					"r_squared", currentFit.RSquared,
+					"adjusted_r_squared", currentFit.AdjustedRSquared,
 					"configs_run", len(configsRun))
 
-				// If R-squared is good enough, we can stop
-				if currentFit.RSquared >= MinAcceptableRSquared {
-					slog.Info(fmt.Sprintf("Achieved acceptable R-squared for %s context", contextType),
+				// If the adjusted R-squared is good enough, we can stop
+				if currentFit.AdjustedRSquared >= b.MinRSquared {
+					b.log().Info(fmt.Sprintf("Achieved acceptable R-squared for %s context", contextType),
 						"component", "benchmark",
 						"iteration", iteration,
-						"r_squared", currentFit.RSquared)
+						"adjusted_r_squared", currentFit.AdjustedRSquared)
 
+					finishFit(currentFit)
+					b.progress.configsDone(len(configs) - len(configsRun))
 					return currentResults, currentFit, nil
 				}
 			}
 		}
 
 		// At the end of each iteration, check if we need to continue
-		// Convert map to slice for model fitting
-		var contextResults []*CompletionResult
-		for _, result := range bestResults {
-			contextResults = append(contextResults, result)
-		}
+		// Aggregate the samples for model fitting
+		contextResults := aggregated()
 
-		slog.Info(fmt.Sprintf("Completed iteration %d for %s context with %d results",
+		b.log().Info(fmt.Sprintf("Completed iteration %d for %s context with %d results",
 			iteration, contextType, len(contextResults)), "component", "benchmark")
 
+		// Span the design space more densely in the next iteration. Repeating the same
+		// configs would only average noise, new points improve the fit. Each refinement
+		// about quadruples the grid, so stop before it exceeds MaxConfigs.
+		var refined []BenchmarkConfig
+		if iteration < b.MaxIterations {
+			refined = densifyConfigs(configs)
+			if b.MaxConfigs > 0 && len(refined) > b.MaxConfigs {
+				b.log().Info(fmt.Sprintf("Refined %s context grid would exceed max_configs, stopping", contextType),
+					"component", "benchmark",
+					"configs", len(refined),
+					"max_configs", b.MaxConfigs)
+				refined = nil
+			}
+		}
+
 		// If this is the last iteration or we don't have enough results, return what we have
-		if iteration == MaxBenchmarkIterations || len(contextResults) < 4 {
+		if refined == nil || len(contextResults) < 4 {
 			var modelFit *ModelFitResult
 			if len(contextResults) >= 4 {
-				modelFit = fitCompletionTimeModel(contextResults)
-				slog.Info(fmt.Sprintf("Final %s model fit after %d iterations", contextType, iteration),
+				modelFit = fitCompletionTimeModel(b.log(), b.fitResults(contextResults), b.ClampRates)
+				b.log().Info(fmt.Sprintf("Final %s model fit after %d iterations", contextType, iteration),
 					"component", "benchmark",
-					"r_squared", modelFit.RSquared)
+					"r_squared", modelFit.RSquared,
+					"adjusted_r_squared", modelFit.AdjustedRSquared)
 			} else {
-				slog.Warn(fmt.Sprintf("Not enough data points for %s model fit", contextType),
+				b.log().Warn(fmt.Sprintf("Not enough data points for %s model fit", contextType),
 					"component", "benchmark",
 					"data_points", len(contextResults))
 			}
+			finishFit(modelFit)
 			return contextResults, modelFit, nil
 		}
 
-		// Otherwise, log that we're going to try another iteration
-		slog.Info(fmt.Sprintf("R-squared not acceptable for %s context, running another iteration", contextType),
+		configs = refined
+		b.progress.addConfigs(len(configs) - len(configsRun))
+
+		b.log().Info(fmt.Sprintf("R-squared not acceptable for %s context, running another iteration", contextType),
 			"component", "benchmark",
 			"iteration", iteration,
-			"min_acceptable", MinAcceptableRSquared)
+			"min_acceptable", b.MinRSquared,
+			"new_configs", len(configs)-len(configsRun))
 	}
 
 	// This should never be reached, but just in case
-	var contextResults []*CompletionResult
-	for _, result := range bestResults {
-		contextResults = append(contextResults, result)
-	}
+	contextResults := aggregated()
 
 	var modelFit *ModelFitResult
 	if len(contextResults) >= 4 {
-		modelFit = fitCompletionTimeModel(contextResults)
+		modelFit = fitCompletionTimeModel(b.log(), b.fitResults(contextResults), b.ClampRates)
 	}
+	finishFit(modelFit)
 
 	return contextResults, modelFit, nil
 }
 
 // RunScalingBenchmark runs benchmarks with increasing prompt sizes and different max tokens
 func (b *Benchmark) RunScalingBenchmark(postfix string) ([]*CompletionResult, *ModelFitResult, *ModelFitResult, error) {
-	slog.Info("Starting scaling benchmark", "component", "benchmark", "url", b.URL)
+	b.log().Info("Starting scaling benchmark", "component", "benchmark", "url", b.URL)
 
-	// Run a warmup request to initialize the model
-	slog.Info("Running warmup request", "component", "benchmark")
-	_, warmupErr := b.RunWithPromptLength(100, 100, "Just a warmup request.")
-	if warmupErr != nil {
-		slog.Warn("Warmup request failed (continuing with benchmark)", "component", "benchmark", "error", warmupErr)
+	// Run warmup requests to initialize the model
+	if b.Warmup {
+		b.WarmupResult = b.runWarmup()
 	} else {
-		slog.Info("Warmup request completed successfully", "component", "benchmark")
+		b.log().Info("Skipping warmup request", "component", "benchmark")
 	}
 
-	// Define benchmark configurations
+	// Define benchmark configurations. The one-token requests mostly measure prefill and
+	// determine the prompt rate; servers may report them with 0 completion tokens.
 	shortContextConfigs := []BenchmarkConfig{
 		{PromptLength: 100, MaxTokens: 1},
 		{PromptLength: 100, MaxTokens: 100},
@@ -766,9 +1322,69 @@ func (b *Benchmark) RunScalingBenchmark(postfix string) ([]*CompletionResult, *M
 		{PromptLength: 10000, MaxTokens: 100},
 	}
 
+	// Pin the prompts to the token counts measured with the server's tokenizer, or land
+	// them near the token counts the lengths stand for
+	if len(b.PromptTokens) > 0 {
+		var err error
+		shortContextConfigs, longContextConfigs, err = b.promptTokenConfigs(postfix)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if len(longContextConfigs) == 0 {
+			b.LongContextSkipped = SkippedNoLongTargets
+		}
+	} else if b.CalibratePromptLength {
+		if b.PromptTokensPerByte() == 0 {
+			b.log().Warn("Can't calibrate prompt lengths without a warmup request the server counted tokens of", "component", "benchmark")
+		}
+		shortContextConfigs = b.calibrateConfigs(shortContextConfigs, postfix)
+		longContextConfigs = b.calibrateConfigs(longContextConfigs, postfix)
+	}
+
+	// Shrink the long context prompts if they don't fit the model's context window
+	if b.DetectContext && b.ContextLimit == nil {
+		longestPrompt, mostTokens := 0, 0
+		for _, config := range longContextConfigs {
+			longestPrompt = max(longestPrompt, config.PromptLength)
+			mostTokens = max(mostTokens, config.MaxTokens)
+		}
+		limit, err := b.DetectContextLimit(longestPrompt+mostTokens*bytesPerToken, postfix)
+		if err != nil {
+			b.log().Warn("Context limit detection failed", "component", "benchmark", "error", err)
+		}
+		b.ContextLimit = limit
+	}
+	if b.ContextLimit != nil {
+		// Requests that exceed the context window are guaranteed to fail, don't send them
+		shortContextConfigs = skipConfigsExceedingContext(b.log(), shortContextConfigs, b.ContextLimit, postfix)
+		if b.ContextFit == ContextFitSkip {
+			longContextConfigs = skipConfigsExceedingContext(b.log(), longContextConfigs, b.ContextLimit, postfix)
+		} else {
+			longContextConfigs = fitConfigsToContext(b.log(), longContextConfigs, b.ContextLimit, postfix)
+		}
+	}
+
 	// Run benchmarks for each context size
-	shortContextResults, shortContextModelFit, _ := b.runContextBenchmark("short", shortContextConfigs, postfix)
-	longContextResults, longContextModelFit, _ := b.runContextBenchmark("long", longContextConfigs, postfix)
+	b.progress.addConfigs(len(shortContextConfigs) + len(longContextConfigs))
+	shortContextResults, shortContextModelFit, _ := b.runContextBenchmark(ContextShort, shortContextConfigs, postfix)
+	if err := b.context().Err(); err != nil {
+		return shortContextResults, shortContextModelFit, nil, err
+	}
+
+	var longContextResults []*CompletionResult
+	var longContextModelFit *ModelFitResult
+	if len(longContextConfigs) > 0 {
+		longContextResults, longContextModelFit, _ = b.runContextBenchmark(ContextLong, longContextConfigs, postfix)
+	} else if b.LongContextSkipped == SkippedNoLongTargets {
+		b.log().Info("Skipping long context benchmark, no prompt_tokens target is long enough",
+			"component", "benchmark",
+			"long_context_prompt_tokens", LongContextPromptTokens)
+	} else {
+		b.LongContextSkipped = SkippedExceedsContext
+		b.log().Warn("Skipping long context benchmark, no config fits the context limit",
+			"component", "benchmark",
+			"max_context_tokens", b.ContextLimit.MaxTokens)
+	}
 
 	// Combine all results
 	allResults := append(shortContextResults, longContextResults...)
@@ -779,39 +1395,134 @@ func (b *Benchmark) RunScalingBenchmark(postfix string) ([]*CompletionResult, *M
 	}
 
 	// Log summary of results
-	slog.Info("Scaling benchmark completed",
+	summary := []interface{}{
 		"component", "benchmark",
 		"short_context_configs", len(shortContextResults),
 		"long_context_configs", len(longContextResults),
-		"short_prompt_tokens_per_sec", math.Round((1000.0/shortContextModelFit.PromptRate)*100)/100,
-		"short_completion_tokens_per_sec", math.Round((1000.0/shortContextModelFit.CompletionRate)*100)/100,
-		"short_r_squared", math.Round(shortContextModelFit.RSquared*100)/100,
-		"long_prompt_tokens_per_sec", math.Round((1000.0/longContextModelFit.PromptRate)*100)/100,
-		"long_completion_tokens_per_sec", math.Round((1000.0/longContextModelFit.CompletionRate)*100)/100,
-		"long_r_squared", math.Round(longContextModelFit.RSquared*100)/100)
+	}
+	if shortContextModelFit != nil {
+		summary = append(summary,
+			"short_prompt_tokens_per_sec", rateTokensPerSec(shortContextModelFit.PromptRate),
+			"short_completion_tokens_per_sec", rateTokensPerSec(shortContextModelFit.CompletionRate),
+			"short_r_squared", math.Round(shortContextModelFit.RSquared*100)/100)
+	}
+	if longContextModelFit != nil {
+		summary = append(summary,
+			"long_prompt_tokens_per_sec", rateTokensPerSec(longContextModelFit.PromptRate),
+			"long_completion_tokens_per_sec", rateTokensPerSec(longContextModelFit.CompletionRate),
+			"long_r_squared", math.Round(longContextModelFit.RSquared*100)/100)
+	} else if b.LongContextSkipped != "" {
+		summary = append(summary, "long_context_skipped", b.LongContextSkipped)
+	}
+	b.log().Info("Scaling benchmark completed", summary...)
 
 	return allResults, shortContextModelFit, longContextModelFit, nil
 }
 
+// rateTokensPerSec converts a fitted rate in ms per token to tokens/sec rounded to two
+// decimals, or 0 if the rate is not positive, e.g. dropped by a reduced model
+func rateTokensPerSec(rate float64) float64 {
+	if rate <= 0 {
+		return 0
+	}
+	return math.Round((1000.0/rate)*100) / 100
+}
+
 // MatrixResult contains benchmark results along with the driver parameters used
 type MatrixResult struct {
-	Params               map[string]string
+	Params               map[string]interface{}
 	OutputFlags          map[string]bool
+	FormatOutputFlags    map[string]map[string]bool // overrides OutputFlags per output format: format -> parameter -> shown
 	Results              []*CompletionResult
 	ShortContextModelFit *ModelFitResult
 	LongContextModelFit  *ModelFitResult
+	Concurrency          *ConcurrencyResult
+	Warmup               *WarmupResult
+	Batch                *BatchResult
 	LocalScore           *float64
+	SetupDuration        time.Duration          // wall-clock duration of the driver setup (cold start)
+	TeardownDuration     time.Duration          // wall-clock duration of the driver teardown
+	EnergyJoules         float64                // total energy used by all requests, if power sampling is enabled
+	TokensPerJoule       float64                // prompt and completion tokens processed per joule
+	PromptSeed           int64                  // seed of the prompt generator
+	CompletionSeed       int                    // sampling seed sent with completion requests
+	MaxContextTokens     int                    // configured or detected context window, 0 if unknown
+	PromptTokensPerByte  float64                // prompt tokens the server counted per byte sent, 0 if not reported
+	Protocol             string                 // HTTP protocol of the responses, e.g. "HTTP/2.0", empty if none succeeded
+	LongContextSkipped   string                 // reason the long context benchmark was skipped, e.g. "exceeds context"
+	PromptReductions     []PromptReduction      // configs whose prompts were shortened after overflowing the context
+	PromptTokenTargets   []PromptTokenTarget    // prompt lengths the prompt_tokens targets were resolved to
+	Sampling             Sampling               // sampling parameters sent with the requests
+	PromptSweep          []SweepPoint           // prompt processing speed per prompt length, if a sweep was requested
+	ServedModel          string                 // model name echoed back by the server, empty if not reported
+	FinishReasons        map[string]int         // number of requests per finish reason, e.g. "length" or "stop"
+	ServerConfig         map[string]interface{} // runtime settings reported by the driver's server, e.g. n_ctx
+	SystemInfo           map[string]interface{} // hardware metadata, e.g. hostname and GPU, if system_info or info_cmd is set
 	Error                error
 }
 
-// Run is a package-level function that runs a scaling benchmark with a provided driver
-func Run(d driver.Driver, driverParams map[string]interface{}) ([]*CompletionResult, *ModelFitResult, *ModelFitResult, error) {
+// Run is a package-level function that runs a scaling benchmark with a provided driver.
+// The returned MatrixResult holds the measurements; parameters and scores are filled in by the caller.
+// Progress is logged to logger, or the default logger if nil.
+func Run(d driver.Driver, driverParams map[string]interface{}, logger *slog.Logger) (*MatrixResult, error) {
+	return runCombination(d, driverParams, logger, nil)
+}
+
+// matrixRun is the state of a matrix run its combinations need
+type matrixRun struct {
+	combination   int                                   // index of the running combination in the matrix
+	driverKey     string                                // driver parameters of the running combination
+	rateLimiters  map[rateLimiterKey]*RateLimiter       // shared by the combinations using an endpoint
+	promptTargets map[promptTargetKey]PromptTokenTarget // resolved prompt_tokens targets
+	progress      *progressTracker                      // reports the progress to the run's handlers
+}
+
+// rateLimiterKey identifies an endpoint and the budgets of its rate limiter
+type rateLimiterKey struct {
+	url                                string
+	requestsPerMinute, tokensPerMinute int
+}
+
+// rateLimiter returns the rate limiter for the endpoint and budgets, shared by all
+// combinations of the run, so a budget isn't reset by starting the next combination.
+// Outside of a matrix run (run is nil) a new one is created.
+func (run *matrixRun) rateLimiter(url string, requestsPerMinute int, tokensPerMinute int) *RateLimiter {
+	if run == nil {
+		return NewRateLimiter(requestsPerMinute, tokensPerMinute)
+	}
+	key := rateLimiterKey{url: url, requestsPerMinute: requestsPerMinute, tokensPerMinute: tokensPerMinute}
+	limiter, ok := run.rateLimiters[key]
+	if !ok {
+		limiter = NewRateLimiter(requestsPerMinute, tokensPerMinute)
+		run.rateLimiters[key] = limiter
+	}
+	return limiter
+}
+
+// runCombination runs a scaling benchmark like Run, as a combination of the matrix run
+// if run is not nil
+func runCombination(d driver.Driver, driverParams map[string]interface{}, logger *slog.Logger, run *matrixRun) (*MatrixResult, error) {
+	logger = loggerOrDefault(logger)
+	matrixResult := &MatrixResult{}
+
 	// Setup driver if provided
 	if d != nil {
-		if err := d.Setup(driverParams); err != nil {
-			return nil, nil, nil, fmt.Errorf("driver setup failed: %v", err)
+		setupStart := time.Now()
+		err := d.Setup(driverParams)
+		matrixResult.SetupDuration = time.Since(setupStart)
+		if err != nil {
+			return matrixResult, fmt.Errorf("driver setup failed: %v", err)
 		}
-		defer d.Teardown()
+
+		logger.Info("Driver setup completed", "component", "benchmark", "setup_duration_ms", matrixResult.SetupDuration.Milliseconds())
+
+		defer func() {
+			teardownStart := time.Now()
+			d.Teardown()
+			matrixResult.TeardownDuration = time.Since(teardownStart)
+
+			logger.Info("Driver teardown completed", "component", "benchmark", "teardown_duration_ms", matrixResult.TeardownDuration.Milliseconds())
+		}()
 	}
 
 	// Get URL and model from driver
@@ -821,18 +1532,400 @@ func Run(d driver.Driver, driverParams map[string]interface{}) ([]*CompletionRes
 	if d != nil {
 		url = d.GetURL()
 		model = d.GetModel().Name
+		matrixResult.ServerConfig = serverConfig(d, logger)
+		info, err := systemInfo(d, driverParams, logger)
+		if err != nil {
+			return matrixResult, err
+		}
+		matrixResult.SystemInfo = info
+	}
+
+	err := runBenchmark(context.Background(), url, model, driverParams, driverRequestTimeout(d), logger, run, matrixResult)
+
+	// Run the post command hook before the deferred teardown
+	if postCmd := paramString(driverParams, "post_cmd", ""); postCmd != "" {
+		runPostCommand(postCmd, driverParams, matrixResult, err, logger)
+	}
+
+	return matrixResult, err
+}
+
+// runBenchmark runs the benchmarks configured by the parameters against the URL and
+// stores the measurements in matrixResult. Requests time out after defaultRequestTimeout
+// unless request_timeout_s is set. run is nil outside of a matrix run.
+func runBenchmark(ctx context.Context, url string, model string, driverParams map[string]interface{}, defaultRequestTimeout time.Duration, logger *slog.Logger, run *matrixRun, matrixResult *MatrixResult) error {
+	requestTimeout, benchmarkTimeout, err := timeoutsFromParams(driverParams, defaultRequestTimeout)
+	if err != nil {
+		return err
+	}
+
+	// Fail the combination if its benchmark runs past the overall deadline
+	if benchmarkTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, benchmarkTimeout)
+		defer cancel()
+	}
+
+	// Create benchmark with the given URL and model
+	benchmark := NewBenchmark(url, model, "", requestTimeout)
+	benchmark.Logger = logger
+	benchmark.Context = ctx
+	if run != nil {
+		benchmark.Combination = run.combination
+		benchmark.driverKey = run.driverKey
+		benchmark.promptTargets = run.promptTargets
+		benchmark.progress = run.progress
+	}
+
+	// Select the API schema of the endpoint
+	benchmark.EndpointType = paramString(driverParams, "endpoint_type", EndpointTypeOpenAI)
+	if err := validateEndpointType(benchmark.EndpointType); err != nil {
+		return err
+	}
+	benchmark.URL, err = endpointURL(url, benchmark.EndpointType, driverParams)
+	if err != nil {
+		return err
+	}
+	benchmark.APIKey = paramString(driverParams, "api_key", "")
+	if tokenCmd := paramString(driverParams, "token_cmd", ""); tokenCmd != "" {
+		benchmark.TokenCommand = NewTokenCommand(tokenCmd)
+		if _, err := benchmark.TokenCommand.Token(); err != nil {
+			return err
+		}
+	}
+	benchmark.AnthropicVersion = paramString(driverParams, "anthropic_version", DefaultAnthropicVersion)
+	if captureDir := paramString(driverParams, "capture_dir", ""); captureDir != "" {
+		if err := os.MkdirAll(captureDir, 0755); err != nil {
+			return fmt.Errorf("error creating capture_dir: %v", err)
+		}
+		benchmark.CaptureDir = captureDir
+	}
+	if benchmark.EstimateUsage, err = paramBool(driverParams, "estimate_usage", false); err != nil {
+		return err
+	}
+	if benchmark.Stream, err = paramBool(driverParams, "stream", false); err != nil {
+		return err
+	}
+	if benchmark.Stream && benchmark.EndpointType == EndpointTypeAnthropic {
+		return fmt.Errorf("stream is not supported with endpoint_type %s", EndpointTypeAnthropic)
+	}
+
+	// Compress request and response bodies if requested
+	benchmark.ContentEncoding = paramString(driverParams, "content_encoding", "")
+	if err := validateEncoding("content_encoding", benchmark.ContentEncoding); err != nil {
+		return err
+	}
+	benchmark.AcceptEncoding = paramString(driverParams, "accept_encoding", "")
+	if err := validateEncoding("accept_encoding", benchmark.AcceptEncoding); err != nil {
+		return err
+	}
+
+	// Tune the connections, e.g. so concurrent requests neither open too many
+	// connections nor serialize on a few
+	transport, err := transportFromParams(driverParams)
+	if err != nil {
+		return err
+	}
+	if err := validateProtocol(transport.Protocol, benchmark.URL); err != nil {
+		return err
+	}
+	benchmark.Protocol = transport.Protocol
+	if !transport.isDefault() {
+		benchmark.Client.Transport = transport.NewTransport()
+		logger.Debug("Using custom HTTP transport", "component", "benchmark",
+			"max_idle_conns", transport.MaxIdleConns,
+			"max_idle_conns_per_host", transport.MaxIdleConnsPerHost,
+			"max_conns_per_host", transport.MaxConnsPerHost,
+			"idle_conn_timeout", transport.IdleConnTimeout,
+			"protocol", transport.Protocol)
+	}
+	concurrency, err := paramInt(driverParams, "concurrency", 1)
+	if err != nil {
+		return err
+	}
+	if concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1, got %d", concurrency)
+	}
+	if transport.MaxConnsPerHost > 0 && concurrency > transport.MaxConnsPerHost {
+		logger.Warn("max_conns_per_host is below concurrency, concurrent requests will queue for a connection",
+			"component", "benchmark",
+			"max_conns_per_host", transport.MaxConnsPerHost,
+			"concurrency", concurrency)
+	}
+	batchSize, err := paramInt(driverParams, "batch_size", 0)
+	if err != nil {
+		return err
+	}
+	if batchSize < 0 {
+		return fmt.Errorf("batch_size must not be negative, got %d", batchSize)
+	}
+	var batchURL string
+	if batchSize > 0 {
+		if benchmark.EndpointType != EndpointTypeOpenAI {
+			return fmt.Errorf("batch_size requires endpoint_type %s", EndpointTypeOpenAI)
+		}
+		if batchURL, err = completionsURL(benchmark.URL, paramString(driverParams, "batch_url", "")); err != nil {
+			return err
+		}
+	}
+
+	// Make sure the server is reachable before measuring anything
+	readyTimeout, err := paramInt(driverParams, "ready_timeout_s", int(DefaultReadyTimeout/time.Second))
+	if err != nil {
+		return err
+	}
+	if readyTimeout > 0 {
+		if err := benchmark.WaitForReady(time.Duration(readyTimeout) * time.Second); err != nil {
+			return err
+		}
+	}
+
+	// Make prompt generation reproducible if requested
+	if benchmark.Deterministic, err = paramBool(driverParams, "deterministic", false); err != nil {
+		return err
+	}
+	if _, ok := driverParams["seed"]; ok || benchmark.Deterministic {
+		seed, err := paramInt(driverParams, "seed", 0)
+		if err != nil {
+			return err
+		}
+		benchmark.SetSeed(int64(seed))
+	}
+	if benchmark.CompletionSeed, err = paramInt(driverParams, "completion_seed", DefaultCompletionSeed); err != nil {
+		return err
+	}
+	if benchmark.Sampling, err = samplingFromParams(driverParams); err != nil {
+		return err
+	}
+	if benchmark.ExtraBody, err = extraBodyFromParams(driverParams); err != nil {
+		return err
+	}
+
+	// Parse the prompt sweep up front so a typo fails before the measurements
+	promptSweep, err := parsePromptSweep(driverParams)
+	if err != nil {
+		return err
+	}
+
+	// Trade measurement time against fit quality
+	if benchmark.MaxIterations, err = paramInt(driverParams, "max_iterations", MaxBenchmarkIterations); err != nil {
+		return err
+	}
+	if benchmark.MaxIterations < 1 {
+		return fmt.Errorf("max_iterations must be at least 1, got %d", benchmark.MaxIterations)
+	}
+	if benchmark.MaxConfigs, err = paramInt(driverParams, "max_configs", DefaultMaxConfigs); err != nil {
+		return err
+	}
+	if benchmark.MaxConfigs < 0 {
+		return fmt.Errorf("max_configs must not be negative, got %d", benchmark.MaxConfigs)
+	}
+	if benchmark.MinRSquared, err = paramFloat(driverParams, "min_r_squared", MinAcceptableRSquared); err != nil {
+		return err
+	}
+	if benchmark.MinRSquared <= 0 || benchmark.MinRSquared > 1 {
+		return fmt.Errorf("min_r_squared must be in (0, 1], got %v", benchmark.MinRSquared)
+	}
+	if benchmark.CachedRepeats, err = paramInt(driverParams, "cached_repeats", DefaultCachedRepeats); err != nil {
+		return err
+	}
+	if benchmark.CachedRepeats < 1 {
+		return fmt.Errorf("cached_repeats must be at least 1, got %d", benchmark.CachedRepeats)
+	}
+	measureCache, err := paramBool(driverParams, "measure_cache", true)
+	if err != nil {
+		return err
+	}
+	if !measureCache {
+		// Only the prefill and completion rates are fitted
+		benchmark.CachedRepeats = 0
+	}
+	if benchmark.Repeats, err = paramInt(driverParams, "repeats", DefaultRepeats); err != nil {
+		return err
+	}
+	if benchmark.Repeats < 1 {
+		return fmt.Errorf("repeats must be at least 1, got %d", benchmark.Repeats)
+	}
+	benchmark.Aggregation = paramString(driverParams, "aggregation", AggregationBest)
+	if err := validateAggregation(benchmark.Aggregation); err != nil {
+		return err
+	}
+
+	if benchmark.ClampRates, err = paramBool(driverParams, "clamp_rates", true); err != nil {
+		return err
+	}
+
+	if benchmark.ThrottlingThreshold, err = paramFloat(driverParams, "throttling_threshold", DefaultThrottlingThreshold); err != nil {
+		return err
+	}
+	if benchmark.ThrottlingThreshold < 0 {
+		return fmt.Errorf("throttling_threshold must not be negative, got %v", benchmark.ThrottlingThreshold)
+	}
+
+	// Size the warmup request, or skip it
+	if benchmark.Warmup, err = paramBool(driverParams, "warmup", true); err != nil {
+		return err
+	}
+	if benchmark.WarmupPromptLength, err = paramInt(driverParams, "warmup_prompt_length", DefaultWarmupPromptLength); err != nil {
+		return err
+	}
+	if benchmark.WarmupPromptLength < 1 {
+		return fmt.Errorf("warmup_prompt_length must be at least 1, got %d", benchmark.WarmupPromptLength)
+	}
+	if benchmark.WarmupMaxTokens, err = paramInt(driverParams, "warmup_max_tokens", DefaultWarmupMaxTokens); err != nil {
+		return err
+	}
+	if benchmark.WarmupMaxTokens < 1 {
+		return fmt.Errorf("warmup_max_tokens must be at least 1, got %d", benchmark.WarmupMaxTokens)
+	}
+	benchmark.WarmupMode = paramString(driverParams, "warmup_mode", WarmupModeFixed)
+	if err := validateWarmupMode(benchmark.WarmupMode); err != nil {
+		return err
+	}
+	if benchmark.WarmupCount, err = paramInt(driverParams, "warmup_count", DefaultWarmupCount); err != nil {
+		return err
+	}
+	if benchmark.WarmupCount < 1 {
+		return fmt.Errorf("warmup_count must be at least 1, got %d", benchmark.WarmupCount)
+	}
+	if benchmark.WarmupTolerance, err = paramFloat(driverParams, "warmup_tolerance", DefaultWarmupTolerance); err != nil {
+		return err
+	}
+	if benchmark.WarmupTolerance <= 0 {
+		return fmt.Errorf("warmup_tolerance must be positive, got %v", benchmark.WarmupTolerance)
+	}
+	if benchmark.WarmupMax, err = paramInt(driverParams, "warmup_max", DefaultWarmupMax); err != nil {
+		return err
+	}
+	if benchmark.WarmupMax < 2 {
+		return fmt.Errorf("warmup_max must be at least 2, got %d", benchmark.WarmupMax)
 	}
 
-	// Create benchmark with URL and model from driver
-	benchmark := NewBenchmark(url, model, "")
-	benchmark.Driver = d
+	// Generate prompts from the configured corpus
+	corpus, err := LoadCorpus(paramString(driverParams, "prompt_corpus", DefaultPromptCorpus))
+	if err != nil {
+		return err
+	}
+	benchmark.Corpus = corpus
 
-	postfix := "\nI need some filler content. Please generate as much lorem ipsum as you can."
-	return benchmark.RunScalingBenchmark(postfix)
+	// Fit long context prompts to the model's context window
+	if benchmark.DetectContext, err = paramBool(driverParams, "detect_context", false); err != nil {
+		return err
+	}
+	maxContext, err := paramInt(driverParams, "max_context", 0)
+	if err != nil {
+		return err
+	}
+	if maxContext > 0 {
+		benchmark.ContextLimit = &ContextLimit{MaxTokens: maxContext, Source: "config"}
+	}
+	benchmark.ContextFit = paramString(driverParams, "context_fit", ContextFitScale)
+	if err := validateContextFit(benchmark.ContextFit); err != nil {
+		return err
+	}
+	if benchmark.ContextRetry, err = paramBool(driverParams, "context_retry", true); err != nil {
+		return err
+	}
+	if benchmark.CalibratePromptLength, err = paramBool(driverParams, "calibrate_prompt_length", false); err != nil {
+		return err
+	}
+	if benchmark.PromptTokens, err = parsePromptTokens(driverParams); err != nil {
+		return err
+	}
+	benchmark.EarlyStop = paramString(driverParams, "early_stop", EarlyStopExclude)
+	if err := validateEarlyStop(benchmark.EarlyStop); err != nil {
+		return err
+	}
+
+	// Record the seeds so the run can be reproduced
+	matrixResult.PromptSeed = benchmark.Seed
+	matrixResult.CompletionSeed = benchmark.CompletionSeed
+	matrixResult.Sampling = benchmark.Sampling
+
+	// Measure energy usage if a power command is configured
+	if powerCmd := paramString(driverParams, "power_cmd", ""); powerCmd != "" {
+		intervalMs, err := paramInt(driverParams, "power_interval_ms", int(DefaultPowerSampleInterval/time.Millisecond))
+		if err != nil {
+			return err
+		}
+		benchmark.PowerSampler = NewPowerSampler(powerCmd, time.Duration(intervalMs)*time.Millisecond)
+		benchmark.PowerSampler.Logger = logger
+	}
+
+	// Limit the request rate for metered endpoints
+	requestsPerMinute, err := paramInt(driverParams, "requests_per_minute", 0)
+	if err != nil {
+		return err
+	}
+	tokensPerMinute, err := paramInt(driverParams, "tokens_per_minute", 0)
+	if err != nil {
+		return err
+	}
+	benchmark.RateLimiter = run.rateLimiter(benchmark.URL, requestsPerMinute, tokensPerMinute)
+
+	// Append the instruction asking for a long completion; an empty postfix sends the
+	// bare prompt
+	postfix := DefaultPostfix
+	if _, ok := driverParams["postfix"]; ok {
+		postfix = ""
+		if instruction := paramString(driverParams, "postfix", ""); instruction != "" {
+			postfix = "\n" + instruction
+		}
+	}
+	results, shortContextModelFit, longContextModelFit, err := benchmark.RunScalingBenchmark(postfix)
+	matrixResult.Results = results
+	matrixResult.ShortContextModelFit = shortContextModelFit
+	matrixResult.LongContextModelFit = longContextModelFit
+	matrixResult.EnergyJoules, matrixResult.TokensPerJoule = energyTotals(results)
+	if benchmark.ContextLimit != nil {
+		matrixResult.MaxContextTokens = benchmark.ContextLimit.MaxTokens
+	}
+	matrixResult.LongContextSkipped = benchmark.LongContextSkipped
+	matrixResult.Warmup = benchmark.WarmupResult
+	matrixResult.PromptReductions = benchmark.PromptReductions
+	matrixResult.PromptTokenTargets = benchmark.PromptTokenTargets
+	matrixResult.ServedModel = servedModel(results)
+	matrixResult.FinishReasons = finishReasonCounts(results)
+	matrixResult.PromptTokensPerByte = benchmark.PromptTokensPerByte()
+	matrixResult.Protocol = benchmark.NegotiatedProtocol()
+	if err != nil {
+		return err
+	}
+
+	// Measure throughput under load if requested. An explicit concurrency of 1 is measured
+	// too, as the baseline of a concurrency sweep.
+	if _, ok := driverParams["concurrency"]; ok || concurrency > 1 {
+		concurrencyResult, err := benchmark.RunConcurrencyBenchmark(ConcurrencyPromptLength, ConcurrencyMaxTokens, concurrency, postfix)
+		if err != nil {
+			logger.Error("Concurrency benchmark failed", "component", "benchmark", "concurrency", concurrency, "error", err)
+		}
+		matrixResult.Concurrency = concurrencyResult
+	}
+
+	// Measure batch prompt processing if requested
+	if batchSize > 0 {
+		batchResult, err := benchmark.RunBatchBenchmark(batchURL, BatchPromptLength, batchSize, postfix)
+		if err != nil {
+			logger.Error("Batch benchmark failed", "component", "benchmark", "batch_size", batchSize, "error", err)
+		}
+		matrixResult.Batch = batchResult
+	}
+
+	// Measure prompt processing speed across prompt lengths if requested
+	if len(promptSweep) > 0 {
+		matrixResult.PromptSweep = benchmark.RunPromptSweep(promptSweep, postfix)
+	}
+
+	return nil
 }
 
-// RunMatrix runs benchmarks with all combinations of parameters from the matrix
-func RunMatrix(driverType string, baseParams map[string]interface{}, matrix map[string]types.ParameterConfig) ([]MatrixResult, error) {
+// RunMatrix runs benchmarks with all combinations of parameters from the matrix, or the
+// listed combinations crossed with the rest of the matrix, that are selected by the filter.
+// Progress is logged to logger, or the default logger if nil; drivers log to the default logger.
+// The handlers are called as the run progresses.
+func RunMatrix(driverType string, baseParams map[string]interface{}, matrix map[string]types.ParameterConfig, combinations []map[string]interface{}, filter CombinationFilter, logger *slog.Logger, handlers Handlers) ([]MatrixResult, error) {
+	logger = loggerOrDefault(logger)
+
 	// Create driver first
 	var d driver.Driver
 	var err error
@@ -847,70 +1940,198 @@ func RunMatrix(driverType string, baseParams map[string]interface{}, matrix map[
 	}
 
 	// Generate all combinations of parameters
-	paramCombinations := generateParamCombinations(matrix)
+	paramCombinations := expandCombinations(matrix, combinations)
 
 	// If no combinations were generated, return an error
 	if len(paramCombinations) == 0 {
 		return nil, fmt.Errorf("no parameter combinations generated from matrix")
 	}
 
-	// Extract output flags
+	// Expand model "*" / "all" into the models listed by the server
+	paramCombinations, err = expandModels(d, baseParams, paramCombinations, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply --only / --skip filters
+	paramNames := make(map[string]bool)
+	for k := range matrix {
+		paramNames[k] = true
+	}
+	for _, combination := range combinations {
+		for k := range combination {
+			paramNames[k] = true
+		}
+	}
+	if err := filter.validate(paramNames); err != nil {
+		return nil, err
+	}
+	totalCombinations := len(paramCombinations)
+	paramCombinations = filter.Apply(paramCombinations)
+	if len(paramCombinations) == 0 {
+		return nil, fmt.Errorf("no parameter combinations match the filters (%d in matrix)", totalCombinations)
+	}
+	if len(paramCombinations) < totalCombinations {
+		logger.Info("Filtered matrix combinations", "component", "benchmark", "selected", len(paramCombinations), "total", totalCombinations)
+	}
+	if err := checkMaxCombinations(baseParams, len(paramCombinations)); err != nil {
+		return nil, err
+	}
+
+	// Extract output flags; parameters only set by combinations are included in the output
 	outputFlags := make(map[string]bool)
+	for k := range paramNames {
+		outputFlags[k] = true
+	}
+	formatOutputFlags := make(map[string]map[string]bool)
 	for k, config := range matrix {
 		outputFlags[k] = config.Output
+		for format, output := range config.FormatOutput {
+			if formatOutputFlags[format] == nil {
+				formatOutputFlags[format] = make(map[string]bool)
+			}
+			formatOutputFlags[format][k] = output
+		}
 	}
 
-	// Run benchmark for each combination
-	var matrixResults []MatrixResult
+	// Keep the driver running between combinations that only differ in benchmark-only
+	// parameters if reuse_driver is set
+	benchmarkOnly, err := benchmarkOnlyParams(d, matrix)
+	if err != nil {
+		return nil, err
+	}
+	var reusable *reusableDriver
+	runDriver := d
+	reuse, err := reuseDriverRequested(baseParams, paramCombinations)
+	if err != nil {
+		return nil, err
+	}
+	if d != nil && reuse {
+		paramCombinations = groupByDriver(paramCombinations, baseParams, benchmarkOnly)
+		reusable = &reusableDriver{Driver: d}
+		runDriver = reusable
+	}
 
-	for _, paramSet := range paramCombinations {
-		// Create a copy of base params
-		params := make(map[string]interface{})
-		for k, v := range baseParams {
-			params[k] = v
+	// Run the combinations in a random order if requested, so drift during the run such
+	// as thermal throttling doesn't favour the combinations that run first. The results
+	// are still returned in the order above.
+	runOrder := make([]int, len(paramCombinations))
+	for i := range runOrder {
+		runOrder[i] = i
+	}
+	shuffleSeed, shuffle, err := shuffleRequested(baseParams, paramCombinations)
+	if err != nil {
+		return nil, err
+	}
+	if shuffle {
+		var driverGroup func(i int) string
+		if reusable != nil {
+			driverGroup = func(i int) string {
+				return driverKey(mergeParams(baseParams, paramCombinations[i]), benchmarkOnly)
+			}
 		}
+		runOrder = shuffledOrder(len(paramCombinations), shuffleSeed, driverGroup)
+		logger.Info("Shuffled the combination order", "component", "benchmark", "seed", shuffleSeed)
+	}
+
+	// Run benchmark for each combination
+	matrixResults := make([]MatrixResult, len(paramCombinations))
 
-		// Override with matrix params
-		for k, v := range paramSet {
-			params[k] = v
+	run := &matrixRun{
+		rateLimiters:  make(map[rateLimiterKey]*RateLimiter),
+		promptTargets: make(map[promptTargetKey]PromptTokenTarget),
+		progress:      newProgressTracker(handlers),
+	}
+	run.progress.startRun(len(paramCombinations))
+	for step, i := range runOrder {
+		paramSet := paramCombinations[i]
+		run.progress.startCombination(outputParams(paramSet, outputFlags))
+
+		// Merge base params with matrix params
+		params := mergeParams(baseParams, paramSet)
+
+		// Skip the teardown if the next combination can reuse the driver
+		if reusable != nil {
+			reusable.keep = false
+			if step+1 < len(runOrder) {
+				next := mergeParams(baseParams, paramCombinations[runOrder[step+1]])
+				// reuse_driver values were checked by reuseDriverRequested
+				reuseNext, _ := paramBool(next, "reuse_driver", false)
+				reusable.keep = reuseNext && driverKey(next, benchmarkOnly) == driverKey(params, benchmarkOnly)
+			}
 		}
 
 		// Run benchmark with this parameter set
-		results, shortContextModelFit, longContextModelFit, err := Run(d, params)
+		run.combination = i
+		run.driverKey = driverKey(params, benchmarkOnly)
+		matrixResult, err := runCombination(runDriver, params, logger, run)
 
-		// Calculate LocalScore
-		var localScore *float64
-		if shortContextModelFit != nil || longContextModelFit != nil {
-			modelFits := []*ModelFitResult{shortContextModelFit, longContextModelFit}
-			localScore = Calculate(modelFits)
+		if reusable != nil && reusable.keep {
+			if err != nil {
+				// The server may be in a bad state, start the next combination afresh
+				reusable.keep = false
+				reusable.Teardown()
+			} else {
+				logger.Info("Keeping the driver running for the next combination", "component", "benchmark")
+			}
 		}
 
+		// Calculate LocalScore
+		matrixResult.LocalScore = localScore(matrixResult)
+
 		// Store results with parameter set
-		matrixResult := MatrixResult{
-			Params:               paramSet,
-			OutputFlags:          outputFlags,
-			Results:              results,
-			ShortContextModelFit: shortContextModelFit,
-			LongContextModelFit:  longContextModelFit,
-			LocalScore:           localScore,
-			Error:                err,
-		}
+		matrixResult.Params = paramSet
+		matrixResult.OutputFlags = outputFlags
+		matrixResult.FormatOutputFlags = formatOutputFlags
+		matrixResult.Error = err
 
-		matrixResults = append(matrixResults, matrixResult)
+		matrixResults[i] = *matrixResult
+		run.progress.combinationDone(*matrixResult)
 	}
 
 	return matrixResults, nil
 }
 
+// expandCombinations returns the parameter sets to run: the cross product of the matrix,
+// or if combinations are listed, each combination crossed with the matrix parameters it
+// does not set itself
+func expandCombinations(matrix map[string]types.ParameterConfig, combinations []map[string]interface{}) []map[string]interface{} {
+	if len(combinations) == 0 {
+		return generateParamCombinations(matrix)
+	}
+
+	var paramCombinations []map[string]interface{}
+	for _, combination := range combinations {
+		remaining := make(map[string]types.ParameterConfig)
+		for k, config := range matrix {
+			if _, set := combination[k]; !set {
+				remaining[k] = config
+			}
+		}
+
+		crossed := generateParamCombinations(remaining)
+		if len(crossed) == 0 {
+			crossed = []map[string]interface{}{{}}
+		}
+		for _, paramSet := range crossed {
+			for k, v := range combination {
+				paramSet[k] = v
+			}
+			paramCombinations = append(paramCombinations, paramSet)
+		}
+	}
+	return paramCombinations
+}
+
 // generateParamCombinations generates all possible combinations of parameters from the matrix
-func generateParamCombinations(matrix map[string]types.ParameterConfig) []map[string]string {
+func generateParamCombinations(matrix map[string]types.ParameterConfig) []map[string]interface{} {
 	if len(matrix) == 0 {
 		return nil
 	}
 
 	// Extract keys, values, and output flags
 	var keys []string
-	var valuesList [][]string
+	var valuesList [][]interface{}
 	var outputFlags []bool
 
 	for k, config := range matrix {
@@ -926,21 +2147,21 @@ func generateParamCombinations(matrix map[string]types.ParameterConfig) []map[st
 	}
 
 	// Generate combinations recursively
-	return generateCombinations(keys, valuesList, outputFlags, 0, make(map[string]string), nil)
+	return generateCombinations(keys, valuesList, outputFlags, 0, make(map[string]interface{}), nil)
 }
 
 // generateCombinations recursively generates all combinations of parameters
 func generateCombinations(
 	keys []string,
-	valuesList [][]string,
+	valuesList [][]interface{},
 	outputFlags []bool,
 	index int,
-	current map[string]string,
-	result []map[string]string,
-) []map[string]string {
+	current map[string]interface{},
+	result []map[string]interface{},
+) []map[string]interface{} {
 	if index == len(keys) {
 		// Make a copy of the current combination
-		combination := make(map[string]string)
+		combination := make(map[string]interface{})
 		for k, v := range current {
 			combination[k] = v
 		}
@@ -0,0 +1,892 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aifoundry-org/turtlenekko/internal/driver"
+)
+
+// newTestBenchmark returns a benchmark sending its requests to a test server that
+// answers with handler
+func newTestBenchmark(t *testing.T, handler http.HandlerFunc) *Benchmark {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	b := NewBenchmark(server.URL, "test-model", "", DefaultRequestTimeout)
+	b.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	return b
+}
+
+// respond returns a handler answering every request with the given status, headers and body
+func respond(status int, header map[string]string, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}
+}
+
+var testParams = ChatCompletionParams{
+	Messages:            []ChatMessage{{Role: "user", Content: strings.Repeat("lorem ipsum ", 40)}},
+	MaxCompletionTokens: 16,
+}
+
+func TestChatCompletionSuccess(t *testing.T) {
+	var request ChatCompletionRequest
+	b := newTestBenchmark(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{
+			"model": "served-model",
+			"choices": [{"message": {"role": "assistant", "content": "lorem"}, "finish_reason": "length"}],
+			"usage": {"prompt_tokens": 120, "completion_tokens": 16, "total_tokens": 136,
+				"prompt_tokens_details": {"cached_tokens": 100}}
+		}`)
+	})
+
+	result, err := b.ChatCompletion(testParams)
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+
+	if request.Model != "test-model" || request.MaxTokens != 16 || len(request.Messages) != 1 {
+		t.Errorf("request = %+v, want model test-model, max_tokens 16 and 1 message", request)
+	}
+
+	// prompt_tokens includes the cached tokens
+	if result.PromptTokens != 20 || result.CachedPromptTokens != 100 || result.CompletionTokens != 16 {
+		t.Errorf("tokens = %d prompt, %d cached, %d completion, want 20, 100, 16",
+			result.PromptTokens, result.CachedPromptTokens, result.CompletionTokens)
+	}
+	if !result.CacheReported {
+		t.Error("CacheReported = false, want true")
+	}
+	if result.UsageEstimated {
+		t.Error("UsageEstimated = true, want false")
+	}
+	if result.FinishReason != "length" {
+		t.Errorf("FinishReason = %q, want length", result.FinishReason)
+	}
+	if result.Model != "served-model" {
+		t.Errorf("Model = %q, want served-model", result.Model)
+	}
+	if result.ResponseTime <= 0 {
+		t.Errorf("ResponseTime = %v, want > 0", result.ResponseTime)
+	}
+}
+
+func TestChatCompletionLlamaCppCacheTimings(t *testing.T) {
+	b := newTestBenchmark(t, respond(http.StatusOK, nil, `{
+		"choices": [{"message": {"role": "assistant", "content": "lorem"}, "finish_reason": "stop"}],
+		"usage": {"prompt_tokens": 50, "completion_tokens": 8},
+		"timings": {"cache_n": 80}
+	}`))
+
+	result, err := b.ChatCompletion(testParams)
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+
+	// A cache count above the prompt tokens is capped
+	if result.PromptTokens != 0 || result.CachedPromptTokens != 50 || !result.CacheReported {
+		t.Errorf("tokens = %d prompt, %d cached (reported %t), want 0, 50 (true)",
+			result.PromptTokens, result.CachedPromptTokens, result.CacheReported)
+	}
+}
+
+func TestChatCompletionErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    []string // substrings of the error
+	}{
+		{
+			name:    "server error",
+			handler: respond(http.StatusInternalServerError, map[string]string{"Content-Type": "text/plain"}, "model crashed\n"),
+			want:    []string{"unexpected status code: 500", "text/plain", "model crashed"},
+		},
+		{
+			name:    "rate limited",
+			handler: respond(http.StatusTooManyRequests, map[string]string{"Retry-After": "0"}, `{"error": "slow down"}`),
+			want:    []string{"unexpected status code: 429", "slow down"},
+		},
+		{
+			name:    "malformed JSON",
+			handler: respond(http.StatusOK, nil, `{"choices": [`),
+			want:    []string{"error decoding response", `{"choices": [`},
+		},
+		{
+			name:    "missing usage",
+			handler: respond(http.StatusOK, nil, `{"choices": [{"message": {"role": "assistant", "content": "lorem"}}]}`),
+			want:    []string{"no token usage information"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBenchmark(t, tt.handler)
+
+			result, err := b.ChatCompletion(testParams)
+			if err == nil {
+				t.Fatalf("ChatCompletion = %+v, want error", result)
+			}
+			if result != nil {
+				t.Errorf("result = %+v, want nil", result)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestChatCompletionRateLimitRetry(t *testing.T) {
+	var requests atomic.Int32
+	b := newTestBenchmark(t, func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 2 {
+			respond(http.StatusTooManyRequests, map[string]string{"Retry-After": "0"}, "")(w, r)
+			return
+		}
+		io.WriteString(w, `{"usage": {"prompt_tokens": 10, "completion_tokens": 4}, "choices": [{"message": {"content": "x"}}]}`)
+	})
+
+	result, err := b.ChatCompletion(testParams)
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+	if result.PromptTokens != 10 || result.CompletionTokens != 4 {
+		t.Errorf("tokens = %d prompt, %d completion, want 10, 4", result.PromptTokens, result.CompletionTokens)
+	}
+}
+
+func TestChatCompletionRateLimitExhausted(t *testing.T) {
+	var requests atomic.Int32
+	b := newTestBenchmark(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		respond(http.StatusTooManyRequests, map[string]string{"Retry-After": "0"}, "")(w, r)
+	})
+
+	if _, err := b.ChatCompletion(testParams); err == nil {
+		t.Fatal("ChatCompletion succeeded, want error")
+	}
+	if got, want := int(requests.Load()), MaxRateLimitRetries+1; got != want {
+		t.Errorf("requests = %d, want %d", got, want)
+	}
+}
+
+func TestChatCompletionRateLimitCancel(t *testing.T) {
+	b := newTestBenchmark(t, respond(http.StatusTooManyRequests, map[string]string{"Retry-After": "60"}, ""))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	b.Context = ctx
+
+	start := time.Now()
+	if _, err := b.ChatCompletion(testParams); err == nil {
+		t.Fatal("ChatCompletion succeeded, want error")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("ChatCompletion took %v, want it to stop waiting once the context is done", elapsed)
+	}
+}
+
+func TestMatrixRunRateLimiter(t *testing.T) {
+	run := &matrixRun{rateLimiters: make(map[rateLimiterKey]*RateLimiter)}
+	first := run.rateLimiter("http://a", 60, 0)
+	if first == nil || run.rateLimiter("http://a", 60, 0) != first {
+		t.Error("combinations using the same endpoint and budgets don't share a rate limiter")
+	}
+	if run.rateLimiter("http://b", 60, 0) == first {
+		t.Error("another endpoint shares the rate limiter")
+	}
+}
+
+func TestChatCompletionEstimateUsage(t *testing.T) {
+	b := newTestBenchmark(t, respond(http.StatusOK, nil,
+		`{"choices": [{"message": {"role": "assistant", "content": "lorem ipsum dolor sit amet"}, "finish_reason": "length"}]}`))
+	b.EstimateUsage = true
+
+	result, err := b.ChatCompletion(testParams)
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	if !result.UsageEstimated {
+		t.Error("UsageEstimated = false, want true")
+	}
+	if result.PromptTokens <= 0 || result.CompletionTokens <= 0 {
+		t.Errorf("tokens = %d prompt, %d completion, want estimates > 0", result.PromptTokens, result.CompletionTokens)
+	}
+	if result.CachedPromptTokens != 0 || result.CacheReported {
+		t.Errorf("cached = %d (reported %t), want 0 (false)", result.CachedPromptTokens, result.CacheReported)
+	}
+}
+
+func TestChatCompletionTimeout(t *testing.T) {
+	b := newTestBenchmark(t, func(w http.ResponseWriter, r *http.Request) {
+		// The server notices the client going away once the body is read
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	b.Timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	_, err := b.ChatCompletion(testParams)
+	if err == nil {
+		t.Fatal("ChatCompletion succeeded, want timeout")
+	}
+	if !strings.Contains(err.Error(), "request timed out after 50ms") {
+		t.Errorf("error %q does not name the request timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("ChatCompletion took %v, want it to fail after the timeout", elapsed)
+	}
+}
+
+func TestChatCompletionExtraBody(t *testing.T) {
+	var body map[string]interface{}
+	b := newTestBenchmark(t, func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		io.WriteString(w, `{"usage": {"prompt_tokens": 10, "completion_tokens": 4}, "choices": [{"message": {"content": "x"}}]}`)
+	})
+	b.ExtraBody = map[string]interface{}{
+		"repeat_penalty": 1.1,
+		"n_predict":      64,
+		"model":          "other-model",
+		"max_tokens":     1,
+	}
+
+	if _, err := b.ChatCompletion(testParams); err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	if body["repeat_penalty"] != 1.1 || body["n_predict"] != float64(64) {
+		t.Errorf("extra fields = %v, %v, want 1.1, 64", body["repeat_penalty"], body["n_predict"])
+	}
+	// The standard fields are kept
+	if body["model"] != "test-model" || body["max_tokens"] != float64(16) {
+		t.Errorf("model = %v, max_tokens = %v, want test-model, 16", body["model"], body["max_tokens"])
+	}
+}
+
+func TestFitPrefillOnlySamples(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	point := func(promptTokens, completionTokens int) *CompletionResult {
+		ms := 0.5*float64(promptTokens) + 20*float64(completionTokens)
+		return &CompletionResult{
+			PromptTokens:     promptTokens,
+			CompletionTokens: completionTokens,
+			ResponseTime:     time.Duration(ms * float64(time.Millisecond)),
+		}
+	}
+
+	// The one-token requests came back with 0 completion tokens
+	fit := fitCompletionTimeModel(logger, []*CompletionResult{
+		point(100, 0), point(100, 100), point(500, 0), point(500, 90),
+	}, true)
+	if fit.Model != "prompt+completion" || fit.PromptRate != 0.5 || fit.CompletionRate != 20 {
+		t.Errorf("fit %s: prompt rate %v, completion rate %v, want prompt+completion: 0.5, 20",
+			fit.Model, fit.PromptRate, fit.CompletionRate)
+	}
+
+	fit = fitCompletionTimeModel(logger, []*CompletionResult{point(100, 0), point(500, 0)}, true)
+	if fit.Model != "prompt" || fit.PromptRate != 0.5 || fit.CompletionRate != 0 {
+		t.Errorf("fit %s: prompt rate %v, completion rate %v, want prompt: 0.5, 0",
+			fit.Model, fit.PromptRate, fit.CompletionRate)
+	}
+}
+
+func TestFitFixedOverhead(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	points := func(overheadMs float64) []*CompletionResult {
+		var results []*CompletionResult
+		for _, tokens := range [][2]int{{100, 1}, {100, 100}, {500, 1}, {500, 90}, {1000, 50}} {
+			ms := overheadMs + 0.5*float64(tokens[0]) + 20*float64(tokens[1])
+			results = append(results, &CompletionResult{
+				PromptTokens:     tokens[0],
+				CompletionTokens: tokens[1],
+				ResponseTime:     time.Duration(ms * float64(time.Millisecond)),
+			})
+		}
+		return results
+	}
+
+	fit := fitCompletionTimeModel(logger, points(40), true)
+	if math.Abs(fit.FixedOverheadMs-40) > 1e-6 || math.Abs(fit.PromptRate-0.5) > 1e-6 || math.Abs(fit.CompletionRate-20) > 1e-6 {
+		t.Errorf("fit: overhead %v, prompt rate %v, completion rate %v, want 40, 0.5, 20",
+			fit.FixedOverheadMs, fit.PromptRate, fit.CompletionRate)
+	}
+	if predicted := fit.PredictMs(points(40)[0]); math.Abs(predicted-110) > 1e-6 {
+		t.Errorf("PredictMs = %v, want 110", predicted)
+	}
+
+	// A negative overhead is not real, the model is fitted through the origin
+	if fit := fitCompletionTimeModel(logger, points(-40), true); fit.FixedOverheadMs != 0 {
+		t.Errorf("fit with negative overhead: overhead %v, want 0", fit.FixedOverheadMs)
+	}
+}
+
+func TestFitRatesBelowFloor(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	// The response time doesn't depend on the prompt, so the fitted prompt rate is 0
+	var results []*CompletionResult
+	for _, tokens := range [][2]int{{100, 1}, {100, 100}, {500, 1}, {500, 90}, {1000, 50}} {
+		results = append(results, &CompletionResult{
+			PromptTokens:     tokens[0],
+			CompletionTokens: tokens[1],
+			ResponseTime:     time.Duration(40+20*tokens[1]) * time.Millisecond,
+		})
+	}
+
+	fit := fitCompletionTimeModel(logger, results, true)
+	if fmt.Sprint(fit.RatesBelowFloor) != "[prompt]" || !fit.Clamped || fit.PromptRate != 0.01 {
+		t.Errorf("clamped fit: rates below floor %v, clamped %v, prompt rate %v, want [prompt], true, 0.01",
+			fit.RatesBelowFloor, fit.Clamped, fit.PromptRate)
+	}
+
+	fit = fitCompletionTimeModel(logger, results, false)
+	if fmt.Sprint(fit.RatesBelowFloor) != "[prompt]" || fit.Clamped || math.Abs(fit.PromptRate) > 1e-6 {
+		t.Errorf("raw fit: rates below floor %v, clamped %v, prompt rate %v, want [prompt], false, 0",
+			fit.RatesBelowFloor, fit.Clamped, fit.PromptRate)
+	}
+}
+
+func TestCalibrateConfigs(t *testing.T) {
+	b := NewBenchmark("", "test-model", "", DefaultRequestTimeout)
+	b.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	configs := []BenchmarkConfig{{PromptLength: 100, MaxTokens: 1}, {PromptLength: 10000, MaxTokens: 100}}
+
+	if got := b.calibrateConfigs(configs, ""); got[1].PromptLength != 10000 {
+		t.Errorf("calibrated to %d bytes without a ratio, want 10000", got[1].PromptLength)
+	}
+
+	// A tokenizer with 2 bytes per token needs half the bytes for the intended tokens
+	messages := []ChatMessage{{Role: "user", Content: strings.Repeat("x", 1000)}}
+	b.recordPromptRatio(messages, &CompletionResult{PromptTokens: 400, CachedPromptTokens: 100})
+	b.recordPromptRatio(messages, &CompletionResult{PromptTokens: 9999, UsageEstimated: true})
+	if ratio := b.PromptTokensPerByte(); ratio != 0.5 {
+		t.Fatalf("PromptTokensPerByte = %v, want 0.5", ratio)
+	}
+	got := b.calibrateConfigs(configs, "")
+	if got[0].PromptLength != 50 || got[1].PromptLength != 5000 || got[1].MaxTokens != 100 {
+		t.Errorf("calibrated configs %+v, want prompt lengths 50, 5000", got)
+	}
+}
+
+func TestShuffledOrder(t *testing.T) {
+	if a, b := shuffledOrder(10, 7, nil), shuffledOrder(10, 7, nil); fmt.Sprint(a) != fmt.Sprint(b) {
+		t.Errorf("orders with the same seed differ: %v, %v", a, b)
+	}
+
+	// Combinations sharing a driver stay together
+	order := shuffledOrder(6, 7, func(i int) string { return fmt.Sprint(i % 2) })
+	if len(order) != 6 {
+		t.Fatalf("order %v, want 6 combinations", order)
+	}
+	for step := 1; step < len(order); step++ {
+		if step != 3 && order[step]%2 != order[step-1]%2 {
+			t.Errorf("order %v splits a driver group", order)
+			break
+		}
+	}
+
+	seed, shuffle, err := shuffleRequested(map[string]interface{}{"shuffle": "42"}, nil)
+	if err != nil || !shuffle || seed != 42 {
+		t.Errorf("shuffleRequested = %d, %t, %v, want 42, true", seed, shuffle, err)
+	}
+	if _, _, err := shuffleRequested(nil, []map[string]interface{}{{"shuffle": "often"}}); err == nil {
+		t.Error("shuffleRequested accepted an invalid value")
+	}
+}
+
+func TestFitResultsEarlyStop(t *testing.T) {
+	stopped := &CompletionResult{CompletionTokens: 30, FinishReason: "stop"}
+	limited := &CompletionResult{CompletionTokens: 100, FinishReason: "length"}
+	prefill := &CompletionResult{CompletionTokens: 0, FinishReason: "stop"}
+	for _, tt := range []struct {
+		result    *CompletionResult
+		maxTokens int
+		want      bool
+	}{
+		{stopped, 100, true},
+		{limited, 100, false},
+		{prefill, 1, false},
+	} {
+		tt.result.EarlyStop = stoppedEarly(tt.result, tt.maxTokens)
+		if tt.result.EarlyStop != tt.want {
+			t.Errorf("stoppedEarly(%+v, %d) = %t, want %t", tt.result, tt.maxTokens, tt.result.EarlyStop, tt.want)
+		}
+	}
+
+	b := NewBenchmark("", "test-model", "", DefaultRequestTimeout)
+	if got := b.fitResults([]*CompletionResult{stopped, limited, prefill}); len(got) != 2 || got[0] != limited {
+		t.Errorf("fitResults kept %d results, want the limited and the prefill one", len(got))
+	}
+	b.EarlyStop = EarlyStopKeep
+	if got := b.fitResults([]*CompletionResult{stopped, limited, prefill}); len(got) != 3 {
+		t.Errorf("fitResults with keep kept %d results, want 3", len(got))
+	}
+}
+
+func TestRepeatsAggregation(t *testing.T) {
+	// Each new prompt takes 100ms, 300ms or 200ms in turn
+	delays := []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 200 * time.Millisecond}
+	var requests atomic.Int32
+	b := newTestBenchmark(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delays[(requests.Add(1)-1)%int32(len(delays))])
+		io.WriteString(w, `{"choices": [{"finish_reason": "length"}], "usage": {"prompt_tokens": 30, "completion_tokens": 10}}`)
+	})
+	b.CachedRepeats = 0
+	b.MaxIterations = 1
+	b.Repeats = len(delays)
+	configs := []BenchmarkConfig{{PromptLength: 100, MaxTokens: 10}}
+
+	responseTime := func(aggregation string) time.Duration {
+		b.Aggregation = aggregation
+		results, _, _ := b.runContextBenchmark(ContextShort, configs, "")
+		if len(results) != 1 {
+			t.Fatalf("%s: got %d data points, want the repeats combined into 1", aggregation, len(results))
+		}
+		return results[0].ResponseTime
+	}
+	best, median := responseTime(AggregationBest), responseTime(AggregationMedian)
+	if got := int(requests.Load()); got != 2*len(delays) {
+		t.Errorf("sent %d requests, want %d", got, 2*len(delays))
+	}
+	if best >= 200*time.Millisecond || median < 200*time.Millisecond || median >= 300*time.Millisecond {
+		t.Errorf("best = %v, median = %v, want about 100ms and 200ms", best, median)
+	}
+}
+
+func TestMaxConfigs(t *testing.T) {
+	var requests atomic.Int32
+	b := newTestBenchmark(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		var req ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		fmt.Fprintf(w, `{"choices": [{"finish_reason": "length"}], "usage": {"prompt_tokens": %d, "completion_tokens": %d}}`,
+			len(req.Messages[0].Content)/4, req.MaxTokens)
+	})
+	b.CachedRepeats = 0
+	b.MinRSquared = 1
+	b.MaxIterations = 3
+	configs := []BenchmarkConfig{
+		{PromptLength: 100, MaxTokens: 1}, {PromptLength: 100, MaxTokens: 9},
+		{PromptLength: 500, MaxTokens: 1}, {PromptLength: 500, MaxTokens: 9},
+	}
+
+	// The first refinement grows the grid to 9 configs, the second to 25
+	b.MaxConfigs = 9
+	results, _, _ := b.runContextBenchmark(ContextShort, configs, "")
+	if got := int(requests.Load()); got != 9 || len(results) != 9 {
+		t.Errorf("sent %d requests for %d data points, want the 9 configs of the first refinement", got, len(results))
+	}
+}
+
+func TestTimeoutsFromParams(t *testing.T) {
+	defaultTimeout := driverRequestTimeout(driver.NewMockDriver())
+	if defaultTimeout == DefaultRequestTimeout {
+		t.Fatalf("mock driver uses the benchmark default %v", defaultTimeout)
+	}
+	if got := driverRequestTimeout(nil); got != DefaultRequestTimeout {
+		t.Errorf("default without a driver = %v, want %v", got, DefaultRequestTimeout)
+	}
+
+	requestTimeout, _, err := timeoutsFromParams(map[string]interface{}{}, defaultTimeout)
+	if err != nil || requestTimeout != defaultTimeout {
+		t.Errorf("request timeout = %v (%v), want the driver's %v", requestTimeout, err, defaultTimeout)
+	}
+	requestTimeout, _, err = timeoutsFromParams(map[string]interface{}{"request_timeout_s": 2}, defaultTimeout)
+	if err != nil || requestTimeout != 2*time.Second {
+		t.Errorf("request timeout = %v (%v), want 2s", requestTimeout, err)
+	}
+}
+
+func TestParamInvalidValues(t *testing.T) {
+	params := map[string]interface{}{"whole": 4.0, "fraction": 2.7, "word": "three", "flag": "maybe", "rate": "fast"}
+	if got, err := paramInt(params, "whole", 3); err != nil || got != 4 {
+		t.Errorf("paramInt(4.0) = %d (%v), want 4", got, err)
+	}
+	if got, err := paramInt(params, "unset", 3); err != nil || got != 3 {
+		t.Errorf("paramInt(unset) = %d (%v), want the default 3", got, err)
+	}
+	for _, key := range []string{"fraction", "word"} {
+		if _, err := paramInt(params, key, 3); err == nil {
+			t.Errorf("paramInt(%v) succeeded, want an error", params[key])
+		}
+	}
+	if _, err := paramBool(params, "flag", true); err == nil {
+		t.Error("paramBool(\"maybe\") succeeded, want an error")
+	}
+	if _, err := paramFloat(params, "rate", 1.5); err == nil {
+		t.Error("paramFloat(\"fast\") succeeded, want an error")
+	}
+
+	// An invalid value fails the combination instead of running with the default
+	server := httptest.NewServer(respond(http.StatusOK, nil, `{"choices": [{"finish_reason": "length"}]}`))
+	defer server.Close()
+	err := runBenchmark(context.Background(), server.URL, "test", map[string]interface{}{"max_iterations": "abc"},
+		DefaultRequestTimeout, nil, nil, &MatrixResult{})
+	if err == nil || !strings.Contains(err.Error(), "max_iterations") {
+		t.Errorf("runBenchmark error = %v, want one about max_iterations", err)
+	}
+}
+
+func TestProbeContextLimit(t *testing.T) {
+	var requests atomic.Int32
+	b := newTestBenchmark(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		var req ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Messages[0].Content) > 4000 {
+			respond(http.StatusBadRequest, nil, `{"error": "bad request"}`)(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"choices": [{"finish_reason": "length"}], "usage": {"prompt_tokens": %d, "completion_tokens": 1}}`,
+			len(req.Messages[0].Content)/4)
+	})
+
+	limit, err := b.probeContextLimit(10000, "")
+	if err != nil {
+		t.Fatalf("probeContextLimit: %v", err)
+	}
+	if limit.MaxPromptLength > 4000 || limit.MaxPromptLength < 3900 {
+		t.Errorf("MaxPromptLength = %d, want just below 4000", limit.MaxPromptLength)
+	}
+
+	// Other failures stop the search instead of shrinking the limit
+	b = newTestBenchmark(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		respond(http.StatusInternalServerError, nil, "model crashed")(w, r)
+	})
+	requests.Store(0)
+	if _, err := b.probeContextLimit(10000, ""); err == nil {
+		t.Error("probeContextLimit succeeded against a failing server")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("probe sent %d requests to a failing server, want 1", got)
+	}
+}
+
+func TestCaptureRequest(t *testing.T) {
+	b := newTestBenchmark(t, respond(http.StatusInternalServerError, map[string]string{"Content-Type": "text/plain"}, "model crashed\n"))
+	b.CaptureDir = t.TempDir()
+
+	// The captured body is still read into the error
+	if _, err := b.ChatCompletion(testParams); err == nil || !strings.Contains(err.Error(), "model crashed") {
+		t.Fatalf("ChatCompletion error = %v, want the response body", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(b.CaptureDir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("captured %d files (%v), want 1", len(files), err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var capture struct {
+		StatusCode int                   `json:"status_code"`
+		Request    ChatCompletionRequest `json:"request"`
+		Response   string                `json:"response"`
+	}
+	if err := json.Unmarshal(data, &capture); err != nil {
+		t.Fatalf("decoding capture: %v", err)
+	}
+	if capture.StatusCode != http.StatusInternalServerError || capture.Response != "model crashed\n" {
+		t.Errorf("capture = status %d, response %q", capture.StatusCode, capture.Response)
+	}
+	if capture.Request.MaxTokens != 16 {
+		t.Errorf("captured request max_tokens = %d, want 16", capture.Request.MaxTokens)
+	}
+}
+
+func TestProtocol(t *testing.T) {
+	server := httptest.NewUnstartedServer(respond(http.StatusOK, nil,
+		`{"choices": [{"message": {"role": "assistant", "content": "lorem"}}], "usage": {"prompt_tokens": 120, "completion_tokens": 16}}`))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	for _, tt := range []struct {
+		protocol string
+		want     string
+	}{
+		{ProtocolHTTP1, "HTTP/1.1"},
+		{ProtocolHTTP2, "HTTP/2.0"},
+	} {
+		transport := TransportConfig{Protocol: tt.protocol}.NewTransport()
+		transport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig
+
+		b := NewBenchmark(server.URL, "test-model", "", DefaultRequestTimeout)
+		b.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		b.Client.Transport = transport
+		b.Protocol = tt.protocol
+		if _, err := b.ChatCompletion(testParams); err != nil {
+			t.Fatalf("%s: ChatCompletion: %v", tt.protocol, err)
+		}
+		if got := b.NegotiatedProtocol(); got != tt.want {
+			t.Errorf("%s: negotiated %s, want %s", tt.protocol, got, tt.want)
+		}
+	}
+
+	if err := validateProtocol(ProtocolHTTP2, "http://localhost:8080/v1/chat/completions"); err == nil {
+		t.Error("http2 with an http URL succeeded")
+	}
+}
+
+func TestCheckMaxCombinations(t *testing.T) {
+	for _, tt := range []struct {
+		params  map[string]interface{}
+		count   int
+		wantErr bool
+	}{
+		{nil, DefaultMaxCombinations, false},
+		{nil, DefaultMaxCombinations + 1, true},
+		{map[string]interface{}{"max_combinations": 729}, 729, false},
+		{map[string]interface{}{"max_combinations": 0}, 10000, false},
+		{map[string]interface{}{"max_combinations": -1}, 1, true},
+	} {
+		if err := checkMaxCombinations(tt.params, tt.count); (err != nil) != tt.wantErr {
+			t.Errorf("checkMaxCombinations(%v, %d) = %v, want error %t", tt.params, tt.count, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAzureURL(t *testing.T) {
+	got, err := endpointURL("", EndpointTypeAzure, map[string]interface{}{"resource": "res", "deployment": "gpt 4o"})
+	if want := "https://res.openai.azure.com/openai/deployments/gpt%204o/chat/completions?api-version=" + DefaultAzureAPIVersion; err != nil || got != want {
+		t.Errorf("endpointURL = %q, %v, want %q", got, err, want)
+	}
+
+	// The driver URL keeps its api-version
+	driverURL := "http://localhost:8080/openai/deployments/gpt/chat/completions?api-version=2024-06-01"
+	if got, err := endpointURL(driverURL, EndpointTypeAzure, nil); err != nil || got != driverURL {
+		t.Errorf("endpointURL = %q, %v, want %q", got, err, driverURL)
+	}
+
+	if _, err := endpointURL("", EndpointTypeAzure, map[string]interface{}{"deployment": "gpt"}); err == nil {
+		t.Error("deployment without resource or base_url succeeded")
+	}
+
+	b := NewBenchmark(driverURL, "gpt", "", DefaultRequestTimeout)
+	b.EndpointType = EndpointTypeAzure
+	b.APIKey = "secret"
+	req := httptest.NewRequest(http.MethodPost, driverURL, nil)
+	b.setHeaders(req)
+	if req.Header.Get("api-key") != "secret" || req.Header.Get("Authorization") != "" {
+		t.Errorf("headers %v, want the key in api-key", req.Header)
+	}
+}
+
+func TestRunBatchBenchmark(t *testing.T) {
+	maxChoices := 0
+	b := newTestBenchmark(t, func(w http.ResponseWriter, r *http.Request) {
+		var req BatchCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.URL.Path != "/v1/completions" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		choices := len(req.Prompt)
+		if maxChoices > 0 && choices > maxChoices {
+			choices = maxChoices
+		}
+		// Batched prompts take as long as a single one
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprintf(w, `{"choices": [%s], "usage": {"prompt_tokens": %d, "completion_tokens": %d}}`,
+			strings.TrimSuffix(strings.Repeat(`{"finish_reason": "length"},`, choices), ","), 100*len(req.Prompt), len(req.Prompt))
+	})
+	url, err := completionsURL(b.URL+"/v1/chat/completions", "")
+	if err != nil || url != b.URL+"/v1/completions" {
+		t.Fatalf("completionsURL = %q, %v", url, err)
+	}
+
+	result, err := b.RunBatchBenchmark(url, BatchPromptLength, 4, "")
+	if err != nil {
+		t.Fatalf("RunBatchBenchmark: %v", err)
+	}
+	if result.BatchSize != 4 || result.BatchSpeedup < 2 {
+		t.Errorf("batch size %d, speedup %.2f, want 4 and about 4", result.BatchSize, result.BatchSpeedup)
+	}
+
+	// A server answering only the first prompt doesn't support batches
+	maxChoices = 1
+	if _, err := b.RunBatchBenchmark(url, BatchPromptLength, 4, ""); err == nil {
+		t.Error("batch answered with a single choice succeeded")
+	}
+}
+
+func TestRunWithContextRetry(t *testing.T) {
+	b := newTestBenchmark(t, func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Messages[0].Content) > 6000 {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error": {"message": "This model's maximum context length is 1500 tokens.", "code": "context_length_exceeded"}}`)
+			return
+		}
+		io.WriteString(w, `{"choices": [{"finish_reason": "length"}], "usage": {"prompt_tokens": 1000, "completion_tokens": 1}}`)
+	})
+	b.CachedRepeats = 0
+
+	// 10000 is retried at 7500, then accepted at 5625
+	results, err := b.runWithContextRetry(BenchmarkConfig{PromptLength: 10000, MaxTokens: 1}, "")
+	if err != nil || len(results) != 1 {
+		t.Fatalf("runWithContextRetry = %d results, %v", len(results), err)
+	}
+	want := []PromptReduction{{PromptLength: 10000, ReducedPromptLength: 5625, MaxTokens: 1}}
+	if fmt.Sprint(b.PromptReductions) != fmt.Sprint(want) {
+		t.Errorf("PromptReductions = %v, want %v", b.PromptReductions, want)
+	}
+
+	b.ContextRetry = false
+	if _, err := b.runWithContextRetry(BenchmarkConfig{PromptLength: 10000, MaxTokens: 1}, ""); err == nil {
+		t.Error("overflowing config succeeded without context_retry")
+	}
+}
+
+func TestRunWarmupStable(t *testing.T) {
+	var requests int32
+	b := newTestBenchmark(t, func(w http.ResponseWriter, r *http.Request) {
+		// The first request is slow while the server warms up
+		if atomic.AddInt32(&requests, 1) == 1 {
+			time.Sleep(300 * time.Millisecond)
+		} else {
+			time.Sleep(60 * time.Millisecond)
+		}
+		io.WriteString(w, `{"choices": [{"finish_reason": "length"}], "usage": {"prompt_tokens": 30, "completion_tokens": 100}}`)
+	})
+	b.CachedRepeats = 0
+	b.WarmupMode = WarmupModeStable
+	b.WarmupTolerance = 0.5
+
+	if warmup := b.runWarmup(); !warmup.Stable || warmup.Requests != 3 {
+		t.Errorf("stable warmup = %+v, want stable after 3 requests", warmup)
+	}
+
+	b.WarmupMode = WarmupModeFixed
+	b.WarmupCount = 2
+	if warmup := b.runWarmup(); warmup.Stable || warmup.Requests != 2 {
+		t.Errorf("fixed warmup = %+v, want 2 requests", warmup)
+	}
+}
+
+func TestChatCompletionStream(t *testing.T) {
+	b := newTestBenchmark(t, func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream || req.StreamOptions == nil || !req.StreamOptions.IncludeUsage {
+			http.Error(w, "not streamed", http.StatusBadRequest)
+			return
+		}
+		// The first token takes longer than the ones after it
+		time.Sleep(50 * time.Millisecond)
+		for i := 0; i < 4; i++ {
+			if i > 0 {
+				time.Sleep(10 * time.Millisecond)
+			}
+			io.WriteString(w, "data: {\"choices\": [{\"delta\": {\"content\": \"lorem \"}}]}\n\n")
+			w.(http.Flusher).Flush()
+		}
+		io.WriteString(w, "data: {\"choices\": [{\"delta\": {}, \"finish_reason\": \"length\"}]}\n\n")
+		io.WriteString(w, "data: {\"choices\": [], \"usage\": {\"prompt_tokens\": 20, \"completion_tokens\": 4}}\n\n")
+		io.WriteString(w, "data: [DONE]\n\n")
+	})
+	b.Stream = true
+
+	result, err := b.ChatCompletion(ChatCompletionParams{Messages: b.generateMessages(80, ""), MaxCompletionTokens: 4})
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	if result.PromptTokens != 20 || result.CompletionTokens != 4 || result.FinishReason != "length" {
+		t.Errorf("result = %+v, want 20 prompt and 4 completion tokens, finish reason length", result)
+	}
+	if result.TimeToFirstToken < 50*time.Millisecond || len(result.InterTokenLatencies) != 3 {
+		t.Fatalf("TTFT %v, %d inter-token latencies, want at least 50ms and 3", result.TimeToFirstToken, len(result.InterTokenLatencies))
+	}
+	if result.MeanInterTokenLatency < 10*time.Millisecond || result.MeanInterTokenLatency > result.TimeToFirstToken {
+		t.Errorf("mean ITL %v, want between 10ms and the TTFT %v", result.MeanInterTokenLatency, result.TimeToFirstToken)
+	}
+	if result.ResponseTime < result.TimeToFirstToken+30*time.Millisecond {
+		t.Errorf("response time %v doesn't cover the stream", result.ResponseTime)
+	}
+
+	fit := &ModelFitResult{}
+	setStreamingLatencies(fit, []*CompletionResult{result})
+	if fit.TTFTMean <= 0 || fit.ITLTokensPerSec <= 0 || fit.ITLTokensPerSec > 100 {
+		t.Errorf("fit = %+v, want a TTFT and at most 100 tokens/sec between tokens", fit)
+	}
+}
+
+func TestResolvePromptTokens(t *testing.T) {
+	var requests int32
+	b := newTestBenchmark(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		var req ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		// A tokenizer with 3 bytes per token and 10 tokens of chat template
+		fmt.Fprintf(w, `{"choices": [{"finish_reason": "length"}], "usage": {"prompt_tokens": %d, "completion_tokens": 1}}`,
+			len(req.Messages[0].Content)/3+10)
+	})
+
+	if _, err := parsePromptTokens(map[string]interface{}{"prompt_tokens": "2048,4096"}); err == nil {
+		t.Error("prompt_tokens without a short context target was accepted")
+	}
+	targets, err := parsePromptTokens(map[string]interface{}{"prompt_tokens": "2048, 128"})
+	if err != nil || len(targets) != 2 || targets[0] != 128 {
+		t.Fatalf("parsePromptTokens = %v, %v, want [128 2048]", targets, err)
+	}
+	for _, list := range []interface{}{[]int{2048, 128}, []interface{}{2048, "128"}} {
+		if got, err := parsePromptTokens(map[string]interface{}{"prompt_tokens": list}); err != nil || len(got) != 2 || got[0] != 128 {
+			t.Errorf("parsePromptTokens(%#v) = %v, %v, want [128 2048]", list, got, err)
+		}
+	}
+
+	b.PromptTokens = targets
+	short, long, err := b.promptTokenConfigs("")
+	if err != nil {
+		t.Fatalf("promptTokenConfigs: %v", err)
+	}
+	if len(short) != 2 || len(long) != 2 {
+		t.Fatalf("got %d short and %d long configs, want 2 each", len(short), len(long))
+	}
+	for _, target := range b.PromptTokenTargets {
+		if math.Abs(float64(target.MeasuredTokens-target.Tokens)) > math.Max(1, PromptTokensTolerance*float64(target.Tokens)) {
+			t.Errorf("target %d resolved to %d characters counted as %d tokens", target.Tokens, target.PromptLength, target.MeasuredTokens)
+		}
+	}
+
+	// Resolved targets are cached for the same endpoint and model
+	sent := atomic.LoadInt32(&requests)
+	if _, _, err := b.promptTokenConfigs(""); err != nil || atomic.LoadInt32(&requests) != sent {
+		t.Errorf("resolving cached targets sent %d requests, %v", atomic.LoadInt32(&requests)-sent, err)
+	}
+}
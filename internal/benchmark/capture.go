@@ -0,0 +1,92 @@
+package benchmark
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// captureRunID prefixes the capture file names, so runs capturing to the same directory
+// don't overwrite each other's files
+var captureRunID = time.Now().Format("20060102-150405")
+
+// captureCount numbers the captured requests of all combinations in the order they were sent
+var captureCount int64
+
+// requestCapture is the content of a capture file: a request as sent and the response
+// as received, decompressed
+type requestCapture struct {
+	Time           time.Time         `json:"time"`
+	URL            string            `json:"url"`
+	ResponseTimeMs float64           `json:"response_time_ms"`
+	StatusCode     int               `json:"status_code,omitempty"`
+	Error          string            `json:"error,omitempty"`
+	Request        interface{}       `json:"request"`
+	ResponseHeader map[string]string `json:"response_header,omitempty"`
+	Response       interface{}       `json:"response,omitempty"`
+}
+
+// captureBody returns a body as raw JSON if it is valid JSON, otherwise as a string
+func captureBody(data []byte) interface{} {
+	if json.Valid(data) {
+		return json.RawMessage(data)
+	}
+	return string(data)
+}
+
+// captureRequest writes a request body sent to url, the response and its timing to a new file in
+// CaptureDir. The response body is read and replaced by a copy, so the caller reads it
+// as usual. Failing to capture is logged and doesn't fail the request.
+func (b *Benchmark) captureRequest(url string, startTime time.Time, requestBody []byte, resp *http.Response, responseTime time.Duration, requestErr error) {
+	capture := requestCapture{
+		Time:           startTime,
+		URL:            url,
+		ResponseTimeMs: float64(responseTime) / float64(time.Millisecond),
+		Request:        captureBody(requestBody),
+	}
+	if requestErr != nil {
+		capture.Error = requestErr.Error()
+	}
+
+	if resp != nil {
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		if err != nil {
+			capture.Error = fmt.Sprintf("error reading response: %v", err)
+		}
+
+		capture.StatusCode = resp.StatusCode
+		capture.ResponseHeader = make(map[string]string)
+		for key := range resp.Header {
+			capture.ResponseHeader[key] = resp.Header.Get(key)
+		}
+		if resp.Header.Get("Content-Encoding") == EncodingGzip && !resp.Uncompressed {
+			if zr, err := gzip.NewReader(bytes.NewReader(data)); err == nil {
+				if decompressed, err := io.ReadAll(zr); err == nil {
+					data = decompressed
+				}
+			}
+		}
+		capture.Response = captureBody(data)
+	}
+
+	n := atomic.AddInt64(&captureCount, 1)
+	path := filepath.Join(b.CaptureDir, fmt.Sprintf("%s-%06d.json", captureRunID, n))
+	data, err := json.MarshalIndent(capture, "", "  ")
+	if err == nil {
+		err = os.WriteFile(path, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		b.log().Warn("Failed to capture request", "component", "benchmark", "path", path, "error", err)
+		return
+	}
+	b.log().Debug("Captured request", "component", "benchmark", "path", path)
+}
@@ -0,0 +1,67 @@
+package benchmark
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Supported content encodings of request and response bodies
+const (
+	EncodingGzip     = "gzip"
+	EncodingIdentity = "identity"
+)
+
+// validateEncoding checks that an encoding parameter is supported. Empty leaves the
+// HTTP client default.
+func validateEncoding(param string, encoding string) error {
+	switch encoding {
+	case "", EncodingGzip, EncodingIdentity:
+		return nil
+	default:
+		return fmt.Errorf("unknown %s: %s (supported: %s, %s)", param, encoding, EncodingGzip, EncodingIdentity)
+	}
+}
+
+// encodeBody compresses a request body with the configured content encoding
+func (b *Benchmark) encodeBody(body []byte) ([]byte, error) {
+	if b.ContentEncoding != EncodingGzip {
+		return body, nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// setEncodingHeaders sets the Content-Encoding and Accept-Encoding headers. Setting
+// Accept-Encoding explicitly turns off the client's transparent decompression, so
+// responses are decompressed by decodeBody.
+func (b *Benchmark) setEncodingHeaders(req *http.Request) {
+	if b.ContentEncoding == EncodingGzip {
+		req.Header.Set("Content-Encoding", EncodingGzip)
+	}
+	if b.AcceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", b.AcceptEncoding)
+	}
+}
+
+// decodeBody returns a reader of the decompressed response body
+func decodeBody(resp *http.Response) (io.Reader, error) {
+	if resp.Header.Get("Content-Encoding") != EncodingGzip || resp.Uncompressed {
+		return resp.Body, nil
+	}
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing response: %v", err)
+	}
+	return zr, nil
+}
@@ -0,0 +1,99 @@
+package benchmark
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Prompt size used for concurrent throughput measurements
+const (
+	ConcurrencyPromptLength = 500
+	ConcurrencyMaxTokens    = 100
+)
+
+// ConcurrencyResult contains throughput and latency measured with multiple in-flight requests
+type ConcurrencyResult struct {
+	Concurrency                      int
+	ConcurrentPromptTokensPerSec     float64 // aggregate prompt tokens/sec across all requests
+	ConcurrentCompletionTokensPerSec float64 // aggregate completion tokens/sec across all requests
+	SingleLatencyMs                  float64 // latency of a single request with no other load
+	MeanLatencyMs                    float64 // mean latency of the concurrent requests
+	LatencyDegradation               float64 // MeanLatencyMs / SingleLatencyMs
+}
+
+// RunConcurrencyBenchmark fires concurrency simultaneous requests and measures aggregate
+// throughput and per-request latency degradation compared to a single request
+func (b *Benchmark) RunConcurrencyBenchmark(promptLength int, maxCompletionTokens int, concurrency int, postfix string) (*ConcurrencyResult, error) {
+	b.log().Info("Running concurrency benchmark",
+		"component", "benchmark",
+		"prompt_length", promptLength,
+		"max_tokens", maxCompletionTokens,
+		"concurrency", concurrency)
+
+	// Measure a single request first as the latency baseline
+	single, err := b.ChatCompletion(b.completionParams(b.generateMessages(promptLength, postfix), maxCompletionTokens))
+	if err != nil {
+		return nil, fmt.Errorf("baseline request failed: %v", err)
+	}
+
+	// Generate all prompts up front so each request has its own unique prefix
+	params := make([]ChatCompletionParams, concurrency)
+	for i := range params {
+		params[i] = b.completionParams(b.generateMessages(promptLength, postfix), maxCompletionTokens)
+	}
+
+	results := make([]*CompletionResult, concurrency)
+	errs := make([]error, concurrency)
+
+	var wg sync.WaitGroup
+	startTime := time.Now()
+	for i := range params {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = b.ChatCompletion(params[i])
+		}(i)
+	}
+	wg.Wait()
+	wallTime := time.Since(startTime)
+
+	// Aggregate successful requests
+	var promptTokens, completionTokens, succeeded int
+	var totalLatency time.Duration
+	for i, result := range results {
+		if errs[i] != nil {
+			b.log().Error("Concurrent request failed", "component", "benchmark", "index", i, "error", errs[i])
+			continue
+		}
+		promptTokens += result.PromptTokens
+		completionTokens += result.CompletionTokens
+		totalLatency += result.ResponseTime
+		succeeded++
+	}
+
+	if succeeded == 0 {
+		return nil, fmt.Errorf("all %d concurrent requests failed", concurrency)
+	}
+
+	result := &ConcurrencyResult{
+		Concurrency:                      concurrency,
+		ConcurrentPromptTokensPerSec:     float64(promptTokens) / wallTime.Seconds(),
+		ConcurrentCompletionTokensPerSec: float64(completionTokens) / wallTime.Seconds(),
+		SingleLatencyMs:                  float64(single.ResponseTime.Milliseconds()),
+		MeanLatencyMs:                    float64(totalLatency.Milliseconds()) / float64(succeeded),
+	}
+	if result.SingleLatencyMs > 0 {
+		result.LatencyDegradation = result.MeanLatencyMs / result.SingleLatencyMs
+	}
+
+	b.log().Info("Concurrency benchmark completed",
+		"component", "benchmark",
+		"concurrency", concurrency,
+		"succeeded", succeeded,
+		"wall_time_ms", wallTime.Milliseconds(),
+		"completion_tokens_per_sec", result.ConcurrentCompletionTokensPerSec,
+		"latency_degradation", result.LatencyDegradation)
+
+	return result, nil
+}
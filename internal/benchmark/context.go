@@ -0,0 +1,345 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Context limit detection settings
+const (
+	// contextCharsPerToken converts a context size in tokens to a prompt length in
+	// characters when the tokenizer ratio was not measured. Lorem ipsum tokenizes at
+	// 3-4 characters per token, so this errs on the short side.
+	contextCharsPerToken = 3.0
+	// contextProbeSteps is the number of binary search steps used to probe the limit
+	contextProbeSteps = 7
+	// contextInfoTimeout is the timeout of model info requests
+	contextInfoTimeout = 5 * time.Second
+	// ContextRetryFactor shrinks the prompt of a config that overflowed the context
+	ContextRetryFactor = 0.75
+	// contextRetries is the number of times an overflowing config is retried shorter
+	contextRetries = 3
+)
+
+// contextOverflowMessages are parts of the error messages servers answer prompts that
+// exceed the context window with, lowercased: OpenAI and vLLM, llama.cpp, Anthropic
+var contextOverflowMessages = []string{
+	"context_length_exceeded",
+	"maximum context length",
+	"context length exceeded",
+	"exceeds the available context size",
+	"prompt is too long",
+}
+
+// PromptReduction records a config whose prompt was shortened after overflowing the context
+type PromptReduction struct {
+	PromptLength        int // requested prompt length in characters
+	ReducedPromptLength int // prompt length that was accepted
+	MaxTokens           int
+}
+
+// Ways of fitting long context prompts to a context limit they exceed
+const (
+	ContextFitScale = "scale" // shrink the prompts proportionally
+	ContextFitSkip  = "skip"  // skip the configs that don't fit
+)
+
+// SkippedExceedsContext is the reason recorded when no long context config fits the
+// context limit
+const SkippedExceedsContext = "exceeds context"
+
+// validateContextFit checks that the context fit mode is supported
+func validateContextFit(mode string) error {
+	switch mode {
+	case ContextFitScale, ContextFitSkip:
+		return nil
+	default:
+		return fmt.Errorf("unknown context_fit: %s (supported: %s, %s)", mode, ContextFitScale, ContextFitSkip)
+	}
+}
+
+// ContextLimit is the context window of the model served by the endpoint
+type ContextLimit struct {
+	MaxTokens       int     // context window in tokens
+	MaxPromptLength int     // longest prompt (characters) known to fit with one completion token, 0 if not probed
+	CharsPerToken   float64 // measured prompt characters per token, 0 if not probed
+	Source          string  // where the limit came from: "config", "props", "models" or "probe"
+}
+
+// llamaCppProps is the subset of the llama.cpp /props response holding the context size
+type llamaCppProps struct {
+	DefaultGenerationSettings struct {
+		NCtx int `json:"n_ctx"`
+	} `json:"default_generation_settings"`
+}
+
+// openAIModelList is the /models response, with the vLLM max_model_len extension
+type openAIModelList struct {
+	Data []struct {
+		ID          string `json:"id"`
+		MaxModelLen int    `json:"max_model_len"`
+	} `json:"data"`
+}
+
+// serverContextLimit asks the server for the model's context size, trying the llama.cpp
+// /props endpoint and the vLLM /models endpoint. It returns nil if neither reports one.
+func (b *Benchmark) serverContextLimit() *ContextLimit {
+	if b.EndpointType != EndpointTypeOpenAI {
+		return nil
+	}
+
+	client := &http.Client{Timeout: contextInfoTimeout}
+
+	if endpoint, err := url.Parse(b.URL); err == nil {
+		var props llamaCppProps
+		propsURL := endpoint.Scheme + "://" + endpoint.Host + "/props"
+		if err := b.getJSON(client, propsURL, &props); err == nil && props.DefaultGenerationSettings.NCtx > 0 {
+			return &ContextLimit{MaxTokens: props.DefaultGenerationSettings.NCtx, Source: "props"}
+		} else if err != nil {
+			b.log().Debug("Server does not report props", "component", "benchmark", "url", propsURL, "error", err)
+		}
+	}
+
+	if strings.HasSuffix(b.URL, "/chat/completions") {
+		var models openAIModelList
+		modelsURL := strings.TrimSuffix(b.URL, "/chat/completions") + "/models"
+		if err := b.getJSON(client, modelsURL, &models); err == nil {
+			for _, model := range models.Data {
+				if model.MaxModelLen > 0 && (model.ID == b.Model || len(models.Data) == 1) {
+					return &ContextLimit{MaxTokens: model.MaxModelLen, Source: "models"}
+				}
+			}
+		} else {
+			b.log().Debug("Server does not report models", "component", "benchmark", "url", modelsURL, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// getJSON fetches a URL with the configured credentials and decodes the JSON response
+func (b *Benchmark) getJSON(client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	b.setHeaders(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// probePromptLength sends a single request with one completion token and returns the
+// number of prompt tokens, or an error if the prompt does not fit
+func (b *Benchmark) probePromptLength(promptLength int, postfix string) (int, error) {
+	result, err := b.ChatCompletion(b.completionParams(b.generateMessages(promptLength, postfix), 1))
+	if err != nil {
+		return 0, err
+	}
+	return result.PromptTokens + result.CachedPromptTokens, nil
+}
+
+// probeContextLimit finds the largest prompt, up to maxPromptLength characters, that the
+// server accepts. The full length is tried first, so a model with a large enough window
+// costs a single request; otherwise a binary search narrows down the limit.
+func (b *Benchmark) probeContextLimit(maxPromptLength int, postfix string) (*ContextLimit, error) {
+	b.log().Info("Probing context limit", "component", "benchmark", "max_prompt_length", maxPromptLength)
+
+	best := &ContextLimit{Source: "probe"}
+	// accept returns an error if the request failed for another reason than the prompt
+	// not fitting, which would make the search converge on a wrong limit
+	accept := func(promptLength int) (bool, error) {
+		promptTokens, err := b.probePromptLength(promptLength, postfix)
+		if err != nil {
+			if !isPromptTooLong(err) {
+				return false, err
+			}
+			b.log().Debug("Prompt does not fit", "component", "benchmark", "prompt_length", promptLength, "error", err)
+			return false, nil
+		}
+		best.MaxPromptLength = promptLength
+		best.MaxTokens = promptTokens + 1
+		if promptTokens > 0 {
+			best.CharsPerToken = float64(promptLength+len(postfix)) / float64(promptTokens)
+		}
+		return true, nil
+	}
+
+	fits, err := accept(maxPromptLength)
+	if err != nil {
+		return nil, err
+	}
+	if fits {
+		return best, nil
+	}
+
+	low, high := 0, maxPromptLength
+	for step := 0; step < contextProbeSteps; step++ {
+		mid := (low + high) / 2
+		fits, err := accept(mid)
+		if err != nil {
+			return nil, err
+		}
+		if fits {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+
+	if best.MaxTokens == 0 {
+		return nil, fmt.Errorf("no probe request succeeded")
+	}
+	return best, nil
+}
+
+// DetectContextLimit determines the context window of the served model, from the
+// server's model info if available, or else by probing with prompts of up to
+// maxPromptLength characters
+func (b *Benchmark) DetectContextLimit(maxPromptLength int, postfix string) (*ContextLimit, error) {
+	if limit := b.serverContextLimit(); limit != nil {
+		b.log().Info("Server reports context limit", "component", "benchmark", "max_context_tokens", limit.MaxTokens, "source", limit.Source)
+		return limit, nil
+	}
+
+	limit, err := b.probeContextLimit(maxPromptLength, postfix)
+	if err != nil {
+		return nil, fmt.Errorf("error probing context limit: %v", err)
+	}
+
+	b.log().Info("Probed context limit", "component", "benchmark", "max_context_tokens", limit.MaxTokens, "max_prompt_length", limit.MaxPromptLength)
+	return limit, nil
+}
+
+// allowedPromptLength returns the longest prompt, in characters, that leaves room for
+// maxTokens completion tokens in the context limit
+func allowedPromptLength(limit *ContextLimit, maxTokens int, postfix string) float64 {
+	if limit.MaxPromptLength > 0 {
+		return float64(limit.MaxPromptLength) - float64(maxTokens-1)*limit.CharsPerToken
+	}
+	overhead := len(postfix) + 32 // prompt prefix and chat template
+	return float64(limit.MaxTokens-maxTokens)*contextCharsPerToken - float64(overhead)
+}
+
+// skipConfigsExceedingContext returns the configs whose prompt and completion fit the
+// context limit, logging the ones that are skipped
+func skipConfigsExceedingContext(logger *slog.Logger, configs []BenchmarkConfig, limit *ContextLimit, postfix string) []BenchmarkConfig {
+	var fitting []BenchmarkConfig
+	for _, config := range configs {
+		if float64(config.PromptLength) <= allowedPromptLength(limit, config.MaxTokens, postfix) {
+			fitting = append(fitting, config)
+			continue
+		}
+		logger.Warn("Skipping config that exceeds the context limit",
+			"component", "benchmark",
+			"prompt_length", config.PromptLength,
+			"max_tokens", config.MaxTokens,
+			"max_context_tokens", limit.MaxTokens)
+	}
+	return fitting
+}
+
+// fitConfigsToContext scales the prompt lengths of configs down proportionally so that
+// the longest prompt plus its completion fits the context limit. If the limit is too
+// small to scale to, the configs that don't fit are skipped.
+func fitConfigsToContext(logger *slog.Logger, configs []BenchmarkConfig, limit *ContextLimit, postfix string) []BenchmarkConfig {
+	longestPrompt, mostTokens := 0, 0
+	for _, config := range configs {
+		longestPrompt = max(longestPrompt, config.PromptLength)
+		mostTokens = max(mostTokens, config.MaxTokens)
+	}
+	if longestPrompt == 0 {
+		return configs
+	}
+
+	// The prompt length that leaves room for the longest completion
+	allowed := allowedPromptLength(limit, mostTokens, postfix)
+	if allowed >= float64(longestPrompt) {
+		return configs
+	}
+	if allowed <= 0 {
+		logger.Warn("Context limit is too small to scale prompts", "component", "benchmark", "max_context_tokens", limit.MaxTokens)
+		return skipConfigsExceedingContext(logger, configs, limit, postfix)
+	}
+
+	factor := allowed / float64(longestPrompt)
+	scaled := make([]BenchmarkConfig, len(configs))
+	for i, config := range configs {
+		scaled[i] = BenchmarkConfig{
+			PromptLength: int(float64(config.PromptLength) * factor),
+			MaxTokens:    config.MaxTokens,
+		}
+	}
+
+	logger.Info("Scaled prompt lengths to fit the context limit",
+		"component", "benchmark",
+		"max_context_tokens", limit.MaxTokens,
+		"longest_prompt_length", int(allowed),
+		"factor", factor)
+
+	return scaled
+}
+
+// isContextOverflow reports whether a request failed because the prompt exceeds the
+// model's context window
+func isContextOverflow(err error) bool {
+	message := strings.ToLower(err.Error())
+	for _, overflow := range contextOverflowMessages {
+		if strings.Contains(message, overflow) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPromptTooLong reports whether a probe request failed because its prompt doesn't fit:
+// the server says so, or rejects the request as invalid without naming the context
+func isPromptTooLong(err error) bool {
+	var statusErr *statusCodeError
+	return isContextOverflow(err) || errors.As(err, &statusErr) && statusErr.statusCode == http.StatusBadRequest
+}
+
+// runWithContextRetry runs a config, retrying it with a prompt shortened by
+// ContextRetryFactor each time the server rejects it as exceeding the context window.
+// Accepted reductions are recorded in PromptReductions.
+func (b *Benchmark) runWithContextRetry(config BenchmarkConfig, postfix string) ([]*CompletionResult, error) {
+	results, err := b.RunWithPromptLength(config.PromptLength, config.MaxTokens, postfix)
+	promptLength := config.PromptLength
+	for retry := 0; retry < contextRetries && err != nil && b.ContextRetry && isContextOverflow(err); retry++ {
+		reduced := int(float64(promptLength) * ContextRetryFactor)
+		b.log().Warn("Prompt exceeds the context window, retrying with a shorter prompt",
+			"component", "benchmark",
+			"prompt_length", promptLength,
+			"reduced_prompt_length", reduced,
+			"max_tokens", config.MaxTokens)
+		promptLength = reduced
+		results, err = b.RunWithPromptLength(promptLength, config.MaxTokens, postfix)
+	}
+	if err == nil && promptLength != config.PromptLength {
+		b.log().Warn("Reduced prompt length to fit the context window",
+			"component", "benchmark",
+			"prompt_length", config.PromptLength,
+			"reduced_prompt_length", promptLength,
+			"max_tokens", config.MaxTokens)
+		b.PromptReductions = append(b.PromptReductions, PromptReduction{
+			PromptLength:        config.PromptLength,
+			ReducedPromptLength: promptLength,
+			MaxTokens:           config.MaxTokens,
+		})
+	}
+	return results, err
+}
@@ -0,0 +1,52 @@
+package benchmark
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+)
+
+// DefaultPromptCorpus is the corpus prompts are generated from unless configured
+const DefaultPromptCorpus = "lorem"
+
+// Built-in prompt corpora. Tokenizers split code, JSON and prose very differently,
+// so prompts should resemble the workload being benchmarked.
+var (
+	//go:embed corpora/code.txt
+	codeCorpus string
+	//go:embed corpora/json.txt
+	jsonCorpus string
+	//go:embed corpora/chat.txt
+	chatCorpus string
+)
+
+// builtinCorpora maps the built-in corpus names to their text
+var builtinCorpora = map[string]string{
+	"lorem": loremIpsumText,
+	"code":  codeCorpus,
+	"json":  jsonCorpus,
+	"chat":  chatCorpus,
+}
+
+// IsBuiltinCorpus reports whether name is a built-in corpus rather than a file
+func IsBuiltinCorpus(name string) bool {
+	_, ok := builtinCorpora[name]
+	return ok
+}
+
+// LoadCorpus returns the text of a built-in corpus (lorem, code, json or chat),
+// or else reads the named file
+func LoadCorpus(name string) (string, error) {
+	if text, ok := builtinCorpora[name]; ok {
+		return text, nil
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", fmt.Errorf("error reading prompt corpus (built-in corpora are lorem, code, json and chat): %v", err)
+	}
+	if len(data) == 0 {
+		return "", fmt.Errorf("prompt corpus %s is empty", name)
+	}
+	return string(data), nil
+}
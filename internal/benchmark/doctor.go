@@ -0,0 +1,86 @@
+package benchmark
+
+import (
+	"fmt"
+
+	"github.com/aifoundry-org/turtlenekko/internal/driver"
+	"github.com/aifoundry-org/turtlenekko/internal/types"
+)
+
+// DoctorCheck is the outcome of a prerequisite check and the combinations it was found for
+type DoctorCheck struct {
+	driver.Check
+	Params []map[string]interface{} // output parameters of the combinations
+}
+
+// localCommandParams are the benchmark parameters holding shell commands run on the
+// machine running the benchmark
+var localCommandParams = []string{"token_cmd", "power_cmd", "post_cmd", "info_cmd"}
+
+// Doctor checks the prerequisites of every combination of the matrix, or the listed
+// combinations crossed with the rest of the matrix, without setting up the driver or
+// running the benchmark: those of the driver if it implements driver.Checker, and the
+// commands of command parameters such as post_cmd. Identical outcomes of several
+// combinations are reported once, in the order they were first found.
+func Doctor(driverType string, baseParams map[string]interface{}, matrix map[string]types.ParameterConfig, combinations []map[string]interface{}) ([]DoctorCheck, error) {
+	var checker driver.Checker
+	remoteCommands := false
+	if driverType != "" {
+		d, err := driver.NewDriver(driverType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create driver: %v", err)
+		}
+		checker, _ = d.(driver.Checker)
+		_, remoteCommands = d.(driver.CommandRunner)
+	}
+
+	paramCombinations := expandCombinations(matrix, combinations)
+	if len(paramCombinations) == 0 {
+		return nil, fmt.Errorf("no parameter combinations generated from matrix")
+	}
+
+	outputFlags := make(map[string]bool)
+	for _, paramSet := range paramCombinations {
+		for k := range paramSet {
+			outputFlags[k] = true
+		}
+	}
+	for k, config := range matrix {
+		outputFlags[k] = config.Output
+	}
+
+	var doctorChecks []DoctorCheck
+	index := make(map[string]int)
+	for _, paramSet := range paramCombinations {
+		params := mergeParams(baseParams, paramSet)
+
+		var checks []driver.Check
+		if checker != nil {
+			checks = checker.Check(params)
+		}
+		for _, key := range localCommandParams {
+			// info_cmd runs on the server host with drivers that run commands there
+			if key == "info_cmd" && remoteCommands {
+				continue
+			}
+			if cmd := paramString(params, key, ""); cmd != "" {
+				checks = append(checks, driver.CheckCommand(key, cmd, params)...)
+			}
+		}
+
+		for _, check := range checks {
+			key := check.Name + "\x00" + check.Detail
+			if check.Err != nil {
+				key += "\x00" + check.Err.Error()
+			}
+			i, ok := index[key]
+			if !ok {
+				i = len(doctorChecks)
+				index[key] = i
+				doctorChecks = append(doctorChecks, DoctorCheck{Check: check})
+			}
+			doctorChecks[i].Params = append(doctorChecks[i].Params, outputParams(paramSet, outputFlags))
+		}
+	}
+	return doctorChecks, nil
+}
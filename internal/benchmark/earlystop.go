@@ -0,0 +1,72 @@
+package benchmark
+
+import "fmt"
+
+// Ways of handling requests that stop before their completion limit, e.g. at an
+// end-of-sequence token, which measure fewer completion tokens than intended
+const (
+	EarlyStopExclude   = "exclude"    // leave them out of the fit
+	EarlyStopIgnoreEOS = "ignore_eos" // ask the server to ignore end-of-sequence tokens, and exclude them if they still occur
+	EarlyStopKeep      = "keep"       // fit them like any other request
+)
+
+// validateEarlyStop checks that the early stop mode is supported
+func validateEarlyStop(mode string) error {
+	switch mode {
+	case EarlyStopExclude, EarlyStopIgnoreEOS, EarlyStopKeep:
+		return nil
+	default:
+		return fmt.Errorf("unknown early_stop: %s (supported: %s, %s, %s)", mode, EarlyStopExclude, EarlyStopIgnoreEOS, EarlyStopKeep)
+	}
+}
+
+// stoppedEarly reports whether a request stopped before generating the requested number
+// of completion tokens for another reason than the limit. One-token requests measure
+// the prefill, so it doesn't matter if they stop.
+func stoppedEarly(result *CompletionResult, maxCompletionTokens int) bool {
+	if maxCompletionTokens <= 1 || result.CompletionTokens >= maxCompletionTokens {
+		return false
+	}
+	switch result.FinishReason {
+	case "", "length", "max_tokens":
+		return false
+	default:
+		return true
+	}
+}
+
+// fitResults returns the results the completion time model is fitted to: all of them,
+// or without those that stopped early unless EarlyStop is "keep"
+func (b *Benchmark) fitResults(results []*CompletionResult) []*CompletionResult {
+	if b.EarlyStop == EarlyStopKeep {
+		return results
+	}
+
+	var fitted []*CompletionResult
+	for _, result := range results {
+		if result != nil && result.EarlyStop {
+			continue
+		}
+		fitted = append(fitted, result)
+	}
+	return fitted
+}
+
+// warnEarlyStops warns once per context about the requests that stopped early and
+// were left out of the fit
+func (b *Benchmark) warnEarlyStops(contextType string, results []*CompletionResult) {
+	if b.EarlyStop == EarlyStopKeep {
+		return
+	}
+	stopped := 0
+	for _, result := range results {
+		if result.EarlyStop {
+			stopped++
+		}
+	}
+	if stopped > 0 {
+		b.log().Warn(fmt.Sprintf("Excluded %s context requests that stopped before max_tokens from the fit", contextType),
+			"component", "benchmark",
+			"requests", stopped)
+	}
+}
@@ -0,0 +1,336 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// Supported endpoint types
+const (
+	EndpointTypeOpenAI    = "openai"
+	EndpointTypeAnthropic = "anthropic"
+	EndpointTypeAzure     = "azure" // OpenAI schema, Azure OpenAI deployment URLs and api-key header
+)
+
+// DefaultAnthropicVersion is the anthropic-version header sent to Anthropic-style endpoints
+const DefaultAnthropicVersion = "2023-06-01"
+
+// ErrorSnippetLength is the number of characters of a response body included in errors
+const ErrorSnippetLength = 200
+
+// DefaultAnthropicMaxTokens is used when no completion budget is given, since the
+// Anthropic messages API requires max_tokens
+const DefaultAnthropicMaxTokens = 1024
+
+// AnthropicMessagesRequest represents the request body for an Anthropic-style messages endpoint
+type AnthropicMessagesRequest struct {
+	Model       string        `json:"model"`
+	Messages    []ChatMessage `json:"messages"`
+	MaxTokens   int           `json:"max_tokens"`
+	Temperature float64       `json:"temperature,omitempty"`
+	TopP        float64       `json:"top_p,omitempty"`
+	TopK        int           `json:"top_k,omitempty"`
+}
+
+// AnthropicMessagesResponse represents the response from an Anthropic-style messages endpoint
+type AnthropicMessagesResponse struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens          int  `json:"input_tokens"`
+		OutputTokens         int  `json:"output_tokens"`
+		CacheReadInputTokens *int `json:"cache_read_input_tokens"`
+	} `json:"usage"`
+}
+
+// validateEndpointType checks that the endpoint type is supported
+func validateEndpointType(endpointType string) error {
+	switch endpointType {
+	case EndpointTypeOpenAI, EndpointTypeAnthropic, EndpointTypeAzure:
+		return nil
+	default:
+		return fmt.Errorf("unknown endpoint type: %s", endpointType)
+	}
+}
+
+// defaultEndpointPath returns the path of the chat endpoint of the endpoint type, joined
+// to base_url when no endpoint_path is given
+func defaultEndpointPath(endpointType string) string {
+	if endpointType == EndpointTypeAnthropic {
+		return "/v1/messages"
+	}
+	return "/v1/chat/completions"
+}
+
+// endpointURL returns the URL requests are sent to. With endpoint_path, it is joined to
+// base_url, or to the scheme and host of the driver URL if no base_url is given, e.g.
+// for gateways mounting the API under a prefix. With only base_url and no driver URL,
+// the default path of the endpoint type is joined to it. Otherwise the driver URL is
+// used as is. Azure endpoints are built from the deployment instead, see azureURL.
+func endpointURL(driverURL string, endpointType string, params map[string]interface{}) (string, error) {
+	if endpointType == EndpointTypeAzure {
+		return azureURL(driverURL, params)
+	}
+
+	baseURL := paramString(params, "base_url", "")
+	endpointPath := paramString(params, "endpoint_path", "")
+
+	if endpointPath == "" {
+		if baseURL == "" || driverURL != "" {
+			return driverURL, nil
+		}
+		endpointPath = defaultEndpointPath(endpointType)
+	}
+
+	if baseURL == "" {
+		if driverURL == "" {
+			return "", fmt.Errorf("endpoint_path requires a base_url or a driver url")
+		}
+		u, err := url.Parse(driverURL)
+		if err != nil {
+			return "", fmt.Errorf("invalid driver url: %v", err)
+		}
+		baseURL = (&url.URL{Scheme: u.Scheme, User: u.User, Host: u.Host}).String()
+	}
+
+	return strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(endpointPath, "/"), nil
+}
+
+// marshalRequest builds the JSON request body for the configured endpoint type
+func (b *Benchmark) marshalRequest(params ChatCompletionParams) ([]byte, error) {
+	var request interface{}
+	if b.EndpointType == EndpointTypeAnthropic {
+		maxTokens := params.MaxCompletionTokens
+		if maxTokens <= 0 {
+			maxTokens = DefaultAnthropicMaxTokens
+		}
+		request = AnthropicMessagesRequest{
+			Model:       b.Model,
+			Messages:    params.Messages,
+			MaxTokens:   maxTokens,
+			Temperature: params.Temperature,
+			TopP:        params.TopP,
+			TopK:        params.TopK,
+		}
+	} else {
+		chatRequest := ChatCompletionRequest{
+			Model:       b.Model,
+			Messages:    params.Messages,
+			Temperature: params.Temperature,
+			TopP:        params.TopP,
+			TopK:        params.TopK,
+			MinP:        params.MinP,
+			MaxTokens:   params.MaxCompletionTokens,
+			Seed:        params.Seed,
+			IgnoreEOS:   params.IgnoreEOS,
+		}
+		if b.Stream {
+			// Without the usage chunk the streamed tokens couldn't be counted
+			chatRequest.Stream = true
+			chatRequest.StreamOptions = &StreamOptions{IncludeUsage: true}
+		}
+		request = chatRequest
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	return b.applyExtraBody(body)
+}
+
+// applyExtraBody merges the configured extra_body fields into a JSON request body
+func (b *Benchmark) applyExtraBody(body []byte) ([]byte, error) {
+	if len(b.ExtraBody) == 0 {
+		return body, nil
+	}
+	body, ignored, err := mergeExtraBody(body, b.ExtraBody)
+	if len(ignored) > 0 {
+		b.extraBodyWarning.Do(func() {
+			b.log().Warn("Ignoring extra_body fields the request already sets",
+				"component", "benchmark",
+				"fields", ignored)
+		})
+	}
+	return body, err
+}
+
+// setHeaders sets the content type and authentication headers for the configured endpoint type
+func (b *Benchmark) setHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+
+	if b.EndpointType == EndpointTypeAnthropic {
+		version := b.AnthropicVersion
+		if version == "" {
+			version = DefaultAnthropicVersion
+		}
+		req.Header.Set("anthropic-version", version)
+		if apiKey := b.apiKey(); apiKey != "" {
+			req.Header.Set("x-api-key", apiKey)
+		}
+		return
+	}
+
+	// Azure takes keys in the api-key header; Microsoft Entra ID tokens of a token
+	// command are bearer tokens as usual
+	if b.EndpointType == EndpointTypeAzure && b.TokenCommand == nil {
+		if b.APIKey != "" {
+			req.Header.Set("api-key", b.APIKey)
+		}
+		return
+	}
+
+	if apiKey := b.apiKey(); apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+}
+
+// apiKey returns the current token of the token command, or else the configured API key
+func (b *Benchmark) apiKey() string {
+	if b.TokenCommand != nil {
+		token, err := b.TokenCommand.Token()
+		if err == nil {
+			return token
+		}
+		b.log().Error("Failed to get token", "component", "benchmark", "error", err)
+	}
+	return b.APIKey
+}
+
+// statusCodeError is an unexpected response status of the server
+type statusCodeError struct {
+	statusCode int
+	message    string
+}
+
+func (e *statusCodeError) Error() string {
+	return e.message
+}
+
+// statusError describes an unexpected response status, including the content type and
+// the start of the body, so e.g. an HTML error page from a gateway is recognizable
+func statusError(resp *http.Response) error {
+	body, err := decodeBody(resp)
+	if err != nil {
+		body = resp.Body
+	}
+	data, _ := io.ReadAll(io.LimitReader(body, 4*ErrorSnippetLength))
+	message := fmt.Sprintf("unexpected status code: %d", resp.StatusCode)
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		message += fmt.Sprintf(" (%s)", mediaType)
+	}
+	if snippet := bodySnippet(data); snippet != "" {
+		message += ": " + snippet
+	}
+	return &statusCodeError{statusCode: resp.StatusCode, message: message}
+}
+
+// bodySnippet returns the first ErrorSnippetLength characters of a response body with
+// whitespace collapsed, so multi-line error pages fit on one log line
+func bodySnippet(data []byte) string {
+	snippet := strings.Join(strings.Fields(strings.ToValidUTF8(string(data), "")), " ")
+	if utf8.RuneCountInString(snippet) <= ErrorSnippetLength {
+		return snippet
+	}
+	return string([]rune(snippet)[:ErrorSnippetLength]) + "..."
+}
+
+// decodeResponse parses the response body of the configured endpoint type into a
+// CompletionResult (without timing) and returns the generated text
+func (b *Benchmark) decodeResponse(body io.Reader) (*CompletionResult, string, error) {
+	if b.EndpointType == EndpointTypeAnthropic {
+		var response AnthropicMessagesResponse
+		if err := json.NewDecoder(body).Decode(&response); err != nil {
+			return nil, "", err
+		}
+
+		content := ""
+		for _, block := range response.Content {
+			if block.Type == "text" {
+				content += block.Text
+			}
+		}
+		if len(response.Content) == 0 {
+			b.log().Warn("Response contains no content", "component", "benchmark")
+		}
+
+		// input_tokens excludes tokens read from the prompt cache
+		result := &CompletionResult{
+			PromptTokens:     response.Usage.InputTokens,
+			CompletionTokens: response.Usage.OutputTokens,
+			FinishReason:     response.StopReason,
+			Model:            response.Model,
+		}
+		if response.Usage.CacheReadInputTokens != nil {
+			result.CachedPromptTokens = *response.Usage.CacheReadInputTokens
+			result.CacheReported = true
+		}
+		return result, content, nil
+	}
+
+	var response ChatCompletionResponse
+	if err := json.NewDecoder(body).Decode(&response); err != nil {
+		return nil, "", err
+	}
+	result, content := b.chatCompletionResult(&response)
+	return result, content, nil
+}
+
+// chatCompletionResult extracts the CompletionResult (without timing) and the generated
+// text of a chat completion response
+func (b *Benchmark) chatCompletionResult(response *ChatCompletionResponse) (*CompletionResult, string) {
+	result := &CompletionResult{
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+		Model:            response.Model,
+	}
+
+	content := ""
+	if len(response.Choices) > 0 {
+		content = response.Choices[0].Message.Content
+		result.FinishReason = response.Choices[0].FinishReason
+	} else {
+		b.log().Warn("Response contains no choices", "component", "benchmark")
+	}
+
+	// prompt_tokens includes cached tokens; split them if the server reports a cache hit count
+	if cachedTokens := reportedCachedTokens(response); cachedTokens != nil {
+		cached := *cachedTokens
+		if cached > result.PromptTokens {
+			cached = result.PromptTokens
+		}
+		if cached < 0 {
+			cached = 0
+		}
+		result.CachedPromptTokens = cached
+		result.PromptTokens -= cached
+		result.CacheReported = true
+	}
+
+	return result, content
+}
+
+// reportedCachedTokens returns the number of cached prompt tokens reported by the
+// server (OpenAI prompt_tokens_details.cached_tokens, or llama.cpp timings.cache_n /
+// tokens_cached), or nil if the response does not include one
+func reportedCachedTokens(response *ChatCompletionResponse) *int {
+	if details := response.Usage.PromptTokensDetails; details != nil && details.CachedTokens != nil {
+		return details.CachedTokens
+	}
+	if response.Timings != nil && response.Timings.CacheN != nil {
+		return response.Timings.CacheN
+	}
+	return response.TokensCached
+}
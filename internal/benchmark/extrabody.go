@@ -0,0 +1,69 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// extraBodyPrefix prefixes parameters setting a single extra_body field, e.g.
+// extra_body.repeat_penalty, so it can be swept like any other parameter
+const extraBodyPrefix = "extra_body."
+
+// extraBodyFromParams reads the extra request body fields: the extra_body parameter, an
+// object or a JSON object string as matrix values are scalars, and the extra_body.<field>
+// parameters, which take precedence. It returns nil if none are set.
+func extraBodyFromParams(params map[string]interface{}) (map[string]interface{}, error) {
+	extraBody := make(map[string]interface{})
+	switch v := params["extra_body"].(type) {
+	case nil:
+	case map[string]interface{}:
+		for key, value := range v {
+			extraBody[key] = value
+		}
+	case string:
+		if err := json.Unmarshal([]byte(v), &extraBody); err != nil {
+			return nil, fmt.Errorf("extra_body must be a JSON object: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("extra_body must be an object, got %v", v)
+	}
+
+	for key, value := range params {
+		if field := strings.TrimPrefix(key, extraBodyPrefix); field != key && field != "" {
+			extraBody[field] = value
+		}
+	}
+
+	if len(extraBody) == 0 {
+		return nil, nil
+	}
+	return extraBody, nil
+}
+
+// mergeExtraBody adds the extra fields to a JSON request body. Fields the request already
+// sets are kept, so extra_body can't break the measurement, and returned as ignored.
+func mergeExtraBody(body []byte, extraBody map[string]interface{}) ([]byte, []string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, nil, err
+	}
+
+	var ignored []string
+	for key, value := range extraBody {
+		if _, ok := fields[key]; ok {
+			ignored = append(ignored, key)
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error marshaling extra_body field %s: %v", key, err)
+		}
+		fields[key] = data
+	}
+	sort.Strings(ignored)
+
+	merged, err := json.Marshal(fields)
+	return merged, ignored, err
+}
@@ -0,0 +1,83 @@
+package benchmark
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CombinationFilter selects which matrix combinations are run. A combination is run if it
+// matches at least one Only filter (or there are none) and does not match any Skip filter.
+type CombinationFilter struct {
+	Only []map[string]string
+	Skip []map[string]string
+}
+
+// ParseFilter parses a "key=value,key2=value2" filter expression
+func ParseFilter(expr string) (map[string]string, error) {
+	filter := make(map[string]string)
+	for _, pair := range strings.Split(expr, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, found := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid filter %q: expected key=value", pair)
+		}
+		filter[key] = strings.TrimSpace(value)
+	}
+	if len(filter) == 0 {
+		return nil, fmt.Errorf("empty filter %q", expr)
+	}
+	return filter, nil
+}
+
+// matchesFilter reports whether a parameter set has every key=value pair of the filter
+func matchesFilter(paramSet map[string]interface{}, filter map[string]string) bool {
+	for key, value := range filter {
+		paramValue, exists := paramSet[key]
+		if !exists || fmt.Sprintf("%v", paramValue) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// validate checks that every filter refers to a parameter of the matrix
+func (f CombinationFilter) validate(paramNames map[string]bool) error {
+	for _, filters := range [][]map[string]string{f.Only, f.Skip} {
+		for _, filter := range filters {
+			for key := range filter {
+				if !paramNames[key] {
+					return fmt.Errorf("filter refers to unknown matrix parameter %q", key)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Apply returns the parameter combinations selected by the filter
+func (f CombinationFilter) Apply(paramCombinations []map[string]interface{}) []map[string]interface{} {
+	var selected []map[string]interface{}
+	for _, paramSet := range paramCombinations {
+		keep := len(f.Only) == 0
+		for _, filter := range f.Only {
+			if matchesFilter(paramSet, filter) {
+				keep = true
+				break
+			}
+		}
+		for _, filter := range f.Skip {
+			if matchesFilter(paramSet, filter) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			selected = append(selected, paramSet)
+		}
+	}
+	return selected
+}
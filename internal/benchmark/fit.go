@@ -0,0 +1,117 @@
+package benchmark
+
+import (
+	"math"
+	"strings"
+)
+
+// fitPredictors names the columns of the regression data the completion time model is
+// fitted to: the token counts and a constant column for the fixed per-request overhead
+var fitPredictors = []string{"prompt", "cached", "completion", "overhead"}
+
+// overheadColumn is the column of ones whose coefficient is the fixed overhead of a
+// request (ms), e.g. network round trip and scheduling
+const overheadColumn = 3
+
+// minFittedRates are the lowest rates (ms per token) a fit reports per token predictor
+var minFittedRates = []float64{0.01, 0.001, 0.1}
+
+// FullFitModel is the model fitted when the data determines all three rates
+const FullFitModel = "prompt+cached+completion"
+
+// fitModels are the token predictor subsets tried in order until one is not
+// rank-deficient: the full model, then without the cached prompt term, then without the
+// completion term, then single predictors. Each is tried with the overhead first.
+var fitModels = [][]int{
+	{0, 1, 2},
+	{0, 2},
+	{0, 1},
+	{0},
+	{2},
+}
+
+// singularTolerance is the share of a predictor's variation that must remain after
+// eliminating the other predictors for the system not to count as rank-deficient
+const singularTolerance = 1e-9
+
+// fitModelName names the token predictors of a subset, e.g. "prompt+completion"; the
+// overhead is reported separately
+func fitModelName(columns []int) string {
+	var names []string
+	for _, column := range columns {
+		if column != overheadColumn {
+			names = append(names, fitPredictors[column])
+		}
+	}
+	return strings.Join(names, "+")
+}
+
+// withOverhead returns the columns of a model with the overhead column added
+func withOverhead(model []int) []int {
+	return append(append([]int{}, model...), overheadColumn)
+}
+
+// solveLeastSquares fits y to the given columns of X by solving the normal equations
+// with Gaussian elimination. It returns false if the system is rank-deficient, e.g.
+// because a predictor is always zero or proportional to another one.
+func solveLeastSquares(X [][]float64, y []float64, columns []int) ([]float64, bool) {
+	n := len(columns)
+	if len(X) < n {
+		return nil, false
+	}
+
+	// Augmented normal equations: X^T * X | X^T * y
+	augmented := make([][]float64, n)
+	diagonal := make([]float64, n)
+	for i, ci := range columns {
+		augmented[i] = make([]float64, n+1)
+		for j, cj := range columns {
+			for k := range X {
+				augmented[i][j] += X[k][ci] * X[k][cj]
+			}
+		}
+		for k := range X {
+			augmented[i][n] += X[k][ci] * y[k]
+		}
+		diagonal[i] = augmented[i][i]
+	}
+
+	for i := 0; i < n; i++ {
+		// Find pivot
+		maxRow := i
+		for j := i + 1; j < n; j++ {
+			if math.Abs(augmented[j][i]) > math.Abs(augmented[maxRow][i]) {
+				maxRow = j
+			}
+		}
+		augmented[i], augmented[maxRow] = augmented[maxRow], augmented[i]
+
+		// The pivot is what is left of the predictor after eliminating the previous ones,
+		// relative to its own scale since token counts differ by orders of magnitude
+		if diagonal[i] <= 0 || math.Abs(augmented[i][i]) < singularTolerance*diagonal[i] {
+			return nil, false
+		}
+
+		// Scale row
+		pivot := augmented[i][i]
+		for j := i; j <= n; j++ {
+			augmented[i][j] /= pivot
+		}
+
+		// Eliminate other rows
+		for j := 0; j < n; j++ {
+			if j != i {
+				factor := augmented[j][i]
+				for k := i; k <= n; k++ {
+					augmented[j][k] -= factor * augmented[i][k]
+				}
+			}
+		}
+	}
+
+	coefficients := make([]float64, n)
+	for i := range coefficients {
+		coefficients[i] = augmented[i][n]
+	}
+	return coefficients, true
+}
@@ -0,0 +1,72 @@
+package benchmark
+
+import (
+	"log/slog"
+	"math"
+	"os/exec"
+
+	"github.com/aifoundry-org/turtlenekko/internal/driver"
+)
+
+// postCommandData returns the template variables of the post_cmd hook: the parameters
+// of the combination and its result metrics, named like the JSON output keys
+func postCommandData(params map[string]interface{}, matrixResult *MatrixResult, benchErr error) map[string]interface{} {
+	data := make(map[string]interface{})
+	for k, v := range params {
+		data[k] = v
+	}
+
+	tokensPerSec := func(rate float64) float64 {
+		if rate <= 0 {
+			return 0
+		}
+		return math.Round((1000.0/rate)*100) / 100
+	}
+	for contextType, modelFit := range map[string]*ModelFitResult{
+		"short": matrixResult.ShortContextModelFit,
+		"long":  matrixResult.LongContextModelFit,
+	} {
+		if modelFit == nil {
+			modelFit = &ModelFitResult{}
+		}
+		prefix := contextType + "_context_"
+		data[prefix+"prompt_tokens_per_sec"] = tokensPerSec(modelFit.PromptRate)
+		data[prefix+"cached_prompt_tokens_per_sec"] = tokensPerSec(modelFit.CachedPromptRate)
+		data[prefix+"completion_tokens_per_sec"] = tokensPerSec(modelFit.CompletionRate)
+		data[prefix+"r_squared"] = math.Round(modelFit.RSquared*100) / 100
+		data[prefix+"adjusted_r_squared"] = math.Round(modelFit.AdjustedRSquared*100) / 100
+	}
+
+	data["localscore_estimate"] = 0.0
+	if score := localScore(matrixResult); score != nil {
+		data["localscore_estimate"] = *score
+	}
+	data["energy_joules"] = math.Round(matrixResult.EnergyJoules*100) / 100
+	data["tokens_per_joule"] = math.Round(matrixResult.TokensPerJoule*100) / 100
+	data["served_model"] = matrixResult.ServedModel
+	data["error"] = ""
+	if benchErr != nil {
+		data["error"] = benchErr.Error()
+	}
+	return data
+}
+
+// runPostCommand runs the post_cmd hook after the benchmark of a combination finished,
+// before the driver teardown. Failures are logged but don't fail the combination.
+func runPostCommand(postCmd string, params map[string]interface{}, matrixResult *MatrixResult, benchErr error, logger *slog.Logger) {
+	cmd, err := driver.InterpolateCommand(postCmd, postCommandData(params, matrixResult, benchErr))
+	if err != nil {
+		logger.Error("Failed to prepare post command", "component", "benchmark", "error", err)
+		return
+	}
+
+	logger.Info("Running post command", "component", "benchmark", "command", cmd)
+
+	output, err := exec.Command("sh", "-c", cmd).CombinedOutput()
+	if err != nil {
+		logger.Error("Post command failed", "component", "benchmark", "error", err, "output", string(output))
+		return
+	}
+
+	logger.Info("Post command completed successfully", "component", "benchmark", "output", string(output))
+}
@@ -46,11 +46,17 @@ func Calculate(modelFits []*ModelFitResult) *float64 {
 		// Calculate the geometric mean and apply scaling factor
 		// score = (prompt_tps * gen_tps * (1000/ttft_ms))^(1/3) * 10
 		score := math.Pow(promptTPS*genTPS*(1000.0/ttftMS), 1.0/3.0) * ScalingFactor
-
-		// Round to 2 decimal places
-		scoreValue := math.Round(score*100) / 100
-		result = &scoreValue
+		result = &score
 	}
 
 	return result
 }
+
+// localScore computes the estimated LocalScore of a combination from its short and long
+// context fits, or nil if neither context was fitted
+func localScore(matrixResult *MatrixResult) *float64 {
+	if matrixResult.ShortContextModelFit == nil && matrixResult.LongContextModelFit == nil {
+		return nil
+	}
+	return Calculate([]*ModelFitResult{matrixResult.ShortContextModelFit, matrixResult.LongContextModelFit})
+}
@@ -0,0 +1,24 @@
+package benchmark
+
+import "fmt"
+
+// DefaultMaxCombinations is the default limit on the number of combinations a matrix
+// may run, so a typo in a matrix doesn't start a run taking days
+const DefaultMaxCombinations = 100
+
+// checkMaxCombinations fails if more combinations would run than the max_combinations
+// base parameter allows; 0 sets no limit
+func checkMaxCombinations(baseParams map[string]interface{}, count int) error {
+	maxCombinations, err := paramInt(baseParams, "max_combinations", DefaultMaxCombinations)
+	if err != nil {
+		return err
+	}
+	if maxCombinations < 0 {
+		return fmt.Errorf("max_combinations must not be negative, got %d", maxCombinations)
+	}
+	if maxCombinations > 0 && count > maxCombinations {
+		return fmt.Errorf("matrix has %d combinations, more than the limit of %d; raise it with --max-combinations %d (0 for no limit) if this is intended",
+			count, maxCombinations, count)
+	}
+	return nil
+}
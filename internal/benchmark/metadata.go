@@ -0,0 +1,83 @@
+package benchmark
+
+import (
+	"log/slog"
+	"sort"
+
+	"github.com/aifoundry-org/turtlenekko/internal/driver"
+)
+
+// checkServedModel warns, once per benchmark, when the server echoes back a different
+// model than the one requested, e.g. because a proxy routed the request elsewhere
+func (b *Benchmark) checkServedModel(result *CompletionResult) {
+	if result.Model == "" || b.Model == "" || result.Model == b.Model {
+		return
+	}
+	b.servedModelWarning.Do(func() {
+		b.log().Warn("Server responded with a different model than requested",
+			"component", "benchmark",
+			"requested_model", b.Model,
+			"served_model", result.Model)
+	})
+}
+
+// servedModel returns the model name the server echoed back, the most common one if it
+// changed between requests, or empty if the server didn't report it
+func servedModel(results []*CompletionResult) string {
+	counts := make(map[string]int)
+	for _, result := range results {
+		if result != nil && result.Model != "" {
+			counts[result.Model]++
+		}
+	}
+
+	var models []string
+	for model := range counts {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+
+	served := ""
+	for _, model := range models {
+		if counts[model] > counts[served] {
+			served = model
+		}
+	}
+	return served
+}
+
+// finishReasonCounts counts the requests per finish reason; requests whose response had
+// no finish reason are not counted
+func finishReasonCounts(results []*CompletionResult) map[string]int {
+	var counts map[string]int
+	for _, result := range results {
+		if result == nil || result.FinishReason == "" {
+			continue
+		}
+		if counts == nil {
+			counts = make(map[string]int)
+		}
+		counts[result.FinishReason]++
+	}
+	return counts
+}
+
+// serverConfig asks the driver for the configuration its server reports, or returns nil
+// if the driver doesn't support it or the request fails
+func serverConfig(d driver.Driver, logger *slog.Logger) map[string]interface{} {
+	if reusable, ok := d.(*reusableDriver); ok {
+		d = reusable.Driver
+	}
+	reporter, ok := d.(driver.ServerConfigReporter)
+	if !ok {
+		return nil
+	}
+
+	config, err := reporter.ServerConfig()
+	if err != nil {
+		logger.Warn("Failed to get the server config", "component", "benchmark", "error", err)
+		return nil
+	}
+	logger.Debug("Server config", "component", "benchmark", "config", config)
+	return config
+}
@@ -0,0 +1,96 @@
+package benchmark
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/aifoundry-org/turtlenekko/internal/driver"
+)
+
+// isAllModels reports whether a model parameter asks for every model the server lists
+func isAllModels(model interface{}) bool {
+	name, ok := model.(string)
+	return ok && (name == "*" || name == "all")
+}
+
+// ListModels returns the IDs of the models listed by the server's /models endpoint,
+// next to the chat completions (or messages) endpoint
+func (b *Benchmark) ListModels() ([]string, error) {
+	base := strings.TrimSuffix(strings.TrimSuffix(b.URL, "/chat/completions"), "/messages")
+	if base == b.URL {
+		return nil, fmt.Errorf("cannot derive the models endpoint from %s", b.URL)
+	}
+
+	var models openAIModelList
+	client := &http.Client{Timeout: contextInfoTimeout}
+	if err := b.getJSON(client, base+"/models", &models); err != nil {
+		return nil, fmt.Errorf("error listing models at %s/models: %v", base, err)
+	}
+
+	var ids []string
+	for _, model := range models.Data {
+		ids = append(ids, model.ID)
+	}
+	return ids, nil
+}
+
+// listDriverModels sets up the driver with params to ask its server for the model list
+func listDriverModels(d driver.Driver, params map[string]interface{}) ([]string, error) {
+	if err := d.Setup(params); err != nil {
+		return nil, fmt.Errorf("driver setup failed: %v", err)
+	}
+	defer d.Teardown()
+
+	b := NewBenchmark(d.GetURL(), "", "", driverRequestTimeout(d))
+	b.EndpointType = paramString(params, "endpoint_type", EndpointTypeOpenAI)
+	b.APIKey = paramString(params, "api_key", "")
+	if tokenCmd := paramString(params, "token_cmd", ""); tokenCmd != "" {
+		b.TokenCommand = NewTokenCommand(tokenCmd)
+	}
+	b.AnthropicVersion = paramString(params, "anthropic_version", DefaultAnthropicVersion)
+	return b.ListModels()
+}
+
+// expandModels replaces each parameter set whose model is "*" or "all" with one parameter
+// set per model the server lists
+func expandModels(d driver.Driver, baseParams map[string]interface{}, paramCombinations []map[string]interface{}, logger *slog.Logger) ([]map[string]interface{}, error) {
+	var expanded []map[string]interface{}
+	for _, paramSet := range paramCombinations {
+		params := make(map[string]interface{})
+		for k, v := range baseParams {
+			params[k] = v
+		}
+		for k, v := range paramSet {
+			params[k] = v
+		}
+
+		if !isAllModels(params["model"]) {
+			expanded = append(expanded, paramSet)
+			continue
+		}
+		if d == nil {
+			return nil, fmt.Errorf("model %v requires a driver", params["model"])
+		}
+
+		models, err := listDriverModels(d, params)
+		if err != nil {
+			return nil, err
+		}
+		if len(models) == 0 {
+			return nil, fmt.Errorf("server at %s lists no models", d.GetURL())
+		}
+		logger.Info("Expanding model list", "component", "benchmark", "models", strings.Join(models, ", "))
+
+		for _, model := range models {
+			modelSet := make(map[string]interface{})
+			for k, v := range paramSet {
+				modelSet[k] = v
+			}
+			modelSet["model"] = model
+			expanded = append(expanded, modelSet)
+		}
+	}
+	return expanded, nil
+}
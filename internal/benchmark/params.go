@@ -0,0 +1,89 @@
+package benchmark
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// paramString returns a parameter as a string, or the default if it is not set
+func paramString(params map[string]interface{}, key string, def string) string {
+	value, ok := params[key]
+	if !ok || value == nil {
+		return def
+	}
+	if str, ok := value.(string); ok {
+		return str
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// paramInt returns a parameter as an integer, or the default if it is not set. A value
+// that isn't a whole number is an error, so a typo doesn't run a different benchmark.
+func paramInt(params map[string]interface{}, key string, def int) (int, error) {
+	switch v := params[key].(type) {
+	case nil:
+		return def, nil
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v == math.Trunc(v) {
+			return int(v), nil
+		}
+	case string:
+		if i, err := strconv.Atoi(v); err == nil {
+			return i, nil
+		}
+	}
+	return def, fmt.Errorf("%s must be an integer, got %v", key, params[key])
+}
+
+// paramBool returns a parameter as a boolean, or the default if it is not set. A value
+// that isn't a boolean is an error.
+func paramBool(params map[string]interface{}, key string, def bool) (bool, error) {
+	switch v := params[key].(type) {
+	case nil:
+		return def, nil
+	case bool:
+		return v, nil
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b, nil
+		}
+	}
+	return def, fmt.Errorf("%s must be true or false, got %v", key, params[key])
+}
+
+// paramFloat returns a parameter as a float, or the default if it is not set. A value
+// that isn't a number is an error.
+func paramFloat(params map[string]interface{}, key string, def float64) (float64, error) {
+	switch v := params[key].(type) {
+	case nil:
+		return def, nil
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, nil
+		}
+	}
+	return def, fmt.Errorf("%s must be a number, got %v", key, params[key])
+}
+
+// mergeParams returns a copy of base overridden by the parameters of a combination
+func mergeParams(base map[string]interface{}, override map[string]interface{}) map[string]interface{} {
+	params := make(map[string]interface{})
+	for k, v := range base {
+		params[k] = v
+	}
+	for k, v := range override {
+		params[k] = v
+	}
+	return params
+}
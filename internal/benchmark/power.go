@@ -0,0 +1,131 @@
+package benchmark
+
+import (
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPowerSampleInterval is how often the power command is polled during a request
+const DefaultPowerSampleInterval = 100 * time.Millisecond
+
+// PowerSampler polls a shell command that prints the current power draw in watts.
+// If the command prints several numbers (e.g. one line per GPU), they are summed.
+type PowerSampler struct {
+	Command  string
+	Interval time.Duration
+	Logger   *slog.Logger // nil logs to the default logger
+}
+
+// NewPowerSampler creates a power sampler for the given command and polling interval
+func NewPowerSampler(command string, interval time.Duration) *PowerSampler {
+	if interval <= 0 {
+		interval = DefaultPowerSampleInterval
+	}
+	return &PowerSampler{
+		Command:  command,
+		Interval: interval,
+	}
+}
+
+// readWatts runs the power command once and returns the reported power draw
+func (s *PowerSampler) readWatts() (float64, error) {
+	output, err := exec.Command("sh", "-c", s.Command).Output()
+	if err != nil {
+		return 0, fmt.Errorf("error running power command: %v", err)
+	}
+
+	total := 0.0
+	found := false
+	for _, field := range strings.FieldsFunc(string(output), func(r rune) bool {
+		return r == '\n' || r == ',' || r == ' ' || r == '\t'
+	}) {
+		watts, err := strconv.ParseFloat(strings.TrimSuffix(field, "W"), 64)
+		if err != nil {
+			continue
+		}
+		total += watts
+		found = true
+	}
+	if !found {
+		return 0, fmt.Errorf("power command output contains no number: %q", strings.TrimSpace(string(output)))
+	}
+	return total, nil
+}
+
+// Start begins polling the power command in the background. The returned function stops
+// polling and returns the energy used since Start in joules (mean power × elapsed time).
+func (s *PowerSampler) Start() func() float64 {
+	startTime := time.Now()
+	done := make(chan struct{})
+
+	var mu sync.Mutex
+	var readings []float64
+
+	sample := func() {
+		watts, err := s.readWatts()
+		if err != nil {
+			loggerOrDefault(s.Logger).Warn("Failed to sample power draw", "component", "benchmark", "error", err)
+			return
+		}
+		mu.Lock()
+		readings = append(readings, watts)
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+
+		sample()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				sample()
+			}
+		}
+	}()
+
+	return func() float64 {
+		elapsed := time.Since(startTime)
+		close(done)
+		wg.Wait()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(readings) == 0 {
+			return 0
+		}
+		sum := 0.0
+		for _, watts := range readings {
+			sum += watts
+		}
+		return sum / float64(len(readings)) * elapsed.Seconds()
+	}
+}
+
+// energyTotals sums the energy used by all requests and returns it together with the
+// number of prompt and completion tokens processed per joule
+func energyTotals(results []*CompletionResult) (energyJoules float64, tokensPerJoule float64) {
+	tokens := 0
+	for _, result := range results {
+		if result == nil || result.EnergyJoules <= 0 {
+			continue
+		}
+		energyJoules += result.EnergyJoules
+		tokens += result.PromptTokens + result.CachedPromptTokens + result.CompletionTokens
+	}
+	if energyJoules > 0 {
+		tokensPerJoule = float64(tokens) / energyJoules
+	}
+	return energyJoules, tokensPerJoule
+}
@@ -0,0 +1,180 @@
+package benchmark
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Progress describes how far a matrix run has come
+type Progress struct {
+	Combination  int                    // 1-based index of the running combination
+	Combinations int                    // number of combinations in the run
+	Params       map[string]interface{} // output parameters of the running combination
+	Config       int                    // benchmark configurations completed in the running combination
+	Configs      int                    // benchmark configurations planned for the running combination
+	Elapsed      time.Duration          // time since the run started
+	ETA          time.Duration          // estimated time remaining, 0 until some work has completed
+}
+
+// String formats the progress, e.g. "Combination 3/12, config 2/8, elapsed 4m12s, ETA 9m"
+func (p Progress) String() string {
+	line := fmt.Sprintf("Combination %d/%d", p.Combination, p.Combinations)
+	if p.Configs > 0 {
+		line += fmt.Sprintf(", config %d/%d", p.Config, p.Configs)
+	}
+	line += ", elapsed " + p.Elapsed.Round(time.Second).String()
+	if p.ETA > 0 {
+		line += ", ETA " + formatETA(p.ETA)
+	}
+	return line
+}
+
+// formatETA rounds an estimate to whole minutes, or seconds under a minute
+func formatETA(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+	return fmt.Sprintf("%dm", int(d.Round(time.Minute).Minutes()))
+}
+
+// Handlers are called as a matrix run progresses. Any of them may be nil.
+type Handlers struct {
+	// Progress is called whenever a combination or benchmark configuration starts or completes
+	Progress func(Progress)
+	// Result is called with the result of each combination as soon as it completes,
+	// before the rest of the matrix runs
+	Result func(MatrixResult)
+	// Request is called with the result of every successful request, e.g. to show recent
+	// response times. It may be called concurrently.
+	Request func(CompletionResult)
+}
+
+// progressTracker follows a matrix run and reports progress to its handlers. A nil
+// tracker, as outside of a matrix run, reports nothing.
+type progressTracker struct {
+	mu           sync.Mutex
+	handlers     Handlers
+	params       map[string]interface{}
+	start        time.Time
+	combination  int
+	combinations int
+	config       int
+	configs      int
+}
+
+// newProgressTracker creates a tracker that reports to the handlers
+func newProgressTracker(handlers Handlers) *progressTracker {
+	return &progressTracker{handlers: handlers}
+}
+
+// startRun starts tracking a run of the given number of combinations
+func (t *progressTracker) startRun(combinations int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.start = time.Now()
+	t.combinations = combinations
+	t.combination = 0
+	t.mu.Unlock()
+}
+
+// startCombination moves on to the next combination
+func (t *progressTracker) startCombination(params map[string]interface{}) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.combination++
+	t.params = params
+	t.config, t.configs = 0, 0
+	t.mu.Unlock()
+	t.report()
+}
+
+// addConfigs adds planned configurations to the running combination
+func (t *progressTracker) addConfigs(n int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.configs += n
+	t.mu.Unlock()
+	t.report()
+}
+
+// configsDone marks configurations of the running combination as completed (or skipped)
+func (t *progressTracker) configsDone(n int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.config = min(t.config+n, t.configs)
+	t.mu.Unlock()
+	t.report()
+}
+
+// combinationDone passes the result of a completed combination to the result handler
+func (t *progressTracker) combinationDone(result MatrixResult) {
+	if t == nil {
+		return
+	}
+	if t.handlers.Result != nil {
+		t.handlers.Result(result)
+	}
+}
+
+// outputParams returns the parameters that are shown in the output
+func outputParams(params map[string]interface{}, outputFlags map[string]bool) map[string]interface{} {
+	filtered := make(map[string]interface{})
+	for k, v := range params {
+		if outputFlags[k] {
+			filtered[k] = v
+		}
+	}
+	return filtered
+}
+
+// requestDone passes the result of a successful request to the request handler
+func (t *progressTracker) requestDone(result CompletionResult) {
+	if t == nil {
+		return
+	}
+	if t.handlers.Request != nil {
+		t.handlers.Request(result)
+	}
+}
+
+// report calls the handler with the current progress. The ETA extrapolates the elapsed
+// time from the completed combinations and the completed share of the running one.
+func (t *progressTracker) report() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.handlers.Progress == nil || t.combinations == 0 {
+		return
+	}
+
+	p := Progress{
+		Combination:  t.combination,
+		Combinations: t.combinations,
+		Params:       t.params,
+		Config:       t.config,
+		Configs:      t.configs,
+		Elapsed:      time.Since(t.start),
+	}
+
+	done := float64(t.combination - 1)
+	if t.configs > 0 {
+		done += float64(t.config) / float64(t.configs)
+	}
+	if done > 0 {
+		total := float64(p.Elapsed) * float64(t.combinations) / done
+		p.ETA = time.Duration(total) - p.Elapsed
+	}
+
+	t.handlers.Progress(p)
+}
@@ -0,0 +1,170 @@
+package benchmark
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LongContextPromptTokens is the smallest prompt_tokens target measured in the long context
+const LongContextPromptTokens = 1024
+
+// PromptTokensTolerance is the relative difference between the prompt tokens the server
+// counts and a prompt_tokens target that is accepted as reaching it
+const PromptTokensTolerance = 0.02
+
+// promptTokensAttempts is the maximum number of requests sent to resolve a prompt_tokens target
+const promptTokensAttempts = 5
+
+// SkippedNoLongTargets is the reason recorded when no prompt_tokens target is long enough
+// for the long context
+var SkippedNoLongTargets = fmt.Sprintf("no prompt_tokens target of %d or more", LongContextPromptTokens)
+
+// PromptTokenTarget is a prompt_tokens target and the prompt length found to produce it
+type PromptTokenTarget struct {
+	Tokens         int // requested prompt tokens
+	PromptLength   int // prompt length in characters
+	MeasuredTokens int // prompt tokens the server counted for the prompt length
+}
+
+// promptTargetKey identifies the server and prompts a resolved target applies to. The
+// driver key tells apart servers the driver started differently at the same URL, e.g.
+// with another model file.
+type promptTargetKey struct {
+	driver, url, model, corpus, postfix string
+	tokens                              int
+}
+
+// parsePromptTokens reads the prompt_tokens parameter: a comma-separated list of prompt
+// lengths in tokens, at least one of them below LongContextPromptTokens. Parameters set
+// from Go may also hold a slice. It returns nil if the parameter isn't set.
+func parsePromptTokens(params map[string]interface{}) ([]int, error) {
+	var targets []int
+	var err error
+	switch v := params["prompt_tokens"].(type) {
+	case nil:
+		return nil, nil
+	case int:
+		targets, err = parseTokenList("prompt_tokens", strconv.Itoa(v))
+	case string:
+		targets, err = parseTokenList("prompt_tokens", v)
+	case []int:
+		fields := make([]string, len(v))
+		for i, tokens := range v {
+			fields[i] = strconv.Itoa(tokens)
+		}
+		targets, err = parseTokenList("prompt_tokens", strings.Join(fields, ","))
+	case []interface{}:
+		fields := make([]string, len(v))
+		for i, item := range v {
+			fields[i] = fmt.Sprint(item)
+		}
+		targets, err = parseTokenList("prompt_tokens", strings.Join(fields, ","))
+	default:
+		return nil, fmt.Errorf("invalid prompt_tokens %v: must be a comma-separated list of token counts such as \"128,512,2048\"", v)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Ints(targets)
+	if targets[0] >= LongContextPromptTokens {
+		return nil, fmt.Errorf("prompt_tokens needs a target below %d tokens for the short context", LongContextPromptTokens)
+	}
+	return targets, nil
+}
+
+// resolvePromptTokens finds the prompt length whose prompt the server counts as the target
+// number of tokens, within PromptTokensTolerance. Starting from the observed or assumed
+// tokens per byte, the length is scaled by target/measured after every request, which
+// converges despite the constant tokens of the chat template. The closest length is
+// used if the target isn't reached within promptTokensAttempts requests.
+func (b *Benchmark) resolvePromptTokens(tokens int, postfix string) (PromptTokenTarget, error) {
+	key := promptTargetKey{driver: b.driverKey, url: b.URL, model: b.Model, corpus: b.Corpus, postfix: postfix, tokens: tokens}
+	if target, ok := b.promptTargets[key]; ok {
+		b.log().Debug("Using cached prompt length for prompt_tokens target", "component", "benchmark",
+			"prompt_tokens", tokens,
+			"prompt_length", target.PromptLength)
+		return target, nil
+	}
+
+	ratio := b.PromptTokensPerByte()
+	if ratio == 0 {
+		ratio = 1.0 / bytesPerToken
+	}
+	// The postfix is part of the prompt, the lengths only cover the generated text
+	totalLength := float64(tokens) / ratio
+
+	var best PromptTokenTarget
+	for attempt := 0; attempt < promptTokensAttempts; attempt++ {
+		promptLength := max(1, int(math.Round(totalLength))-len(postfix))
+		messages := b.generateMessages(promptLength, postfix)
+		result, err := b.ChatCompletion(b.completionParams(messages, 1))
+
+		// Small delay between requests to avoid overwhelming the server
+		time.Sleep(500 * time.Millisecond)
+
+		if err != nil {
+			return PromptTokenTarget{}, fmt.Errorf("error resolving prompt_tokens target %d: %v", tokens, err)
+		}
+		if result.UsageEstimated {
+			return PromptTokenTarget{}, fmt.Errorf("prompt_tokens requires a server that reports token usage")
+		}
+		b.recordPromptRatio(messages, result)
+
+		measured := result.PromptTokens + result.CachedPromptTokens
+		if attempt == 0 || math.Abs(float64(measured-tokens)) < math.Abs(float64(best.MeasuredTokens-tokens)) {
+			best = PromptTokenTarget{Tokens: tokens, PromptLength: promptLength, MeasuredTokens: measured}
+		}
+		if math.Abs(float64(measured-tokens)) <= math.Max(1, PromptTokensTolerance*float64(tokens)) || measured == 0 {
+			break
+		}
+		totalLength = float64(promptLength+len(postfix)) * float64(tokens) / float64(measured)
+	}
+
+	if math.Abs(float64(best.MeasuredTokens-tokens)) > math.Max(1, PromptTokensTolerance*float64(tokens)) {
+		b.log().Warn("Prompt length did not reach the prompt_tokens target, using the closest", "component", "benchmark",
+			"prompt_tokens", tokens,
+			"measured_prompt_tokens", best.MeasuredTokens,
+			"prompt_length", best.PromptLength)
+	}
+	b.log().Info("Resolved prompt_tokens target", "component", "benchmark",
+		"prompt_tokens", tokens,
+		"measured_prompt_tokens", best.MeasuredTokens,
+		"prompt_length", best.PromptLength)
+
+	if b.promptTargets == nil {
+		b.promptTargets = make(map[promptTargetKey]PromptTokenTarget)
+	}
+	b.promptTargets[key] = best
+	return best, nil
+}
+
+// promptTokenConfigs resolves the prompt_tokens targets and returns the short and long
+// context configs measuring them, each with one and 100 completion tokens like the
+// default configs
+func (b *Benchmark) promptTokenConfigs(postfix string) ([]BenchmarkConfig, []BenchmarkConfig, error) {
+	var shortConfigs, longConfigs []BenchmarkConfig
+	b.PromptTokenTargets = nil
+	for _, tokens := range b.PromptTokens {
+		target, err := b.resolvePromptTokens(tokens, postfix)
+		if err != nil {
+			return nil, nil, err
+		}
+		b.PromptTokenTargets = append(b.PromptTokenTargets, target)
+
+		configs := []BenchmarkConfig{
+			{PromptLength: target.PromptLength, MaxTokens: 1},
+			{PromptLength: target.PromptLength, MaxTokens: 100},
+		}
+		if tokens < LongContextPromptTokens {
+			shortConfigs = append(shortConfigs, configs...)
+		} else {
+			longConfigs = append(longConfigs, configs...)
+		}
+	}
+	return shortConfigs, longConfigs, nil
+}
@@ -0,0 +1,58 @@
+package benchmark
+
+import (
+	"fmt"
+	"time"
+)
+
+// Prompt and completion sizes of a quick measurement
+const (
+	QuickPromptLength = 500
+	QuickMaxTokens    = 100
+)
+
+// QuickResult is a single-shot measurement of an endpoint
+type QuickResult struct {
+	PromptTokens           int
+	CompletionTokens       int
+	TTFTMs                 float64 // response time of a request with one completion token
+	ResponseTimeMs         float64 // response time of the request with maxCompletionTokens
+	PromptTokensPerSec     float64 // prompt tokens / TTFT
+	CompletionTokensPerSec float64 // completion tokens after the first / the extra response time
+}
+
+// RunQuick measures an endpoint with two requests of the same prompt length and no
+// warmup: one with a single completion token, whose response time approximates the time
+// to first token, and one with up to maxCompletionTokens, whose extra time gives the
+// completion speed. It takes seconds instead of minutes, e.g. to smoke-test an endpoint.
+func (b *Benchmark) RunQuick(promptLength int, maxCompletionTokens int, postfix string) (*QuickResult, error) {
+	b.log().Info("Running quick measurement", "component", "benchmark", "url", b.URL)
+
+	first, err := b.ChatCompletion(b.completionParams(b.generateMessages(promptLength, postfix), 1))
+	if err != nil {
+		return nil, fmt.Errorf("first token request failed: %v", err)
+	}
+	full, err := b.ChatCompletion(b.completionParams(b.generateMessages(promptLength, postfix), maxCompletionTokens))
+	if err != nil {
+		return nil, fmt.Errorf("completion request failed: %v", err)
+	}
+
+	result := &QuickResult{
+		PromptTokens:     first.PromptTokens + first.CachedPromptTokens,
+		CompletionTokens: full.CompletionTokens,
+		TTFTMs:           durationMs(first.ResponseTime),
+		ResponseTimeMs:   durationMs(full.ResponseTime),
+	}
+	if result.TTFTMs > 0 {
+		result.PromptTokensPerSec = float64(result.PromptTokens) / result.TTFTMs * 1000
+	}
+	if generation := result.ResponseTimeMs - result.TTFTMs; full.CompletionTokens > 1 && generation > 0 {
+		result.CompletionTokensPerSec = float64(full.CompletionTokens-1) / generation * 1000
+	}
+	return result, nil
+}
+
+// durationMs converts a duration to fractional milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
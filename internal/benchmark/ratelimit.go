@@ -0,0 +1,84 @@
+package benchmark
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Retry behaviour for rate limited (HTTP 429) responses
+const (
+	MaxRateLimitRetries     = 5
+	DefaultRateLimitBackoff = 10 * time.Second
+)
+
+// RateLimiter limits the request and token throughput sent to an endpoint. It is shared
+// by all in-flight requests of a benchmark, so it also applies under concurrency.
+type RateLimiter struct {
+	requests *rate.Limiter
+	tokens   *rate.Limiter
+}
+
+// NewRateLimiter creates a rate limiter for the given per-minute budgets.
+// A budget of 0 means unlimited; if both are 0, nil is returned.
+func NewRateLimiter(requestsPerMinute int, tokensPerMinute int) *RateLimiter {
+	if requestsPerMinute <= 0 && tokensPerMinute <= 0 {
+		return nil
+	}
+
+	limiter := &RateLimiter{}
+	if requestsPerMinute > 0 {
+		limiter.requests = rate.NewLimiter(rate.Limit(float64(requestsPerMinute)/60), 1)
+	}
+	if tokensPerMinute > 0 {
+		limiter.tokens = rate.NewLimiter(rate.Limit(float64(tokensPerMinute)/60), tokensPerMinute)
+	}
+	return limiter
+}
+
+// Wait blocks until a request using the given number of tokens fits in the budget
+func (l *RateLimiter) Wait(ctx context.Context, tokens int) error {
+	if l.requests != nil {
+		if err := l.requests.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if l.tokens != nil {
+		// A single request larger than the whole budget can only wait for the full bucket
+		if tokens > l.tokens.Burst() {
+			tokens = l.tokens.Burst()
+		}
+		if err := l.tokens.WaitN(ctx, tokens); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retryAfter returns how long to wait before retrying a rate limited request,
+// based on the Retry-After header (delay in seconds or an HTTP date)
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return DefaultRateLimitBackoff
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+		return 0
+	}
+	return DefaultRateLimitBackoff
+}
+
+// estimateRequestTokens roughly estimates the tokens a request will use (about 4 bytes
+// per prompt token plus the completion budget), for charging the token budget up front
+func estimateRequestTokens(params ChatCompletionParams) int {
+	return estimateTokens(params.Messages) + params.MaxCompletionTokens
+}
@@ -0,0 +1,70 @@
+package benchmark
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Readiness check settings
+const (
+	DefaultReadyTimeout = 60 * time.Second
+	readyPollInterval   = time.Second
+	readyRequestTimeout = 10 * time.Second
+)
+
+// checkReady sends a one-token chat request and reports whether the server answered.
+// Any response other than a gateway or unavailable error means it is up: a 4xx
+// response still shows that the server is listening and will report the error itself.
+func (b *Benchmark) checkReady(client *http.Client) error {
+	body, err := b.marshalRequest(ChatCompletionParams{
+		Messages:            []ChatMessage{{Role: "user", Content: "Hi"}},
+		MaxCompletionTokens: 1,
+	})
+	if err != nil {
+		return fmt.Errorf("error marshaling request: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", b.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+	b.setHeaders(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// WaitForReady polls the endpoint until the server answers or the timeout expires, so a
+// server that never came up fails with a clear error instead of on the first request
+func (b *Benchmark) WaitForReady(timeout time.Duration) error {
+	client := &http.Client{Timeout: readyRequestTimeout}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		err := b.checkReady(client)
+		if err == nil {
+			b.log().Debug("Server is ready", "component", "benchmark", "url", b.URL)
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("server at %s did not become ready within %v: %v", b.URL, timeout, err)
+		}
+
+		b.log().Debug("Server not ready yet", "component", "benchmark", "url", b.URL, "error", err)
+		time.Sleep(readyPollInterval)
+	}
+}
@@ -0,0 +1,139 @@
+package benchmark
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+)
+
+// rawCSVColumns are the columns of the raw-csv output format read by ReplayRawCSV;
+// energy_joules is optional
+var rawCSVColumns = []string{"combination", "context", "prompt_tokens", "cached_prompt_tokens", "completion_tokens", "response_time_ms"}
+
+// ReplayRawCSV reads results captured with the raw-csv output format and fits the
+// completion time models of each combination like a benchmark run would, without
+// sending any requests. The raw CSV doesn't record the parameters, so combinations are
+// returned in their captured order with their number as the "combination" parameter.
+func ReplayRawCSV(r io.Reader, logger *slog.Logger) ([]MatrixResult, error) {
+	logger = loggerOrDefault(logger)
+
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading raw results header: %v", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, name := range rawCSVColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("raw results are missing the %s column", name)
+		}
+	}
+	energyColumn, hasEnergy := columns["energy_joules"]
+
+	// Results per combination and context, in the order the combinations appear
+	var order []int
+	shortResults := make(map[int][]*CompletionResult)
+	longResults := make(map[int][]*CompletionResult)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading raw results: %v", err)
+		}
+
+		values := make(map[string]int)
+		for _, name := range []string{"combination", "prompt_tokens", "cached_prompt_tokens", "completion_tokens"} {
+			value, err := strconv.Atoi(record[columns[name]])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s: %v", line, name, err)
+			}
+			values[name] = value
+		}
+		responseTimeMs, err := strconv.ParseFloat(record[columns["response_time_ms"]], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid response_time_ms: %v", line, err)
+		}
+
+		result := &CompletionResult{
+			PromptTokens:       values["prompt_tokens"],
+			CachedPromptTokens: values["cached_prompt_tokens"],
+			CompletionTokens:   values["completion_tokens"],
+			ResponseTime:       time.Duration(math.Round(responseTimeMs * float64(time.Millisecond))),
+		}
+		if hasEnergy && record[energyColumn] != "" {
+			result.EnergyJoules, err = strconv.ParseFloat(record[energyColumn], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid energy_joules: %v", line, err)
+			}
+		}
+
+		combination := values["combination"]
+		if _, ok := shortResults[combination]; !ok {
+			if _, ok := longResults[combination]; !ok {
+				order = append(order, combination)
+			}
+		}
+		result.Context = record[columns["context"]]
+		switch result.Context {
+		case ContextShort:
+			shortResults[combination] = append(shortResults[combination], result)
+		case ContextLong:
+			longResults[combination] = append(longResults[combination], result)
+		default:
+			return nil, fmt.Errorf("line %d: invalid context %q, expected short or long", line, result.Context)
+		}
+	}
+
+	var matrixResults []MatrixResult
+	for _, combination := range order {
+		logger.Info("Replaying combination", "component", "benchmark", "combination", combination)
+
+		matrixResult := Replay(shortResults[combination], longResults[combination], logger)
+		matrixResult.Params = map[string]interface{}{"combination": combination}
+		matrixResult.OutputFlags = map[string]bool{"combination": true}
+		matrixResults = append(matrixResults, *matrixResult)
+	}
+	return matrixResults, nil
+}
+
+// Replay fits the completion time models to previously captured short and long context
+// results and computes the metrics a benchmark run would report for them
+func Replay(shortResults, longResults []*CompletionResult, logger *slog.Logger) *MatrixResult {
+	logger = loggerOrDefault(logger)
+
+	fit := func(contextType string, results []*CompletionResult) *ModelFitResult {
+		if len(results) < 4 {
+			if len(results) > 0 {
+				logger.Warn(fmt.Sprintf("Not enough data points for %s model fit", contextType),
+					"component", "benchmark",
+					"data_points", len(results))
+			}
+			return nil
+		}
+		modelFit := fitCompletionTimeModel(logger, results, true)
+		setLatencyPercentiles(modelFit, results)
+		return modelFit
+	}
+
+	results := append(append([]*CompletionResult{}, shortResults...), longResults...)
+	matrixResult := &MatrixResult{
+		Results:              results,
+		ShortContextModelFit: fit("short", shortResults),
+		LongContextModelFit:  fit("long", longResults),
+	}
+	matrixResult.EnergyJoules, matrixResult.TokensPerJoule = energyTotals(results)
+	matrixResult.LocalScore = localScore(matrixResult)
+	if matrixResult.ShortContextModelFit == nil && matrixResult.LongContextModelFit == nil {
+		matrixResult.Error = fmt.Errorf("not enough data points to fit a model")
+	}
+	return matrixResult
+}
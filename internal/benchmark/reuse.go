@@ -0,0 +1,113 @@
+package benchmark
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aifoundry-org/turtlenekko/internal/driver"
+	"github.com/aifoundry-org/turtlenekko/internal/types"
+)
+
+// reusableDriver keeps a driver running between consecutive combinations with the same
+// driver parameters, turning the teardown and following setup into no-ops
+type reusableDriver struct {
+	driver.Driver
+	running bool // the driver is set up
+	keep    bool // skip the next teardown, the next combination reuses the driver
+}
+
+// Setup sets up the driver unless it is still running from the previous combination
+func (d *reusableDriver) Setup(params map[string]interface{}) error {
+	if d.running {
+		return nil
+	}
+	if err := d.Driver.Setup(params); err != nil {
+		return err
+	}
+	d.running = true
+	return nil
+}
+
+// Teardown tears down the driver unless the next combination reuses it
+func (d *reusableDriver) Teardown() error {
+	if d.keep || !d.running {
+		return nil
+	}
+	d.running = false
+	return d.Driver.Teardown()
+}
+
+// benchmarkOnlyParams returns the matrix parameters declared with driver: false, checking
+// that the driver doesn't consume any of them
+func benchmarkOnlyParams(d driver.Driver, matrix map[string]types.ParameterConfig) (map[string]bool, error) {
+	driverParams := make(map[string]bool)
+	if d != nil {
+		for _, param := range d.Describe().Params {
+			driverParams[param.Name] = true
+		}
+	}
+
+	benchmarkOnly := map[string]bool{"reuse_driver": true}
+	for k, config := range matrix {
+		if config.Driver {
+			continue
+		}
+		if driverParams[k] {
+			return nil, fmt.Errorf("parameter %s is used by the %s driver and cannot be declared with driver: false", k, d.Describe().Name)
+		}
+		benchmarkOnly[k] = true
+	}
+	return benchmarkOnly, nil
+}
+
+// driverKey identifies the driver parameters of a combination: all parameters except
+// the benchmark-only ones
+func driverKey(params map[string]interface{}, benchmarkOnly map[string]bool) string {
+	var keys []string
+	for k := range params {
+		if !benchmarkOnly[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&sb, "%s=%#v\n", k, params[k])
+	}
+	return sb.String()
+}
+
+// groupByDriver reorders parameter sets so that the ones with the same driver parameters
+// run consecutively, keeping the order of their first appearance
+func groupByDriver(paramSets []map[string]interface{}, baseParams map[string]interface{}, benchmarkOnly map[string]bool) []map[string]interface{} {
+	var order []string
+	groups := make(map[string][]map[string]interface{})
+	for _, paramSet := range paramSets {
+		key := driverKey(mergeParams(baseParams, paramSet), benchmarkOnly)
+		if _, seen := groups[key]; !seen {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], paramSet)
+	}
+
+	var grouped []map[string]interface{}
+	for _, key := range order {
+		grouped = append(grouped, groups[key]...)
+	}
+	return grouped
+}
+
+// reuseDriverRequested reports whether any combination sets reuse_driver
+func reuseDriverRequested(baseParams map[string]interface{}, paramSets []map[string]interface{}) (bool, error) {
+	requested := false
+	for _, paramSet := range paramSets {
+		reuse, err := paramBool(mergeParams(baseParams, paramSet), "reuse_driver", false)
+		if err != nil {
+			return false, err
+		}
+		requested = requested || reuse
+	}
+	return requested, nil
+}
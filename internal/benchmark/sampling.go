@@ -0,0 +1,65 @@
+package benchmark
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Sampling holds the sampling parameters sent with benchmark requests
+type Sampling struct {
+	Temperature float64
+	TopP        float64
+	TopK        int     // 0 leaves the server default
+	MinP        float64 // 0 leaves the server default
+}
+
+// DefaultSampling is greedy sampling, which makes completions reproducible
+var DefaultSampling = Sampling{Temperature: 0.0, TopP: 1.0}
+
+// String formats the sampling parameters, e.g. "temperature 0.7, top_p 0.9, top_k 40"
+func (s Sampling) String() string {
+	str := fmt.Sprintf("temperature %s, top_p %s",
+		strconv.FormatFloat(s.Temperature, 'f', -1, 64),
+		strconv.FormatFloat(s.TopP, 'f', -1, 64))
+	if s.TopK > 0 {
+		str += fmt.Sprintf(", top_k %d", s.TopK)
+	}
+	if s.MinP > 0 {
+		str += ", min_p " + strconv.FormatFloat(s.MinP, 'f', -1, 64)
+	}
+	return str
+}
+
+// samplingFromParams reads the sampling parameters, defaulting to DefaultSampling
+func samplingFromParams(params map[string]interface{}) (Sampling, error) {
+	var sampling Sampling
+	var err error
+	if sampling.Temperature, err = paramFloat(params, "temperature", DefaultSampling.Temperature); err != nil {
+		return sampling, err
+	}
+	if sampling.TopP, err = paramFloat(params, "top_p", DefaultSampling.TopP); err != nil {
+		return sampling, err
+	}
+	if sampling.TopK, err = paramInt(params, "top_k", DefaultSampling.TopK); err != nil {
+		return sampling, err
+	}
+	if sampling.MinP, err = paramFloat(params, "min_p", DefaultSampling.MinP); err != nil {
+		return sampling, err
+	}
+	return sampling, nil
+}
+
+// completionParams returns the parameters of a benchmark request with the configured
+// sampling and completion seed
+func (b *Benchmark) completionParams(messages []ChatMessage, maxCompletionTokens int) ChatCompletionParams {
+	return ChatCompletionParams{
+		Messages:            messages,
+		Temperature:         b.Sampling.Temperature,
+		TopP:                b.Sampling.TopP,
+		TopK:                b.Sampling.TopK,
+		MinP:                b.Sampling.MinP,
+		MaxCompletionTokens: maxCompletionTokens,
+		Seed:                b.CompletionSeed,
+		IgnoreEOS:           b.EarlyStop == EarlyStopIgnoreEOS,
+	}
+}
@@ -0,0 +1,69 @@
+package benchmark
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// shuffleRequested returns the seed of the shuffled combination order if the shuffle
+// parameter is set, by the base parameters or any combination: true shuffles with a
+// time-based seed, a number with that seed
+func shuffleRequested(baseParams map[string]interface{}, paramSets []map[string]interface{}) (int64, bool, error) {
+	value, ok := baseParams["shuffle"]
+	for _, paramSet := range paramSets {
+		if ok {
+			break
+		}
+		value, ok = paramSet["shuffle"]
+	}
+	if !ok {
+		return 0, false, nil
+	}
+
+	switch v := value.(type) {
+	case bool:
+		return time.Now().UnixNano(), v, nil
+	case int:
+		return int64(v), true, nil
+	case int64:
+		return v, true, nil
+	case float64:
+		return int64(v), true, nil
+	case string:
+		if shuffle, err := strconv.ParseBool(v); err == nil {
+			return time.Now().UnixNano(), shuffle, nil
+		}
+		if seed, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return seed, true, nil
+		}
+	}
+	return 0, false, fmt.Errorf("shuffle must be true, false or a seed, got %v", value)
+}
+
+// shuffledOrder returns a random order of n combinations. If key is set, combinations with
+// the same key are kept together, in the order of their first member, so a reused driver
+// still serves all of its combinations in a row.
+func shuffledOrder(n int, seed int64, key func(i int) string) []int {
+	order := rand.New(rand.NewSource(seed)).Perm(n)
+	if key == nil {
+		return order
+	}
+
+	var keys []string
+	groups := make(map[string][]int)
+	for _, i := range order {
+		k := key(i)
+		if _, seen := groups[k]; !seen {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], i)
+	}
+
+	grouped := make([]int, 0, n)
+	for _, k := range keys {
+		grouped = append(grouped, groups[k]...)
+	}
+	return grouped
+}
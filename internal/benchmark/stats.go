@@ -0,0 +1,88 @@
+package benchmark
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// percentile returns the p-th percentile (0-100) of the values using linear interpolation
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	rank := p / 100.0 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// setLatencyPercentiles computes response time percentiles over all raw results
+// and stores them on the model fit
+func setLatencyPercentiles(modelFit *ModelFitResult, results []*CompletionResult) {
+	if modelFit == nil {
+		return
+	}
+
+	var responseTimes []float64
+	for _, r := range results {
+		if r != nil {
+			responseTimes = append(responseTimes, float64(r.ResponseTime.Milliseconds()))
+		}
+	}
+
+	modelFit.LatencyP50 = percentile(responseTimes, 50)
+	modelFit.LatencyP90 = percentile(responseTimes, 90)
+	modelFit.LatencyP99 = percentile(responseTimes, 99)
+}
+
+// setStreamingLatencies computes the time to first token and inter-token latency over all
+// raw streamed results and stores them on the model fit
+func setStreamingLatencies(modelFit *ModelFitResult, results []*CompletionResult) {
+	if modelFit == nil {
+		return
+	}
+
+	var ttfts []float64
+	var latencies []time.Duration
+	var decodeTime time.Duration
+	decodeTokens := 0
+	for _, r := range results {
+		if r == nil || r.TimeToFirstToken <= 0 {
+			continue
+		}
+		ttfts = append(ttfts, durationMs(r.TimeToFirstToken))
+		if len(r.InterTokenLatencies) > 0 && r.CompletionTokens > 1 {
+			latencies = append(latencies, r.InterTokenLatencies...)
+			for _, latency := range r.InterTokenLatencies {
+				decodeTime += latency
+			}
+			// The tokens after the first are generated between the first and last delta
+			decodeTokens += r.CompletionTokens - 1
+		}
+	}
+	if len(ttfts) == 0 {
+		return
+	}
+
+	sum := 0.0
+	for _, ttft := range ttfts {
+		sum += ttft
+	}
+	modelFit.TTFTMean = sum / float64(len(ttfts))
+	mean, p99 := interTokenStats(latencies)
+	modelFit.ITLMean = durationMs(mean)
+	modelFit.ITLP99 = durationMs(p99)
+	if decodeTime > 0 {
+		modelFit.ITLTokensPerSec = float64(decodeTokens) / decodeTime.Seconds()
+	}
+}
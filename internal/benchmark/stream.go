@@ -0,0 +1,125 @@
+package benchmark
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// StreamOptions asks a streaming endpoint to send the token usage in a final chunk
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// chatCompletionChunk is the subset of a streamed chat completion chunk holding the deltas
+type chatCompletionChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// streamedResponse is a chat completion reassembled from its server-sent events, with the
+// arrival times of the generated content
+type streamedResponse struct {
+	Response            ChatCompletionResponse
+	TimeToFirstToken    time.Duration   // from sending the request to the first content delta
+	InterTokenLatencies []time.Duration // between successive content deltas
+}
+
+// readStream reads the server-sent events of a streamed chat completion sent at
+// startTime until the [DONE] event or the end of the body. Chunks are merged into one
+// response, so the usage and cache reporting of the final chunk are decoded as usual.
+func readStream(r io.Reader, startTime time.Time) (*streamedResponse, error) {
+	var streamed streamedResponse
+	var content, finishReason string
+	var lastDelta time.Time
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		receivedAt := time.Now()
+		if data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:")); ok {
+			data = bytes.TrimSpace(data)
+			if string(data) == "[DONE]" {
+				break
+			}
+
+			var chunk chatCompletionChunk
+			if err := json.Unmarshal(data, &chunk); err != nil {
+				return nil, fmt.Errorf("error decoding stream chunk: %v: %s", err, bodySnippet(data))
+			}
+			// Fields a chunk sets, e.g. the usage of the final one, replace the earlier ones
+			if err := json.Unmarshal(data, &streamed.Response); err != nil {
+				return nil, fmt.Errorf("error decoding stream chunk: %v: %s", err, bodySnippet(data))
+			}
+
+			for _, choice := range chunk.Choices {
+				if choice.FinishReason != "" {
+					finishReason = choice.FinishReason
+				}
+				// Reasoning models stream their reasoning separately, its tokens are generated too
+				delta := choice.Delta.Content + choice.Delta.ReasoningContent
+				if delta == "" {
+					continue
+				}
+				content += choice.Delta.Content
+				if lastDelta.IsZero() {
+					streamed.TimeToFirstToken = receivedAt.Sub(startTime)
+				} else {
+					streamed.InterTokenLatencies = append(streamed.InterTokenLatencies, receivedAt.Sub(lastDelta))
+				}
+				lastDelta = receivedAt
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	streamed.Response.Choices = streamed.Response.Choices[:0]
+	streamed.Response.Choices = append(streamed.Response.Choices, struct {
+		Message      ChatMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
+	}{Message: ChatMessage{Role: "assistant", Content: content}, FinishReason: finishReason})
+	return &streamed, nil
+}
+
+// readStreamBody reads a streamed response as it arrives, keeping a copy of the body as
+// received in raw for capturing
+func readStreamBody(resp *http.Response, raw *bytes.Buffer, startTime time.Time) (*streamedResponse, error) {
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(resp.Body, raw), resp.Body}
+	body, err := decodeBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	return readStream(body, startTime)
+}
+
+// interTokenStats returns the mean and 99th percentile of inter-token latencies
+func interTokenStats(latencies []time.Duration) (time.Duration, time.Duration) {
+	if len(latencies) == 0 {
+		return 0, 0
+	}
+	values := make([]float64, len(latencies))
+	sum := 0.0
+	for i, latency := range latencies {
+		values[i] = float64(latency)
+		sum += values[i]
+	}
+	return time.Duration(sum / float64(len(values))), time.Duration(percentile(values, 99))
+}
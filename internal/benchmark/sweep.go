@@ -0,0 +1,132 @@
+package benchmark
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSweepPromptTokens are the prompt lengths, in tokens, of a prompt sweep
+var DefaultSweepPromptTokens = []int{256, 1024, 2048, 4096, 8192, 16384}
+
+// SweepRepeats is the number of requests per prompt length; the fastest one is kept
+const SweepRepeats = 2
+
+// SweepPoint is the prompt processing speed measured at one prompt length
+type SweepPoint struct {
+	PromptTokens       int     // prompt tokens reported by the server
+	ResponseTimeMs     float64 // response time of the fastest request with one completion token
+	PromptTokensPerSec float64 // PromptTokens / ResponseTimeMs
+}
+
+// parsePromptSweep reads the prompt_sweep parameter: true for the default prompt lengths,
+// or a comma-separated list of prompt lengths in tokens. It returns nil if no sweep is
+// requested.
+func parsePromptSweep(params map[string]interface{}) ([]int, error) {
+	switch v := params["prompt_sweep"].(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		if v {
+			return DefaultSweepPromptTokens, nil
+		}
+		return nil, nil
+	case int:
+		return parseTokenList("prompt_sweep", strconv.Itoa(v))
+	case string:
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			return parsePromptSweep(map[string]interface{}{"prompt_sweep": enabled})
+		}
+		return parseTokenList("prompt_sweep", v)
+	default:
+		return nil, fmt.Errorf("invalid prompt_sweep: %v", v)
+	}
+}
+
+// parseTokenList parses the comma-separated list of prompt lengths in tokens of a parameter
+func parseTokenList(name string, list string) ([]int, error) {
+	var lengths []int
+	for _, field := range strings.Split(list, ",") {
+		length, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || length <= 0 {
+			return nil, fmt.Errorf("invalid %s length %q: must be a positive number of tokens", name, field)
+		}
+		lengths = append(lengths, length)
+	}
+	return lengths, nil
+}
+
+// RunPromptSweep measures prompt processing speed at each of the given prompt lengths
+// in tokens, with a single completion token so the response time is dominated by the
+// prefill. Lengths that don't fit the context limit are skipped, and the sweep stops at
+// the first length the server fails, since longer prompts would fail too.
+func (b *Benchmark) RunPromptSweep(promptTokens []int, postfix string) []SweepPoint {
+	b.log().Info("Running prompt sweep", "component", "benchmark", "prompt_tokens", fmt.Sprint(promptTokens))
+
+	// Prompt lengths are generated in characters; the ratio is refined with every response
+	charsPerToken := float64(bytesPerToken)
+	if b.ContextLimit != nil && b.ContextLimit.CharsPerToken > 0 {
+		charsPerToken = b.ContextLimit.CharsPerToken
+	}
+
+	var points []SweepPoint
+	b.progress.addConfigs(len(promptTokens))
+	for i, tokens := range promptTokens {
+		promptLength := int(float64(tokens)*charsPerToken) - len(postfix)
+		if promptLength <= 0 {
+			promptLength = 1
+		}
+		if b.ContextLimit != nil && float64(promptLength) > allowedPromptLength(b.ContextLimit, 1, postfix) {
+			b.log().Warn("Skipping sweep prompt length that exceeds the context limit",
+				"component", "benchmark",
+				"prompt_tokens", tokens,
+				"max_context_tokens", b.ContextLimit.MaxTokens)
+			b.progress.configsDone(1)
+			continue
+		}
+
+		var fastest *CompletionResult
+		for repeat := 0; repeat < SweepRepeats; repeat++ {
+			result, err := b.ChatCompletion(b.completionParams(b.generateMessages(promptLength, postfix), 1))
+
+			// Small delay between requests to avoid overwhelming the server
+			time.Sleep(500 * time.Millisecond)
+
+			if err != nil {
+				b.log().Error("Prompt sweep request failed, stopping the sweep",
+					"component", "benchmark",
+					"prompt_tokens", tokens,
+					"error", err)
+				b.progress.configsDone(len(promptTokens) - i)
+				return points
+			}
+			if fastest == nil || result.ResponseTime < fastest.ResponseTime {
+				fastest = result
+			}
+		}
+
+		measuredTokens := fastest.PromptTokens + fastest.CachedPromptTokens
+		if measuredTokens > 0 {
+			charsPerToken = float64(promptLength+len(postfix)) / float64(measuredTokens)
+		}
+
+		point := SweepPoint{
+			PromptTokens:   measuredTokens,
+			ResponseTimeMs: float64(fastest.ResponseTime.Microseconds()) / 1000,
+		}
+		if point.ResponseTimeMs > 0 {
+			point.PromptTokensPerSec = float64(point.PromptTokens) / point.ResponseTimeMs * 1000
+		}
+		points = append(points, point)
+		b.progress.configsDone(1)
+
+		b.log().Info("Prompt sweep point completed",
+			"component", "benchmark",
+			"prompt_tokens", point.PromptTokens,
+			"response_time_ms", point.ResponseTimeMs,
+			"prompt_tokens_per_sec", point.PromptTokensPerSec)
+	}
+
+	return points
+}
@@ -0,0 +1,137 @@
+package benchmark
+
+import (
+	"bufio"
+	"bytes"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/aifoundry-org/turtlenekko/internal/driver"
+)
+
+// systemInfo returns the metadata describing the hardware of a combination: with the
+// system_info parameter, the hostname, OS, CPU and NVIDIA GPUs of the machine running
+// the benchmark, and the fields printed by the info_cmd parameter. It returns nil if
+// neither is configured.
+func systemInfo(d driver.Driver, params map[string]interface{}, logger *slog.Logger) (map[string]interface{}, error) {
+	info := make(map[string]interface{})
+	collect, err := paramBool(params, "system_info", false)
+	if err != nil {
+		return nil, err
+	}
+	if collect {
+		collectSystemInfo(info, logger)
+	}
+	if infoCmd := paramString(params, "info_cmd", ""); infoCmd != "" {
+		runInfoCommand(d, infoCmd, params, info, logger)
+	}
+
+	if len(info) == 0 {
+		return nil, nil
+	}
+	logger.Debug("System info", "component", "benchmark", "info", info)
+	return info, nil
+}
+
+// collectSystemInfo adds the hostname, OS, CPU and NVIDIA GPUs of the local machine to
+// info, leaving out what can't be determined
+func collectSystemInfo(info map[string]interface{}, logger *slog.Logger) {
+	if hostname, err := os.Hostname(); err == nil {
+		info["hostname"] = hostname
+	}
+	info["os"] = runtime.GOOS + "/" + runtime.GOARCH
+	info["cpu_count"] = runtime.NumCPU()
+	if model := cpuModel(); model != "" {
+		info["cpu_model"] = model
+	}
+
+	if _, err := exec.LookPath("nvidia-smi"); err != nil {
+		return
+	}
+	output, err := exec.Command("nvidia-smi", "--query-gpu=name,memory.total", "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		logger.Warn("Failed to query the GPUs", "component", "benchmark", "error", err)
+		return
+	}
+	var names []string
+	memoryMiB := 0
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		name, memory, ok := strings.Cut(line, ",")
+		if !ok {
+			continue
+		}
+		names = append(names, strings.TrimSpace(name))
+		if mib, err := strconv.Atoi(strings.TrimSpace(memory)); err == nil {
+			memoryMiB += mib
+		}
+	}
+	if len(names) > 0 {
+		info["gpu"] = strings.Join(names, ", ")
+		info["gpu_count"] = len(names)
+		info["gpu_memory_mib"] = memoryMiB
+	}
+}
+
+// cpuModel returns the CPU model name, or empty if the OS doesn't report it
+func cpuModel() string {
+	switch runtime.GOOS {
+	case "linux":
+		data, err := os.ReadFile("/proc/cpuinfo")
+		if err != nil {
+			return ""
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			key, value, ok := strings.Cut(line, ":")
+			if ok && strings.TrimSpace(key) == "model name" {
+				return strings.TrimSpace(value)
+			}
+		}
+	case "darwin":
+		output, err := exec.Command("sysctl", "-n", "machdep.cpu.brand_string").Output()
+		if err == nil {
+			return strings.TrimSpace(string(output))
+		}
+	}
+	return ""
+}
+
+// runInfoCommand runs the info_cmd parameter, on the server host if the driver runs
+// commands there, and adds the "key=value" lines it prints to info. Failures are logged
+// but don't fail the combination.
+func runInfoCommand(d driver.Driver, infoCmd string, params map[string]interface{}, info map[string]interface{}, logger *slog.Logger) {
+	cmd, err := driver.InterpolateCommand(infoCmd, params)
+	if err != nil {
+		logger.Error("Failed to prepare info command", "component", "benchmark", "error", err)
+		return
+	}
+
+	logger.Info("Running info command", "component", "benchmark", "command", cmd)
+
+	if reusable, ok := d.(*reusableDriver); ok {
+		d = reusable.Driver
+	}
+	var output []byte
+	if runner, ok := d.(driver.CommandRunner); ok {
+		output, err = runner.RunCommand(cmd)
+	} else {
+		output, err = exec.Command("sh", "-c", cmd).Output()
+	}
+	if err != nil {
+		logger.Error("Info command failed", "component", "benchmark", "error", err, "output", string(output))
+		return
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		info[key] = strings.TrimSpace(value)
+	}
+}
@@ -0,0 +1,73 @@
+package benchmark
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultThrottlingThreshold is the slowdown of the last iteration relative to the first
+// above which thermal throttling is reported
+const DefaultThrottlingThreshold = 0.1
+
+// iterationSlowdowns returns, per iteration, the median ratio of measured to predicted
+// response time relative to that of the first iteration. Iterations run different
+// configs, so the raw rates aren't comparable; the ratio to the fitted model is.
+// It returns nil with fewer than two iterations.
+func iterationSlowdowns(modelFit *ModelFitResult, iterationResults [][]*CompletionResult) []float64 {
+	var ratios []float64
+	for _, results := range iterationResults {
+		var iterationRatios []float64
+		for _, result := range results {
+			predicted := modelFit.PredictMs(result)
+			if predicted <= 0 {
+				continue
+			}
+			measured := float64(result.ResponseTime.Microseconds()) / 1000
+			iterationRatios = append(iterationRatios, measured/predicted)
+		}
+		if len(iterationRatios) == 0 {
+			continue
+		}
+		ratios = append(ratios, percentile(iterationRatios, 50))
+	}
+	if len(ratios) < 2 || ratios[0] <= 0 {
+		return nil
+	}
+
+	slowdowns := make([]float64, len(ratios))
+	for i, ratio := range ratios {
+		slowdowns[i] = math.Round(ratio/ratios[0]*100) / 100
+	}
+	return slowdowns
+}
+
+// detectThrottling stores the per-iteration slowdowns on the model fit and flags
+// possible thermal throttling if every iteration was at least as slow as the previous
+// one and the last was slower than the first by more than ThrottlingThreshold. Taking
+// the fastest sample hides such a drift, so the rates are biased towards the cool start.
+func (b *Benchmark) detectThrottling(contextType string, modelFit *ModelFitResult, iterationResults [][]*CompletionResult) {
+	if modelFit == nil || modelFit.Fallback {
+		return
+	}
+
+	slowdowns := iterationSlowdowns(modelFit, iterationResults)
+	modelFit.IterationSlowdowns = slowdowns
+	if len(slowdowns) < 2 {
+		return
+	}
+
+	for i := 1; i < len(slowdowns); i++ {
+		if slowdowns[i] < slowdowns[i-1] {
+			return
+		}
+	}
+	if slowdowns[len(slowdowns)-1]-1 <= b.ThrottlingThreshold {
+		return
+	}
+
+	modelFit.Throttling = true
+	b.log().Warn(fmt.Sprintf("Possible thermal throttling detected in %s context benchmark", contextType),
+		"component", "benchmark",
+		"iteration_slowdowns", slowdowns,
+		"threshold", b.ThrottlingThreshold)
+}
@@ -0,0 +1,64 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aifoundry-org/turtlenekko/internal/driver"
+)
+
+// DefaultRequestTimeout is the default timeout of a single chat completion request. It is
+// long enough for large context generations on CPU; drivers of servers that answer
+// quickly declare a shorter one.
+const DefaultRequestTimeout = 600 * time.Second
+
+// requestContext returns the context of a single request attempt, cancelled after the
+// request timeout
+func (b *Benchmark) requestContext() (context.Context, context.CancelFunc) {
+	if b.Timeout > 0 {
+		return context.WithTimeout(b.context(), b.Timeout)
+	}
+	return context.WithCancel(b.context())
+}
+
+// requestError wraps the error of a request, naming the timeout if one cancelled it
+func (b *Benchmark) requestError(ctx context.Context, message string, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		if errors.Is(b.context().Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%s: benchmark deadline exceeded (benchmark_timeout_s)", message)
+		}
+		return fmt.Errorf("%s: request timed out after %v (request_timeout_s)", message, b.Timeout)
+	}
+	return fmt.Errorf("%s: %v", message, err)
+}
+
+// driverRequestTimeout returns the default request timeout with d: the driver's own if it
+// implements driver.RequestTimeouter, otherwise DefaultRequestTimeout
+func driverRequestTimeout(d driver.Driver) time.Duration {
+	if timeouter, ok := d.(driver.RequestTimeouter); ok {
+		return timeouter.RequestTimeout()
+	}
+	return DefaultRequestTimeout
+}
+
+// timeoutsFromParams reads the request_timeout_s and benchmark_timeout_s parameters,
+// with defaultRequestTimeout used if request_timeout_s isn't set
+func timeoutsFromParams(params map[string]interface{}, defaultRequestTimeout time.Duration) (requestTimeout time.Duration, benchmarkTimeout time.Duration, err error) {
+	requestTimeoutS, err := paramFloat(params, "request_timeout_s", defaultRequestTimeout.Seconds())
+	if err != nil {
+		return 0, 0, err
+	}
+	if requestTimeoutS < 0 {
+		return 0, 0, fmt.Errorf("request_timeout_s must not be negative, got %v", requestTimeoutS)
+	}
+	benchmarkTimeoutS, err := paramFloat(params, "benchmark_timeout_s", 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	if benchmarkTimeoutS < 0 {
+		return 0, 0, fmt.Errorf("benchmark_timeout_s must not be negative, got %v", benchmarkTimeoutS)
+	}
+	return time.Duration(requestTimeoutS * float64(time.Second)), time.Duration(benchmarkTimeoutS * float64(time.Second)), nil
+}
@@ -0,0 +1,60 @@
+package benchmark
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// TokenCommand provides the bearer token from a shell command that prints it, e.g.
+// "gcloud auth print-access-token". The command is re-run when the token is rejected.
+type TokenCommand struct {
+	Command string
+
+	mu    sync.Mutex
+	token string
+}
+
+// NewTokenCommand creates a token source for the given command
+func NewTokenCommand(command string) *TokenCommand {
+	return &TokenCommand{Command: command}
+}
+
+// Token returns the current token, running the command if there is none yet
+func (t *TokenCommand) Token() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token == "" {
+		return t.run()
+	}
+	return t.token, nil
+}
+
+// Refresh re-runs the command after the server rejected the stale token. If another
+// request has refreshed it in the meantime, the new token is returned without running
+// the command again.
+func (t *TokenCommand) Refresh(stale string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != stale {
+		return t.token, nil
+	}
+	return t.run()
+}
+
+// run runs the command and stores its trimmed output as the token
+func (t *TokenCommand) run() (string, error) {
+	output, err := exec.Command("sh", "-c", t.Command).Output()
+	if err != nil {
+		return "", fmt.Errorf("error running token command: %v", err)
+	}
+	token := strings.TrimSpace(string(output))
+	if token == "" {
+		return "", fmt.Errorf("token command printed no token")
+	}
+	t.token = token
+	return token, nil
+}
@@ -0,0 +1,52 @@
+package benchmark
+
+import "math"
+
+// recordPromptRatio adds a request's prompt to the observed prompt tokens per byte.
+// Only prompts the server counted itself are used; the first one is logged, as it tells
+// how many bytes produce a given token count with the server's tokenizer.
+func (b *Benchmark) recordPromptRatio(messages []ChatMessage, result *CompletionResult) {
+	tokens := result.PromptTokens + result.CachedPromptTokens
+	if result.UsageEstimated || tokens == 0 {
+		return
+	}
+	for _, message := range messages {
+		b.promptBytes += len(message.Content)
+	}
+	b.promptTokens += tokens
+
+	if b.promptBytes > 0 && b.promptTokens == tokens {
+		b.log().Info("Observed prompt tokens per byte",
+			"component", "benchmark",
+			"prompt_tokens_per_byte", b.PromptTokensPerByte(),
+			"assumed", 1.0/bytesPerToken)
+	}
+}
+
+// PromptTokensPerByte returns the prompt tokens the server counted per byte of prompt
+// sent, over all requests so far, or 0 if no server counts were reported
+func (b *Benchmark) PromptTokensPerByte() float64 {
+	if b.promptBytes == 0 {
+		return 0
+	}
+	return float64(b.promptTokens) / float64(b.promptBytes)
+}
+
+// calibrateConfigs scales the prompt lengths of configs by the observed prompt tokens per
+// byte, so the prompts come out at the token counts the lengths stand for at bytesPerToken
+// bytes per token, whatever the server's tokenizer. The configs are returned unchanged if
+// the ratio isn't known yet.
+func (b *Benchmark) calibrateConfigs(configs []BenchmarkConfig, postfix string) []BenchmarkConfig {
+	ratio := b.PromptTokensPerByte()
+	if ratio == 0 {
+		return configs
+	}
+
+	calibrated := make([]BenchmarkConfig, len(configs))
+	for i, config := range configs {
+		targetTokens := float64(config.PromptLength+len(postfix)) / bytesPerToken
+		config.PromptLength = max(1, int(math.Round(targetTokens/ratio))-len(postfix))
+		calibrated[i] = config
+	}
+	return calibrated
+}
@@ -0,0 +1,123 @@
+package benchmark
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTP protocols the client can be restricted to
+const (
+	ProtocolHTTP1 = "http1" // HTTP/1.1 only, a connection per in-flight request
+	ProtocolHTTP2 = "http2" // HTTP/2 only, requests multiplexed over one connection
+)
+
+// TransportConfig holds the connection settings of the HTTP client sending the requests.
+// Zero values keep the defaults of Go's http.DefaultTransport.
+type TransportConfig struct {
+	MaxIdleConns        int           // idle connections kept open across all hosts
+	MaxIdleConnsPerHost int           // idle connections kept open per host
+	MaxConnsPerHost     int           // connections per host, including active ones; 0 is unlimited
+	IdleConnTimeout     time.Duration // how long an idle connection is kept open
+	Protocol            string        // ProtocolHTTP1 or ProtocolHTTP2, empty negotiates
+}
+
+// transportFromParams reads the connection settings from the max_idle_conns,
+// max_idle_conns_per_host, max_conns_per_host, idle_conn_timeout_s and protocol parameters
+func transportFromParams(params map[string]interface{}) (TransportConfig, error) {
+	config := TransportConfig{Protocol: paramString(params, "protocol", "")}
+	var err error
+	if config.MaxIdleConns, err = paramInt(params, "max_idle_conns", 0); err != nil {
+		return config, err
+	}
+	if config.MaxIdleConnsPerHost, err = paramInt(params, "max_idle_conns_per_host", 0); err != nil {
+		return config, err
+	}
+	if config.MaxConnsPerHost, err = paramInt(params, "max_conns_per_host", 0); err != nil {
+		return config, err
+	}
+	idleConnTimeoutS, err := paramFloat(params, "idle_conn_timeout_s", 0)
+	if err != nil {
+		return config, err
+	}
+	config.IdleConnTimeout = time.Duration(idleConnTimeoutS * float64(time.Second))
+	for name, value := range map[string]int{
+		"max_idle_conns":          config.MaxIdleConns,
+		"max_idle_conns_per_host": config.MaxIdleConnsPerHost,
+		"max_conns_per_host":      config.MaxConnsPerHost,
+	} {
+		if value < 0 {
+			return config, fmt.Errorf("%s must not be negative, got %d", name, value)
+		}
+	}
+	if config.IdleConnTimeout < 0 {
+		return config, fmt.Errorf("idle_conn_timeout_s must not be negative, got %v", config.IdleConnTimeout.Seconds())
+	}
+	switch config.Protocol {
+	case "", ProtocolHTTP1, ProtocolHTTP2:
+	default:
+		return config, fmt.Errorf("invalid protocol %q, must be %s or %s", config.Protocol, ProtocolHTTP1, ProtocolHTTP2)
+	}
+	return config, nil
+}
+
+// isDefault reports whether no setting differs from the default transport
+func (c TransportConfig) isDefault() bool {
+	return c == TransportConfig{}
+}
+
+// NewTransport returns a copy of http.DefaultTransport with the configured settings
+func (c TransportConfig) NewTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if c.MaxIdleConns > 0 {
+		transport.MaxIdleConns = c.MaxIdleConns
+	}
+	if c.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = c.MaxIdleConnsPerHost
+	}
+	if c.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = c.MaxConnsPerHost
+	}
+	if c.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = c.IdleConnTimeout
+	}
+	switch c.Protocol {
+	case ProtocolHTTP1:
+		// A non-nil empty map disables HTTP/2
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	case ProtocolHTTP2:
+		transport.ForceAttemptHTTP2 = true
+	}
+	return transport
+}
+
+// validateProtocol checks that the protocol can be used with the endpoint URL: HTTP/2 is
+// negotiated during the TLS handshake, so it needs an https URL
+func validateProtocol(protocol string, url string) error {
+	if protocol == ProtocolHTTP2 && !strings.HasPrefix(url, "https://") {
+		return fmt.Errorf("protocol %s needs an https URL, got %s (HTTP/2 without TLS is not supported)", ProtocolHTTP2, url)
+	}
+	return nil
+}
+
+// checkProtocol records the protocol of the first response and fails responses that
+// didn't use the requested one, which would measure something else than intended
+func (b *Benchmark) checkProtocol(resp *http.Response) error {
+	b.protocolOnce.Do(func() {
+		b.negotiatedProtocol = resp.Proto
+		b.log().Info("Negotiated protocol", "component", "benchmark", "protocol", resp.Proto)
+	})
+	if b.Protocol == ProtocolHTTP2 && resp.ProtoMajor != 2 {
+		return fmt.Errorf("server answered with %s although protocol is %s", resp.Proto, ProtocolHTTP2)
+	}
+	return nil
+}
+
+// NegotiatedProtocol returns the protocol of the first response, e.g. "HTTP/2.0", or an
+// empty string if no request succeeded
+func (b *Benchmark) NegotiatedProtocol() string {
+	return b.negotiatedProtocol
+}
@@ -0,0 +1,25 @@
+package benchmark
+
+// bytesPerToken is the approximate number of bytes per token used for client-side estimates
+const bytesPerToken = 4
+
+// estimateTokens roughly estimates the number of prompt tokens of the messages
+func estimateTokens(messages []ChatMessage) int {
+	promptBytes := 0
+	for _, message := range messages {
+		promptBytes += len(message.Content)
+	}
+	return promptBytes/bytesPerToken + 1
+}
+
+// estimateUsage fills in token counts estimated from the request and the generated
+// text, for servers that do not report usage
+func estimateUsage(result *CompletionResult, params ChatCompletionParams, content string) {
+	result.PromptTokens = estimateTokens(params.Messages)
+	result.CachedPromptTokens = 0
+	result.CacheReported = false
+	if result.CompletionTokens == 0 && content != "" {
+		result.CompletionTokens = len(content)/bytesPerToken + 1
+	}
+	result.UsageEstimated = true
+}
@@ -0,0 +1,91 @@
+package benchmark
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Warmup modes
+const (
+	WarmupModeFixed  = "fixed"  // send a fixed number of warmup requests
+	WarmupModeStable = "stable" // repeat warmup requests until their response times stabilize
+)
+
+// Default warmup settings
+const (
+	DefaultWarmupCount     = 1    // warmup requests in fixed mode
+	DefaultWarmupTolerance = 0.05 // relative difference of consecutive response times considered stable
+	DefaultWarmupMax       = 10   // most warmup requests in stable mode
+)
+
+// WarmupResult describes the warmup requests sent before the measurements
+type WarmupResult struct {
+	Mode     string
+	Requests int  // warmup requests sent, including failed ones
+	Stable   bool // in stable mode, whether the response times stabilized before the maximum
+}
+
+// validateWarmupMode checks that the warmup mode is supported
+func validateWarmupMode(mode string) error {
+	switch mode {
+	case WarmupModeFixed, WarmupModeStable:
+		return nil
+	default:
+		return fmt.Errorf("unknown warmup_mode: %s (supported: %s, %s)", mode, WarmupModeFixed, WarmupModeStable)
+	}
+}
+
+// warmupStable reports whether two consecutive warmup response times are within the
+// relative tolerance of each other
+func warmupStable(previous, current time.Duration, tolerance float64) bool {
+	if previous <= 0 {
+		return false
+	}
+	return math.Abs(float64(current-previous))/float64(previous) <= tolerance
+}
+
+// runWarmup sends the warmup requests: WarmupCount of them in fixed mode, or in stable
+// mode until two consecutive response times are within WarmupTolerance, up to WarmupMax.
+// Failed warmup requests are logged and don't stop the benchmark.
+func (b *Benchmark) runWarmup() *WarmupResult {
+	result := &WarmupResult{Mode: b.WarmupMode}
+	limit := b.WarmupCount
+	if b.WarmupMode == WarmupModeStable {
+		limit = b.WarmupMax
+	}
+
+	var previous time.Duration
+	for result.Requests < limit {
+		result.Requests++
+		b.log().Info("Running warmup request", "component", "benchmark",
+			"request", result.Requests,
+			"prompt_length", b.WarmupPromptLength,
+			"max_tokens", b.WarmupMaxTokens)
+		results, err := b.RunWithPromptLength(b.WarmupPromptLength, b.WarmupMaxTokens, "Just a warmup request.")
+		if err != nil {
+			b.log().Warn("Warmup request failed (continuing with benchmark)", "component", "benchmark", "error", err)
+			previous = 0
+			continue
+		}
+		b.log().Info("Warmup request completed successfully", "component", "benchmark",
+			"response_time_ms", results[0].ResponseTime.Milliseconds())
+
+		if b.WarmupMode == WarmupModeStable && warmupStable(previous, results[0].ResponseTime, b.WarmupTolerance) {
+			result.Stable = true
+			b.log().Info("Warmup response times stabilized", "component", "benchmark",
+				"requests", result.Requests,
+				"tolerance", b.WarmupTolerance)
+			break
+		}
+		previous = results[0].ResponseTime
+	}
+
+	if b.WarmupMode == WarmupModeStable && !result.Stable {
+		b.log().Warn("Warmup response times did not stabilize, the first measurements may include warmup effects",
+			"component", "benchmark",
+			"requests", result.Requests,
+			"tolerance", b.WarmupTolerance)
+	}
+	return result
+}
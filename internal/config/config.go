@@ -2,8 +2,11 @@ package config
 
 import (
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/aifoundry-org/turtlenekko/internal/types"
 	"gopkg.in/yaml.v3"
@@ -13,28 +16,83 @@ import (
 type Config struct {
 	Driver string                           `yaml:"driver"`
 	Matrix map[string]types.ParameterConfig `yaml:"matrix"`
+	// Combinations lists exact parameter sets to run instead of the full cross product
+	Combinations []map[string]interface{} `yaml:"combinations"`
+	// Thresholds are the accepted ranges of result metrics, checked after the run
+	Thresholds map[string]types.Threshold `yaml:"thresholds"`
+	// Warmup configures the warmup requests, nil keeps the defaults
+	Warmup *types.WarmupConfig `yaml:"warmup"`
 }
 
-// Load loads the configuration from a YAML file
+// StdinPath is the configuration path that reads the configuration from stdin
+const StdinPath = "-"
+
+// Load loads the configuration from a YAML file, or from stdin if path is StdinPath
 func Load(path string) (*Config, error) {
 	// Check if file exists
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		slog.Warn("Configuration file not found, printing example config", "path", path)
-		PrintDefaultConfig()
-		return nil, fmt.Errorf("configuration file not found: %s", path)
+	if path != StdinPath {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			slog.Warn("Configuration file not found, printing example config", "path", path)
+			PrintDefaultConfig()
+			return nil, fmt.Errorf("configuration file not found: %s", path)
+		}
+	}
+
+	data, dir, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parse(data, dir)
+}
+
+// readFile reads a configuration file, or stdin if path is StdinPath. It also returns
+// the directory values files are relative to: the file's, or the working directory for stdin.
+func readFile(path string) ([]byte, string, error) {
+	if path == StdinPath {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, "", fmt.Errorf("error reading configuration from stdin: %v", err)
+		}
+		return data, ".", nil
 	}
 
-	// Read file
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("error reading configuration file: %v", err)
+		return nil, "", fmt.Errorf("error reading configuration file: %v", err)
 	}
+	return data, filepath.Dir(path), nil
+}
+
+// checkStdinOnce rejects reading stdin for more than one of paths, as it can only be read once
+func checkStdinOnce(paths []string) error {
+	count := 0
+	for _, path := range paths {
+		if path == StdinPath {
+			count++
+		}
+	}
+	if count > 1 {
+		return fmt.Errorf("configuration can only be read from stdin (%s) once", StdinPath)
+	}
+	return nil
+}
 
+// Parse parses configuration data in YAML (or JSON) format. Parameters can't read their
+// values from files, as the data may not come from this machine.
+func Parse(data []byte) (*Config, error) {
+	return parse(data, "")
+}
+
+// parse parses configuration data, reading values files relative to dir if it is set
+func parse(data []byte, dir string) (*Config, error) {
 	// Parse YAML
 	// First try to parse with a flexible format that can handle both simple arrays and objects
 	var flexConfig struct {
-		Driver string                 `yaml:"driver"`
-		Matrix map[string]interface{} `yaml:"matrix"`
+		Driver       string                     `yaml:"driver"`
+		Matrix       map[string]interface{}     `yaml:"matrix"`
+		Combinations []map[string]interface{}   `yaml:"combinations"`
+		Thresholds   map[string]types.Threshold `yaml:"thresholds"`
+		Warmup       *types.WarmupConfig        `yaml:"warmup"`
 	}
 
 	if err := yaml.Unmarshal(data, &flexConfig); err != nil {
@@ -49,8 +107,11 @@ func Load(path string) (*Config, error) {
 
 	// Create the final config
 	config := &Config{
-		Driver: flexConfig.Driver,
-		Matrix: make(map[string]types.ParameterConfig),
+		Driver:       flexConfig.Driver,
+		Matrix:       make(map[string]types.ParameterConfig),
+		Combinations: flexConfig.Combinations,
+		Thresholds:   flexConfig.Thresholds,
+		Warmup:       flexConfig.Warmup,
 	}
 
 	// Process each parameter in the matrix
@@ -59,44 +120,55 @@ func Load(path string) (*Config, error) {
 		case map[string]interface{}: // Object with attributes
 			paramConfig := types.ParameterConfig{
 				Output: true, // Default to true
+				Driver: true,
+			}
+
+			// Read the values from a file, one per line
+			if valuesFile, ok := v["values_file"].(string); ok {
+				if _, ok := v["values"]; ok {
+					return nil, fmt.Errorf("values and values_file of key %s are mutually exclusive", key)
+				}
+				if dir == "" {
+					return nil, fmt.Errorf("values_file of key %s is only supported in configuration files", key)
+				}
+				values, err := readValuesFile(dir, valuesFile)
+				if err != nil {
+					return nil, fmt.Errorf("invalid values_file for key %s: %v", key, err)
+				}
+				v["values"] = values
 			}
 
 			// Handle the case where we have a list of objects with values
 			if valuesArray, ok := v["values"].([]interface{}); ok {
 				// This is the format: key: { values: [...], output: bool }
-				strValues := make([]string, len(valuesArray))
-				for i, val := range valuesArray {
-					if str, ok := val.(string); ok {
-						strValues[i] = str
-					} else {
-						strValues[i] = fmt.Sprintf("%v", val)
+				paramConfig.Values = valuesArray
+
+				// Extract output flag if present, either for all formats or per format
+				switch output := v["output"].(type) {
+				case bool:
+					paramConfig.Output = output
+				case map[string]interface{}:
+					formatOutput, err := parseFormatOutput(key, output)
+					if err != nil {
+						return nil, err
 					}
+					paramConfig.FormatOutput = formatOutput
 				}
-				paramConfig.Values = strValues
 
-				// Extract output flag if present
-				if output, ok := v["output"].(bool); ok {
-					paramConfig.Output = output
+				// Extract driver flag if present
+				if driverFlag, ok := v["driver"].(bool); ok {
+					paramConfig.Driver = driverFlag
 				}
 
 				config.Matrix[key] = paramConfig
 			}
 
 		case []interface{}: // Simple array of values
-			// Convert to []string
-			strValues := make([]string, len(v))
-			for i, val := range v {
-				if str, ok := val.(string); ok {
-					strValues[i] = str
-				} else {
-					strValues[i] = fmt.Sprintf("%v", val)
-				}
-			}
-
 			// Create parameter config with default attributes
 			config.Matrix[key] = types.ParameterConfig{
-				Values: strValues,
+				Values: v,
 				Output: true, // Default to true
+				Driver: true,
 			}
 
 		default:
@@ -112,3 +184,86 @@ func Load(path string) (*Config, error) {
 
 	return config, nil
 }
+
+// parseFormatOutput parses the per-format output flags of a parameter, e.g.
+// {csv: false, json: true}
+func parseFormatOutput(key string, output map[string]interface{}) (map[string]bool, error) {
+	formatOutput := make(map[string]bool)
+	for format, value := range output {
+		if !isOutputFormat(format) {
+			return nil, fmt.Errorf("invalid output format %q for key %s, expected one of %s", format, key, strings.Join(types.OutputFormats, ", "))
+		}
+		flag, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid output flag for format %s of key %s: %v", format, key, value)
+		}
+		formatOutput[format] = flag
+	}
+	return formatOutput, nil
+}
+
+// isOutputFormat reports whether format is one of types.OutputFormats
+func isOutputFormat(format string) bool {
+	for _, f := range types.OutputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadFiles loads several configuration files and merges them in order: later files
+// override the driver and combinations of earlier ones, and matrix parameters and thresholds
+// are merged key by key, with an entry in a later file replacing the same one from earlier files
+func LoadFiles(paths []string) (*Config, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no configuration file given")
+	}
+	if err := checkStdinOnce(paths); err != nil {
+		return nil, err
+	}
+
+	merged := &Config{
+		Matrix:     make(map[string]types.ParameterConfig),
+		Thresholds: make(map[string]types.Threshold),
+	}
+	for _, path := range paths {
+		cfg, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		Merge(merged, cfg)
+		slog.Debug("Merged configuration file", "path", path, "driver", merged.Driver)
+	}
+
+	if merged.Driver == "" {
+		return nil, fmt.Errorf("no driver set in configuration")
+	}
+	if len(merged.Matrix) == 0 && len(merged.Combinations) == 0 {
+		return nil, fmt.Errorf("no matrix parameters or combinations set in configuration")
+	}
+
+	return merged, nil
+}
+
+// Merge applies override on top of base
+func Merge(base *Config, override *Config) {
+	if override.Driver != "" {
+		base.Driver = override.Driver
+	}
+	for key, paramConfig := range override.Matrix {
+		base.Matrix[key] = paramConfig
+	}
+	if len(override.Combinations) > 0 {
+		base.Combinations = override.Combinations
+	}
+	for metric, threshold := range override.Thresholds {
+		if base.Thresholds == nil {
+			base.Thresholds = make(map[string]types.Threshold)
+		}
+		base.Thresholds[metric] = threshold
+	}
+	if override.Warmup != nil {
+		base.Warmup = override.Warmup
+	}
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFile writes a file in dir and returns its path
+func writeFile(t *testing.T, dir string, name string, data string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValuesFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "configs/models.txt", "# models\nllama-3-8b\n\nqwen: 7b\n")
+	writeFile(t, dir, "configs/numbers.txt", "2048\n4096\ntrue\n")
+	writeFile(t, dir, "configs/empty.txt", "# nothing\n")
+	writeFile(t, dir, "shared.txt", "a\nb\n")
+
+	for _, tt := range []struct {
+		name   string
+		matrix string
+		want   []interface{}
+		err    string // part of the error, empty if the configuration loads
+	}{
+		{"relative to the config", "model: {values_file: models.txt}", []interface{}{"llama-3-8b", "qwen: 7b"}, ""},
+		{"relative parent", "model: {values_file: ../shared.txt}", []interface{}{"a", "b"}, ""},
+		{"absolute", fmt.Sprintf("model: {values_file: %s}", filepath.Join(dir, "shared.txt")), []interface{}{"a", "b"}, ""},
+		{"typed values", "model: {values_file: numbers.txt}", []interface{}{2048, 4096, true}, ""},
+		{"with values", "model: {values: [x], values_file: models.txt}", nil, "mutually exclusive"},
+		{"missing file", "model: {values_file: missing.txt}", nil, "error reading values file"},
+		{"no values", "model: {values_file: empty.txt}", nil, "has no values"},
+	} {
+		path := writeFile(t, dir, "configs/config.yaml", "driver: mock\nmatrix:\n  "+tt.matrix+"\n")
+		cfg, err := Load(path)
+		switch {
+		case tt.err != "":
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want one containing %q", tt.name, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case !reflect.DeepEqual(cfg.Matrix["model"].Values, tt.want):
+			t.Errorf("%s: values = %#v, want %#v", tt.name, cfg.Matrix["model"].Values, tt.want)
+		}
+	}
+}
+
+func TestValuesFileWithoutFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "models.txt", "a\nb\n")
+	data := "driver: mock\nmatrix:\n  model: {values_file: models.txt}\n"
+
+	// Configurations that don't come from a file, e.g. posted to the server, can't read files
+	if _, err := Parse([]byte(data)); err == nil || !strings.Contains(err.Error(), "only supported in configuration files") {
+		t.Errorf("Parse error = %v, want values_file to be rejected", err)
+	}
+	if errs := Validate([]byte(data)); len(errs) != 1 || !strings.Contains(errs[0].Message, "only supported in configuration files") {
+		t.Errorf("Validate = %v, want values_file to be rejected", errs)
+	}
+
+	// Configurations read from stdin resolve values files in the working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	defer func() { os.Stdin = stdin }()
+	os.Stdin, err = os.Open(writeFile(t, t.TempDir(), "stdin.yaml", data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Stdin.Close()
+
+	cfg, err := Load(StdinPath)
+	if err != nil {
+		t.Fatalf("Load(stdin): %v", err)
+	}
+	if got := cfg.Matrix["model"].Values; !reflect.DeepEqual(got, []interface{}{"a", "b"}) {
+		t.Errorf("values from stdin config = %v, want [a b]", got)
+	}
+}
+
+func TestLoadFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.yaml", `driver: llamacpp
+matrix:
+  model: [a, b]
+  ctx_size: [2048]
+thresholds:
+  localscore_estimate: {min: 10}
+`)
+	machine := writeFile(t, dir, "machine.yaml", `driver: mock
+matrix:
+  ctx_size: {values: [4096, 8192], output: false}
+thresholds:
+  short_context_r_squared: {min: 0.9}
+`)
+
+	cfg, err := LoadFiles([]string{base, machine})
+	if err != nil {
+		t.Fatalf("LoadFiles: %v", err)
+	}
+	if cfg.Driver != "mock" {
+		t.Errorf("driver = %q, want the later file's mock", cfg.Driver)
+	}
+	if got := cfg.Matrix["model"].Values; !reflect.DeepEqual(got, []interface{}{"a", "b"}) {
+		t.Errorf("model = %v, want it kept from the base file", got)
+	}
+	if ctxSize := cfg.Matrix["ctx_size"]; !reflect.DeepEqual(ctxSize.Values, []interface{}{4096, 8192}) || ctxSize.Output {
+		t.Errorf("ctx_size = %+v, want it replaced by the later file", ctxSize)
+	}
+	if len(cfg.Thresholds) != 2 {
+		t.Errorf("thresholds = %v, want both files' thresholds", cfg.Thresholds)
+	}
+
+	if _, err := LoadFiles([]string{StdinPath, StdinPath}); err == nil {
+		t.Error("LoadFiles read stdin twice")
+	}
+	if _, err := LoadFiles([]string{writeFile(t, dir, "nodriver.yaml", "matrix:\n  model: [a]\n")}); err == nil {
+		t.Error("LoadFiles accepted a configuration without a driver")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		config string
+		line   int
+		want   string // part of the only error, empty if the configuration is valid
+	}{
+		{"valid", "driver: mock\nmatrix:\n  model: [a, b]\n  ctx_size: {values: [2048], output: false}\n", 0, ""},
+		{"combinations only", "driver: mock\ncombinations:\n  - model: a\n", 0, ""},
+		{"unknown top-level key", "driver: mock\nmatrix:\n  model: [a]\nmatrx:\n  model: [b]\n", 4, `unknown top-level key "matrx"`},
+		{"invalid driver", "driver: llama\nmatrix:\n  model: [a]\n", 1, `invalid driver "llama"`},
+		{"scalar parameter", "driver: mock\nmatrix:\n  model: a\n", 3, `parameter "model": must be a list`},
+		{"unknown attribute", "driver: mock\nmatrix:\n  model: {values: [a], ouput: false}\n", 3, `unknown attribute "ouput"`},
+		{"missing values", "driver: mock\nmatrix:\n  model: {output: false}\n", 3, "missing values or values_file"},
+		{"missing matrix", "driver: mock\n", 0, `missing required key "matrix" or "combinations"`},
+		{"not a mapping", "- driver: mock\n", 1, "configuration must be a mapping"},
+	} {
+		errs := Validate([]byte(tt.config))
+		switch {
+		case tt.want == "" && len(errs) > 0:
+			t.Errorf("%s: unexpected errors %v", tt.name, errs)
+		case tt.want != "" && len(errs) != 1:
+			t.Errorf("%s: errors %v, want one containing %q", tt.name, errs, tt.want)
+		case tt.want != "" && (!strings.Contains(errs[0].Message, tt.want) || errs[0].Line != tt.line):
+			t.Errorf("%s: error %q on line %d, want %q on line %d", tt.name, errs[0].Message, errs[0].Line, tt.want, tt.line)
+		}
+	}
+}
+
+func TestValidateFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.yaml", "driver: mock\n")
+	override := writeFile(t, dir, "override.yaml", "matrix:\n  model: [a]\n  ctx_size: {values_file: missing.txt}\n")
+
+	// Required keys may be set in any of the files, problems are reported with their file
+	errs, err := ValidateFiles([]string{base, override})
+	if err != nil {
+		t.Fatalf("ValidateFiles: %v", err)
+	}
+	if len(errs) != 1 || errs[0].File != override || errs[0].Line != 3 {
+		t.Errorf("errors = %+v, want the missing values file on line 3 of %s", errs, override)
+	}
+
+	both := writeFile(t, dir, "both.yaml", "driver: mock\nmatrix:\n  model:\n    values: [a]\n    values_file: base.yaml\n")
+	errs, err = ValidateFiles([]string{both})
+	if err != nil {
+		t.Fatalf("ValidateFiles: %v", err)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "mutually exclusive") || errs[0].Line != 5 {
+		t.Errorf("errors = %+v, want values and values_file rejected on line 5", errs)
+	}
+
+	errs, err = ValidateFiles([]string{override})
+	if err != nil {
+		t.Fatalf("ValidateFiles: %v", err)
+	}
+	if len(errs) != 2 || !strings.Contains(errs[1].Message, `missing required key "driver"`) {
+		t.Errorf("errors = %+v, want the missing driver", errs)
+	}
+}
@@ -9,7 +9,7 @@ const defaultConfigTemplate = `# Turtlenekko Configuration File
 # This is an example configuration file with common settings
 
 # Driver configuration
-# Available drivers: "dummy", "local_cmd"
+# Available drivers: "dummy", "local_cmd", "ssh", "mock", "llamacpp", "vllm"
 driver: "dummy"
 
 # Matrix of parameters to test
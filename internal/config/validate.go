@@ -0,0 +1,347 @@
+package config
+
+import (
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aifoundry-org/turtlenekko/internal/driver"
+	"github.com/aifoundry-org/turtlenekko/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Schema is the JSON schema describing the configuration file
+//
+//go:embed schema.json
+var Schema string
+
+// ValidationError describes a problem found in a configuration file
+type ValidationError struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return e.Message
+}
+
+// knownTopLevelKeys lists the keys accepted at the top level of a configuration file
+var knownTopLevelKeys = map[string]bool{
+	"driver":       true,
+	"matrix":       true,
+	"combinations": true,
+	"thresholds":   true,
+	"warmup":       true,
+}
+
+// ValidateFile loads and validates a configuration file without running it
+func ValidateFile(path string) ([]ValidationError, error) {
+	return ValidateFiles([]string{path})
+}
+
+// ValidateFiles validates configuration files that are merged together. Each file is
+// checked on its own, but required keys only need to be set in one of them.
+func ValidateFiles(paths []string) ([]ValidationError, error) {
+	var errs []ValidationError
+	seen := make(map[string]bool)
+
+	if err := checkStdinOnce(paths); err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		data, dir, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		fileErrs, fileKeys := validate(data, dir)
+		for _, fileErr := range fileErrs {
+			fileErr.File = path
+			errs = append(errs, fileErr)
+		}
+		for key := range fileKeys {
+			seen[key] = true
+		}
+	}
+
+	for _, missing := range missingKeys(seen) {
+		missing.File = paths[len(paths)-1]
+		errs = append(errs, missing)
+	}
+
+	return errs, nil
+}
+
+// Validate checks configuration data against the schema and reports all problems found
+func Validate(data []byte) []ValidationError {
+	errs, seen := validate(data, "")
+	if seen == nil {
+		return errs
+	}
+	return append(errs, missingKeys(seen)...)
+}
+
+// missingKeys reports required top-level keys that are not set
+func missingKeys(seen map[string]bool) []ValidationError {
+	var errs []ValidationError
+	if !seen["driver"] {
+		errs = append(errs, ValidationError{Message: fmt.Sprintf("missing required key %q", "driver")})
+	}
+	if !seen["matrix"] && !seen["combinations"] {
+		errs = append(errs, ValidationError{Message: fmt.Sprintf("missing required key %q or %q", "matrix", "combinations")})
+	}
+	return errs
+}
+
+// validate checks configuration data without requiring any key, and returns the
+// problems found together with the top-level keys that are set (nil if unparseable).
+// Values files are read relative to dir, if it is set.
+func validate(data []byte, dir string) ([]ValidationError, map[string]bool) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []ValidationError{{Message: fmt.Sprintf("error parsing configuration file: %v", err)}}, nil
+	}
+
+	if len(doc.Content) == 0 {
+		return []ValidationError{{Message: "configuration file is empty"}}, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return []ValidationError{{Line: root.Line, Message: "configuration must be a mapping"}}, nil
+	}
+
+	var errs []ValidationError
+	seen := make(map[string]bool)
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		seen[key.Value] = true
+
+		switch key.Value {
+		case "driver":
+			errs = append(errs, validateDriver(value)...)
+		case "matrix":
+			errs = append(errs, validateMatrix(value, dir)...)
+		case "combinations":
+			errs = append(errs, validateCombinations(value)...)
+		case "thresholds":
+			errs = append(errs, validateThresholds(value)...)
+		case "warmup":
+			errs = append(errs, validateWarmup(value)...)
+		default:
+			if !knownTopLevelKeys[key.Value] {
+				errs = append(errs, ValidationError{Line: key.Line, Message: fmt.Sprintf("unknown top-level key %q", key.Value)})
+			}
+		}
+	}
+
+	return errs, seen
+}
+
+// validateDriver checks that the driver is a known driver name
+func validateDriver(node *yaml.Node) []ValidationError {
+	if node.Kind != yaml.ScalarNode || node.Tag != "!!str" {
+		return []ValidationError{{Line: node.Line, Message: "driver must be a string"}}
+	}
+	if _, err := driver.NewDriver(node.Value); err != nil {
+		return []ValidationError{{Line: node.Line, Message: fmt.Sprintf("invalid driver %q", node.Value)}}
+	}
+	return nil
+}
+
+// validateMatrix checks that every matrix parameter is either a list of values
+// or an object with values or a values file and optional output and driver flags
+func validateMatrix(node *yaml.Node, dir string) []ValidationError {
+	if node.Kind != yaml.MappingNode {
+		return []ValidationError{{Line: node.Line, Message: "matrix must be a mapping of parameter names to values"}}
+	}
+
+	var errs []ValidationError
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+
+		switch value.Kind {
+		case yaml.SequenceNode:
+			errs = append(errs, validateValues(key.Value, value)...)
+
+		case yaml.MappingNode:
+			hasValues := false
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				attrKey, attrValue := value.Content[j], value.Content[j+1]
+				switch attrKey.Value {
+				case "values":
+					if hasValues {
+						errs = append(errs, ValidationError{Line: attrKey.Line, Message: fmt.Sprintf("parameter %q: values and values_file are mutually exclusive", key.Value)})
+					}
+					hasValues = true
+					if attrValue.Kind != yaml.SequenceNode {
+						errs = append(errs, ValidationError{Line: attrValue.Line, Message: fmt.Sprintf("parameter %q: values must be a list", key.Value)})
+						continue
+					}
+					errs = append(errs, validateValues(key.Value, attrValue)...)
+				case "values_file":
+					if hasValues {
+						errs = append(errs, ValidationError{Line: attrKey.Line, Message: fmt.Sprintf("parameter %q: values and values_file are mutually exclusive", key.Value)})
+					}
+					hasValues = true
+					if attrValue.Kind != yaml.ScalarNode || attrValue.Tag != "!!str" {
+						errs = append(errs, ValidationError{Line: attrValue.Line, Message: fmt.Sprintf("parameter %q: values_file must be a path", key.Value)})
+						continue
+					}
+					if dir == "" {
+						errs = append(errs, ValidationError{Line: attrValue.Line, Message: fmt.Sprintf("parameter %q: values_file is only supported in configuration files", key.Value)})
+					} else if _, err := readValuesFile(dir, attrValue.Value); err != nil {
+						errs = append(errs, ValidationError{Line: attrValue.Line, Message: fmt.Sprintf("parameter %q: %v", key.Value, err)})
+					}
+				case "output":
+					if attrValue.Kind == yaml.MappingNode {
+						errs = append(errs, validateFormatOutput(key.Value, attrValue)...)
+						continue
+					}
+					if attrValue.Kind != yaml.ScalarNode || attrValue.Tag != "!!bool" {
+						errs = append(errs, ValidationError{Line: attrValue.Line, Message: fmt.Sprintf("parameter %q: output must be a boolean or a mapping of output formats to booleans", key.Value)})
+					}
+				case "driver":
+					if attrValue.Kind != yaml.ScalarNode || attrValue.Tag != "!!bool" {
+						errs = append(errs, ValidationError{Line: attrValue.Line, Message: fmt.Sprintf("parameter %q: %s must be a boolean", key.Value, attrKey.Value)})
+					}
+				default:
+					errs = append(errs, ValidationError{Line: attrKey.Line, Message: fmt.Sprintf("parameter %q: unknown attribute %q", key.Value, attrKey.Value)})
+				}
+			}
+			if !hasValues {
+				errs = append(errs, ValidationError{Line: value.Line, Message: fmt.Sprintf("parameter %q: missing values or values_file", key.Value)})
+			}
+
+		default:
+			errs = append(errs, ValidationError{Line: value.Line, Message: fmt.Sprintf("parameter %q: must be a list of values or an object with values", key.Value)})
+		}
+	}
+
+	return errs
+}
+
+// validateFormatOutput checks that the per-format output flags of a parameter map known
+// output formats to booleans
+func validateFormatOutput(param string, node *yaml.Node) []ValidationError {
+	var errs []ValidationError
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		format, flag := node.Content[i], node.Content[i+1]
+		if !isOutputFormat(format.Value) {
+			errs = append(errs, ValidationError{Line: format.Line, Message: fmt.Sprintf("parameter %q: unknown output format %q, expected one of %s", param, format.Value, strings.Join(types.OutputFormats, ", "))})
+		}
+		if flag.Kind != yaml.ScalarNode || flag.Tag != "!!bool" {
+			errs = append(errs, ValidationError{Line: flag.Line, Message: fmt.Sprintf("parameter %q: output for %s must be a boolean", param, format.Value)})
+		}
+	}
+	return errs
+}
+
+// validateCombinations checks that combinations is a non-empty list of mappings
+// from parameter names to scalar values
+func validateCombinations(node *yaml.Node) []ValidationError {
+	if node.Kind != yaml.SequenceNode {
+		return []ValidationError{{Line: node.Line, Message: "combinations must be a list of parameter sets"}}
+	}
+	if len(node.Content) == 0 {
+		return []ValidationError{{Line: node.Line, Message: "combinations must not be empty"}}
+	}
+
+	var errs []ValidationError
+	for i, combination := range node.Content {
+		if combination.Kind != yaml.MappingNode || len(combination.Content) == 0 {
+			errs = append(errs, ValidationError{Line: combination.Line, Message: fmt.Sprintf("combination %d: must be a non-empty mapping of parameter names to values", i+1)})
+			continue
+		}
+		for j := 0; j+1 < len(combination.Content); j += 2 {
+			key, value := combination.Content[j], combination.Content[j+1]
+			if value.Kind != yaml.ScalarNode {
+				errs = append(errs, ValidationError{Line: value.Line, Message: fmt.Sprintf("combination %d: parameter %q must be a string, number or boolean", i+1, key.Value)})
+			}
+		}
+	}
+	return errs
+}
+
+// validateThresholds checks that every threshold sets a numeric min and/or max
+func validateThresholds(node *yaml.Node) []ValidationError {
+	if node.Kind != yaml.MappingNode {
+		return []ValidationError{{Line: node.Line, Message: "thresholds must be a mapping of metric names to min/max bounds"}}
+	}
+
+	var errs []ValidationError
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if value.Kind != yaml.MappingNode || len(value.Content) == 0 {
+			errs = append(errs, ValidationError{Line: value.Line, Message: fmt.Sprintf("threshold %q: must set min and/or max", key.Value)})
+			continue
+		}
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			boundKey, boundValue := value.Content[j], value.Content[j+1]
+			switch boundKey.Value {
+			case "min", "max":
+				if boundValue.Kind != yaml.ScalarNode || (boundValue.Tag != "!!int" && boundValue.Tag != "!!float") {
+					errs = append(errs, ValidationError{Line: boundValue.Line, Message: fmt.Sprintf("threshold %q: %s must be a number", key.Value, boundKey.Value)})
+				}
+			default:
+				errs = append(errs, ValidationError{Line: boundKey.Line, Message: fmt.Sprintf("threshold %q: unknown attribute %q", key.Value, boundKey.Value)})
+			}
+		}
+	}
+	return errs
+}
+
+// validateWarmup checks the warmup mode and that its settings are positive numbers
+func validateWarmup(node *yaml.Node) []ValidationError {
+	if node.Kind != yaml.MappingNode {
+		return []ValidationError{{Line: node.Line, Message: "warmup must be a mapping with mode, count, tolerance and max"}}
+	}
+
+	var errs []ValidationError
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "mode":
+			if value.Kind != yaml.ScalarNode || (value.Value != "fixed" && value.Value != "stable") {
+				errs = append(errs, ValidationError{Line: value.Line, Message: "warmup: mode must be fixed or stable"})
+			}
+		case "count", "max":
+			minimum := 1
+			if key.Value == "max" {
+				minimum = 2 // stability needs two consecutive requests
+			}
+			if n, err := strconv.Atoi(value.Value); value.Kind != yaml.ScalarNode || value.Tag != "!!int" || err != nil || n < minimum {
+				errs = append(errs, ValidationError{Line: value.Line, Message: fmt.Sprintf("warmup: %s must be an integer of at least %d", key.Value, minimum)})
+			}
+		case "tolerance":
+			if value.Kind != yaml.ScalarNode || (value.Tag != "!!int" && value.Tag != "!!float") || strings.HasPrefix(value.Value, "-") {
+				errs = append(errs, ValidationError{Line: value.Line, Message: "warmup: tolerance must be a positive number"})
+			}
+		default:
+			errs = append(errs, ValidationError{Line: key.Line, Message: fmt.Sprintf("warmup: unknown attribute %q", key.Value)})
+		}
+	}
+	return errs
+}
+
+// validateValues checks that a list of parameter values is non-empty and contains only scalars
+func validateValues(name string, node *yaml.Node) []ValidationError {
+	if len(node.Content) == 0 {
+		return []ValidationError{{Line: node.Line, Message: fmt.Sprintf("parameter %q: values must not be empty", name)}}
+	}
+
+	var errs []ValidationError
+	for _, item := range node.Content {
+		if item.Kind != yaml.ScalarNode {
+			errs = append(errs, ValidationError{Line: item.Line, Message: fmt.Sprintf("parameter %q: values must be strings, numbers or booleans", name)})
+		}
+	}
+	return errs
+}
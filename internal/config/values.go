@@ -0,0 +1,57 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readValuesFile reads the values of a parameter from a file with one value per line,
+// relative to dir unless absolute. Blank lines and lines starting with # are skipped, and
+// each value is read as a YAML scalar, so numbers and booleans keep their types.
+func readValuesFile(dir string, path string) ([]interface{}, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading values file: %v", err)
+	}
+
+	var values []interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		values = append(values, scalarValue(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading values file %s: %v", path, err)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("values file %s has no values", path)
+	}
+	return values, nil
+}
+
+// scalarValue decodes a line as a YAML scalar, or returns it as a string if it isn't one,
+// e.g. a model name with ": " that YAML would read as a mapping
+func scalarValue(line string) interface{} {
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(line), &value); err != nil {
+		return line
+	}
+	switch value.(type) {
+	case string, int, float64, bool:
+		return value
+	default:
+		return line
+	}
+}
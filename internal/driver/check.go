@@ -0,0 +1,110 @@
+package driver
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// checkTimeout is the timeout of network checks
+const checkTimeout = 5 * time.Second
+
+// Check is the outcome of checking one prerequisite of a driver
+type Check struct {
+	Name   string // what was checked, e.g. "llama-server binary"
+	Detail string // what was found, e.g. the binary path
+	Err    error  // why the check failed, nil if it passed
+}
+
+// Checker is implemented by drivers that can check their prerequisites without setting up,
+// e.g. that their binaries are installed and their ports are free, so problems surface
+// before a long benchmark run
+type Checker interface {
+	// Check checks the prerequisites of Setup with the given parameters
+	Check(params map[string]interface{}) []Check
+}
+
+// checkBinary checks that a binary is installed, on the PATH or at the given path
+func checkBinary(name string, binary string) Check {
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return Check{Name: name, Err: err}
+	}
+	return Check{Name: name, Detail: path}
+}
+
+// shellBuiltins are words a command can start with that aren't binaries to look up
+var shellBuiltins = map[string]bool{
+	"cd": true, "export": true, "source": true, ".": true, "set": true, "eval": true,
+	"if": true, "for": true, "while": true, "(": true, "{": true,
+}
+
+// CheckCommand checks that a shell command template can be run: the shell is installed,
+// the template is valid and the program it starts is installed, e.g. docker for a
+// "docker run ..." command. Commands starting with a shell builtin only check the shell.
+func CheckCommand(name string, cmdTemplate string, params map[string]interface{}) []Check {
+	checks := []Check{checkBinary("shell (sh)", "sh")}
+
+	cmd, err := InterpolateCommand(cmdTemplate, params)
+	if err != nil {
+		return append(checks, Check{Name: name, Err: err})
+	}
+
+	fields := strings.Fields(cmd)
+	for len(fields) > 0 && (fields[0] == "exec" || fields[0] == "nohup" || strings.Contains(fields[0], "=")) {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 || shellBuiltins[fields[0]] {
+		return checks
+	}
+	return append(checks, checkBinary(fmt.Sprintf("%s program (%s)", name, fields[0]), fields[0]))
+}
+
+// checkFile checks that a file exists
+func checkFile(name string, path string) Check {
+	if _, err := os.Stat(path); err != nil {
+		return Check{Name: name, Err: err}
+	}
+	return Check{Name: name, Detail: path}
+}
+
+// checkPortFree checks that nothing listens on the port a driver will start a server on
+func checkPortFree(name string, host string, port int) Check {
+	address := net.JoinHostPort(host, fmt.Sprintf("%d", port))
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return Check{Name: name, Err: fmt.Errorf("%s is not available: %v", address, err)}
+	}
+	listener.Close()
+	return Check{Name: name, Detail: address + " is free"}
+}
+
+// checkReachable checks that a server answers HTTP requests to rawURL; any response
+// counts, as the benchmark reports errors of the endpoint itself
+func checkReachable(name string, rawURL string) Check {
+	if _, err := url.ParseRequestURI(rawURL); err != nil {
+		return Check{Name: name, Err: fmt.Errorf("invalid url: %v", err)}
+	}
+	client := &http.Client{Timeout: checkTimeout}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return Check{Name: name, Err: err}
+	}
+	resp.Body.Close()
+	return Check{Name: name, Detail: fmt.Sprintf("%s answered with status %d", rawURL, resp.StatusCode)}
+}
+
+// checkDial checks that a TCP connection to address can be opened
+func checkDial(name string, address string) Check {
+	conn, err := net.DialTimeout("tcp", address, checkTimeout)
+	if err != nil {
+		return Check{Name: name, Err: err}
+	}
+	conn.Close()
+	return Check{Name: name, Detail: address + " accepts connections"}
+}
@@ -0,0 +1,10 @@
+package driver
+
+// CommandRunner is implemented by drivers that run commands on the host serving the
+// model, so commands describing the server, e.g. info_cmd, run there rather than on
+// the machine running the benchmark
+type CommandRunner interface {
+	// RunCommand runs a shell command on the server host and returns its standard
+	// output. Call it after Setup.
+	RunCommand(cmd string) ([]byte, error)
+}
@@ -26,6 +26,39 @@ type Driver interface {
 	// GetModel returns the model information
 	GetModel() Model
 
+	// Describe returns the driver's description and the parameters it consumes
+	Describe() Info
+}
+
+// Info describes a driver and the parameters it consumes
+type Info struct {
+	Name        string
+	Description string
+	Params      []ParamInfo
+}
+
+// ParamInfo describes a driver parameter
+type ParamInfo struct {
+	Name        string
+	Description string
+	Required    bool
+	Default     string
+}
+
+// driverTypes lists the registered driver types in the order they are documented
+var driverTypes = []string{"dummy", "local_cmd", "ssh", "mock", "llamacpp", "vllm"}
+
+// Drivers describes all registered drivers
+func Drivers() []Info {
+	var infos []Info
+	for _, driverType := range driverTypes {
+		d, err := NewDriver(driverType)
+		if err != nil {
+			continue
+		}
+		infos = append(infos, d.Describe())
+	}
+	return infos
 }
 
 // NewDriver creates a new driver instance based on the driver type
@@ -35,6 +68,14 @@ func NewDriver(driverType string) (Driver, error) {
 		return NewDummyDriver(), nil
 	case "local_cmd":
 		return NewLocalCmdDriver(), nil
+	case "ssh":
+		return NewSSHDriver(), nil
+	case "mock":
+		return NewMockDriver(), nil
+	case "llamacpp":
+		return NewLlamaCppDriver(), nil
+	case "vllm":
+		return NewVLLMDriver(), nil
 	default:
 		return nil, fmt.Errorf("unsupported driver type: %s", driverType)
 	}
@@ -26,13 +26,13 @@ func (d *DummyDriver) Setup(params map[string]interface{}) error {
 		d.url = url
 		slog.Info("Setting URL", "component", "dummy", "url", d.url)
 	}
-	
+
 	// Extract model if provided
 	if modelName, ok := params["model"].(string); ok {
 		d.model.Name = modelName
 		slog.Info("Setting model", "component", "dummy", "model", d.model.Name)
 	}
-	
+
 	slog.Info("Dummy driver setup completed", "component", "dummy")
 	return nil
 }
@@ -52,3 +52,24 @@ func (d *DummyDriver) GetURL() string {
 func (d *DummyDriver) GetModel() Model {
 	return d.model
 }
+
+// Check checks that the server at the configured URL is reachable
+func (d *DummyDriver) Check(params map[string]interface{}) []Check {
+	url, _ := params["url"].(string)
+	if url == "" {
+		return nil
+	}
+	return []Check{checkReachable("server", url)}
+}
+
+// Describe returns the driver's description and parameters
+func (d *DummyDriver) Describe() Info {
+	return Info{
+		Name:        "dummy",
+		Description: "Benchmarks an already running server without managing it",
+		Params: []ParamInfo{
+			{Name: "url", Description: "URL of the chat completions endpoint", Required: true},
+			{Name: "model", Description: "Model name sent with requests"},
+		},
+	}
+}
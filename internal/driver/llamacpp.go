@@ -0,0 +1,248 @@
+package driver
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Defaults used by the llamacpp driver
+const (
+	DefaultLlamaCppBinary         = "llama-server"
+	DefaultLlamaCppHost           = "127.0.0.1"
+	DefaultLlamaCppPort           = 8080
+	DefaultLlamaCppStartupTimeout = 300 * time.Second
+	llamaCppStopTimeout           = 30 * time.Second
+)
+
+// LlamaCppDriver implements the Driver interface by launching llama.cpp's llama-server
+// directly and waiting for its /health endpoint to report readiness
+type LlamaCppDriver struct {
+	url     string
+	baseURL string
+	params  map[string]interface{}
+	model   Model
+	process *serverProcess
+}
+
+// llamaCppProps is the subset of the /props response describing the server configuration
+type llamaCppProps struct {
+	DefaultGenerationSettings struct {
+		NCtx int `json:"n_ctx"`
+	} `json:"default_generation_settings"`
+	TotalSlots int    `json:"total_slots"`
+	ModelPath  string `json:"model_path"`
+	BuildInfo  string `json:"build_info"`
+}
+
+// NewLlamaCppDriver creates a new LlamaCppDriver instance
+func NewLlamaCppDriver() *LlamaCppDriver {
+	return &LlamaCppDriver{
+		model: Model{Name: ""},
+		url:   "",
+	}
+}
+
+// buildArgs builds the llama-server command line from the parameters
+func (d *LlamaCppDriver) buildArgs(params map[string]interface{}, host string, port int) ([]string, error) {
+	modelPath, _ := params["model_path"].(string)
+	if modelPath == "" {
+		return nil, fmt.Errorf("model_path parameter is required")
+	}
+
+	args := []string{
+		"--model", modelPath,
+		"--host", host,
+		"--port", fmt.Sprintf("%d", port),
+	}
+	if ctxSize, ok := params["ctx_size"]; ok {
+		args = append(args, "--ctx-size", fmt.Sprintf("%v", ctxSize))
+	}
+	if ngl, ok := params["ngl"]; ok {
+		args = append(args, "--n-gpu-layers", fmt.Sprintf("%v", ngl))
+	}
+	if extraArgs, ok := params["extra_args"].(string); ok && extraArgs != "" {
+		args = append(args, strings.Fields(extraArgs)...)
+	}
+	return args, nil
+}
+
+// Setup starts llama-server and waits until it is ready to serve requests
+func (d *LlamaCppDriver) Setup(params map[string]interface{}) error {
+	binaryPath := DefaultLlamaCppBinary
+	if path, ok := params["binary_path"].(string); ok && path != "" {
+		binaryPath = path
+	}
+
+	host := DefaultLlamaCppHost
+	if h, ok := params["host"].(string); ok && h != "" {
+		host = h
+	}
+	port := int(floatParam(params, "port", DefaultLlamaCppPort))
+
+	args, err := d.buildArgs(params, host, port)
+	if err != nil {
+		return err
+	}
+
+	// Default the model name to the model file name
+	if modelName, ok := params["model"].(string); ok && modelName != "" {
+		d.model.Name = modelName
+	} else {
+		d.model.Name = strings.TrimSuffix(filepath.Base(params["model_path"].(string)), ".gguf")
+	}
+
+	startupTimeout := time.Duration(floatParam(params, "startup_timeout_s", DefaultLlamaCppStartupTimeout.Seconds()) * float64(time.Second))
+
+	slog.Info("Starting llama-server", "component", "llamacpp", "binary", binaryPath, "args", strings.Join(args, " "))
+
+	process, err := startServerProcess(exec.Command(binaryPath, args...))
+	if err != nil {
+		return fmt.Errorf("error starting llama-server: %v", err)
+	}
+	d.process = process
+
+	baseURL := fmt.Sprintf("http://%s:%d", host, port)
+	if err := d.waitForHealth(baseURL+"/health", startupTimeout); err != nil {
+		d.process.stop("llamacpp", llamaCppStopTimeout)
+		output := d.process.output.String()
+		d.process = nil
+		return fmt.Errorf("%v, output: %s", err, output)
+	}
+
+	d.url = baseURL + "/v1/chat/completions"
+	d.baseURL = baseURL
+	d.params = params
+
+	slog.Info("llama-server is ready", "component", "llamacpp", "url", d.url, "pid", d.process.cmd.Process.Pid)
+
+	return nil
+}
+
+// waitForHealth polls the health endpoint until it returns 200, the server exits or the timeout expires
+func (d *LlamaCppDriver) waitForHealth(healthURL string, timeout time.Duration) error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		if exited, status := d.process.exited(); exited {
+			return fmt.Errorf("llama-server exited during startup: %v", status)
+		}
+
+		resp, err := client.Get(healthURL)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+			slog.Debug("llama-server not ready yet", "component", "llamacpp", "status_code", resp.StatusCode)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("llama-server did not become ready within %v", timeout)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// Teardown stops llama-server
+func (d *LlamaCppDriver) Teardown() error {
+	if d.process == nil {
+		return nil
+	}
+
+	slog.Info("Stopping llama-server", "component", "llamacpp")
+
+	err := d.process.stop("llamacpp", llamaCppStopTimeout)
+	d.process = nil
+	if err != nil {
+		return err
+	}
+
+	slog.Info("llama-server stopped", "component", "llamacpp")
+
+	return nil
+}
+
+// ServerConfig returns the context size per slot, number of slots, model path and build
+// reported by the /props endpoint, warning if the context size differs from ctx_size
+func (d *LlamaCppDriver) ServerConfig() (map[string]interface{}, error) {
+	var props llamaCppProps
+	if err := getJSON(d.baseURL+"/props", &props); err != nil {
+		return nil, fmt.Errorf("error getting llama-server props: %v", err)
+	}
+
+	config := make(map[string]interface{})
+	nCtx := props.DefaultGenerationSettings.NCtx
+	if nCtx > 0 {
+		config["n_ctx"] = nCtx
+	}
+	if props.TotalSlots > 0 {
+		config["total_slots"] = props.TotalSlots
+	}
+	if props.ModelPath != "" {
+		config["model_path"] = props.ModelPath
+	}
+	if props.BuildInfo != "" {
+		config["build_info"] = props.BuildInfo
+	}
+
+	// n_ctx is per slot; older servers split ctx_size between the parallel slots
+	warnConfigMismatch("llamacpp", d.params, "ctx_size", "n_ctx", nCtx, nCtx*max(props.TotalSlots, 1))
+	return config, nil
+}
+
+// GetURL returns the URL of the llama-server chat completion endpoint
+func (d *LlamaCppDriver) GetURL() string {
+	return d.url
+}
+
+// GetModel returns the model information
+func (d *LlamaCppDriver) GetModel() Model {
+	return d.model
+}
+
+// Check checks that llama-server and the model file exist and that the port the server
+// will listen on is free
+func (d *LlamaCppDriver) Check(params map[string]interface{}) []Check {
+	binaryPath := DefaultLlamaCppBinary
+	if path, ok := params["binary_path"].(string); ok && path != "" {
+		binaryPath = path
+	}
+	checks := []Check{checkBinary("llama-server binary", binaryPath)}
+
+	if modelPath, _ := params["model_path"].(string); modelPath != "" {
+		checks = append(checks, checkFile("model file", modelPath))
+	} else {
+		checks = append(checks, Check{Name: "model file", Err: fmt.Errorf("model_path parameter is required")})
+	}
+
+	host := DefaultLlamaCppHost
+	if h, ok := params["host"].(string); ok && h != "" {
+		host = h
+	}
+	return append(checks, checkPortFree("server port", host, int(floatParam(params, "port", DefaultLlamaCppPort))))
+}
+
+// Describe returns the driver's description and parameters
+func (d *LlamaCppDriver) Describe() Info {
+	return Info{
+		Name:        "llamacpp",
+		Description: "Starts llama-server with the given model and stops it afterwards",
+		Params: []ParamInfo{
+			{Name: "model_path", Description: "GGUF model file", Required: true},
+			{Name: "binary_path", Description: "llama-server binary", Default: DefaultLlamaCppBinary},
+			{Name: "ctx_size", Description: "Context size (--ctx-size)"},
+			{Name: "ngl", Description: "Layers offloaded to the GPU (--n-gpu-layers)"},
+			{Name: "extra_args", Description: "Additional llama-server arguments"},
+			{Name: "host", Description: "Address the server listens on", Default: DefaultLlamaCppHost},
+			{Name: "port", Description: "Port the server listens on", Default: fmt.Sprint(DefaultLlamaCppPort)},
+			{Name: "startup_timeout_s", Description: "How long to wait for the model to load", Default: fmt.Sprint(DefaultLlamaCppStartupTimeout.Seconds())},
+			{Name: "model", Description: "Model name reported in the results", Default: "model file name"},
+		},
+	}
+}
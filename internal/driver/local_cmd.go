@@ -27,15 +27,15 @@ func NewLocalCmdDriver() *LocalCmdDriver {
 	}
 }
 
-// interpolateCommand replaces template variables in the command string with parameter values
-func (d *LocalCmdDriver) interpolateCommand(cmdTemplate string) (string, error) {
+// InterpolateCommand replaces template variables in the command string with parameter values
+func InterpolateCommand(cmdTemplate string, params map[string]interface{}) (string, error) {
 	tmpl, err := template.New("command").Parse(cmdTemplate)
 	if err != nil {
 		return "", fmt.Errorf("invalid command template: %v", err)
 	}
 
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, d.params); err != nil {
+	if err := tmpl.Execute(&buf, params); err != nil {
 		return "", fmt.Errorf("error interpolating command: %v", err)
 	}
 
@@ -70,7 +70,7 @@ func (d *LocalCmdDriver) Setup(params map[string]interface{}) error {
 	}
 
 	// Interpolate and run the setup command
-	cmd, err := d.interpolateCommand(setupCmd)
+	cmd, err := InterpolateCommand(setupCmd, d.params)
 	if err != nil {
 		return fmt.Errorf("failed to prepare setup command: %v", err)
 	}
@@ -115,7 +115,7 @@ func (d *LocalCmdDriver) Teardown() error {
 	}
 
 	// Interpolate and run the teardown command
-	cmd, err := d.interpolateCommand(d.teardownCmd)
+	cmd, err := InterpolateCommand(d.teardownCmd, d.params)
 	if err != nil {
 		return fmt.Errorf("failed to prepare teardown command: %v", err)
 	}
@@ -135,3 +135,36 @@ func (d *LocalCmdDriver) Teardown() error {
 
 	return nil
 }
+
+// Check checks that the setup and teardown commands can be run, or without a setup
+// command, that the server at the configured URL is reachable
+func (d *LocalCmdDriver) Check(params map[string]interface{}) []Check {
+	var checks []Check
+	for _, key := range []string{"setup_cmd", "teardown_cmd"} {
+		if cmd, ok := params[key].(string); ok && cmd != "" {
+			checks = append(checks, CheckCommand(key, cmd, params)...)
+		}
+	}
+
+	// A server started by the setup command isn't running yet
+	if url, ok := params["url"].(string); ok && url != "" {
+		if setupCmd, _ := params["setup_cmd"].(string); setupCmd == "" {
+			checks = append(checks, checkReachable("server", url))
+		}
+	}
+	return checks
+}
+
+// Describe returns the driver's description and parameters
+func (d *LocalCmdDriver) Describe() Info {
+	return Info{
+		Name:        "local_cmd",
+		Description: "Runs local shell commands to start and stop the server",
+		Params: []ParamInfo{
+			{Name: "url", Description: "URL of the chat completions endpoint (or printed by setup_cmd)"},
+			{Name: "model", Description: "Model name sent with requests"},
+			{Name: "setup_cmd", Description: "Command run before the benchmark, with {{.param}} templates"},
+			{Name: "teardown_cmd", Description: "Command run after the benchmark, with {{.param}} templates"},
+		},
+	}
+}
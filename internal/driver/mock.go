@@ -0,0 +1,325 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Default synthetic rates used by the mock driver (ms per token)
+const (
+	DefaultMockPromptRate       = 0.5
+	DefaultMockCachedPromptRate = 0.01
+	DefaultMockCompletionRate   = 5.0
+	DefaultMockBatchPromptRate  = 0.25
+)
+
+// mockRequestTimeout is the default request timeout with the mock driver, whose server
+// answers within seconds unless it stops responding
+const mockRequestTimeout = 30 * time.Second
+
+// MockDriver implements the Driver interface by serving an in-process OpenAI compatible
+// chat completion endpoint that sleeps proportionally to configured per-token rates.
+// It makes the whole pipeline runnable without a real LLM server.
+type MockDriver struct {
+	url    string
+	model  Model
+	server *http.Server
+
+	promptRate       float64 // ms per prompt token
+	cachedPromptRate float64 // ms per cached prompt token
+	completionRate   float64 // ms per completion token
+	batchPromptRate  float64 // ms per prompt token of a request with several prompts
+	contextSize      int     // context window in tokens, 0 for unlimited
+
+	mu          sync.Mutex
+	seenPrompts map[string]bool
+}
+
+// NewMockDriver creates a new MockDriver instance
+func NewMockDriver() *MockDriver {
+	return &MockDriver{
+		model:            Model{Name: ""},
+		url:              "",
+		promptRate:       DefaultMockPromptRate,
+		cachedPromptRate: DefaultMockCachedPromptRate,
+		completionRate:   DefaultMockCompletionRate,
+		batchPromptRate:  DefaultMockBatchPromptRate,
+	}
+}
+
+// mockRequest is the subset of the chat completion request used by the mock server
+type mockRequest struct {
+	Model    string `json:"model"`
+	Messages []struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"messages"`
+	MaxTokens int  `json:"max_tokens"`
+	Stream    bool `json:"stream"`
+}
+
+// handleChatCompletion responds with plausible token counts after sleeping for the synthetic duration
+func (d *MockDriver) handleChatCompletion(w http.ResponseWriter, r *http.Request) {
+	var req mockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// Approximate tokenization: about 4 bytes per token
+	prompt := ""
+	for _, message := range req.Messages {
+		prompt += message.Content
+	}
+	promptTokens := len(prompt)/4 + 1
+
+	completionTokens := req.MaxTokens
+	if completionTokens <= 0 {
+		completionTokens = 100
+	}
+
+	// Reject requests that don't fit the context window like OpenAI does
+	if d.contextSize > 0 && promptTokens+completionTokens > d.contextSize {
+		message := fmt.Sprintf("This model's maximum context length is %d tokens. However, you requested %d tokens (%d in the messages, %d in the completion).",
+			d.contextSize, promptTokens+completionTokens, promptTokens, completionTokens)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]string{"message": message, "type": "invalid_request_error", "code": "context_length_exceeded"},
+		})
+		return
+	}
+
+	// Identical prompts are served from the "cache"
+	d.mu.Lock()
+	cached := d.seenPrompts[prompt]
+	d.seenPrompts[prompt] = true
+	d.mu.Unlock()
+
+	promptRate := d.promptRate
+	cachedTokens := 0
+	if cached {
+		promptRate = d.cachedPromptRate
+		cachedTokens = promptTokens
+	}
+	usage := map[string]interface{}{
+		"prompt_tokens":         promptTokens,
+		"completion_tokens":     completionTokens,
+		"total_tokens":          promptTokens + completionTokens,
+		"prompt_tokens_details": map[string]int{"cached_tokens": cachedTokens},
+	}
+	if req.Stream {
+		time.Sleep(time.Duration(promptRate * float64(promptTokens) * float64(time.Millisecond)))
+		d.streamChatCompletion(w, req.Model, completionTokens, usage)
+		return
+	}
+
+	delayMs := promptRate*float64(promptTokens) + d.completionRate*float64(completionTokens)
+	time.Sleep(time.Duration(delayMs * float64(time.Millisecond)))
+
+	response := map[string]interface{}{
+		"id":      "mock",
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   req.Model,
+		"choices": []map[string]interface{}{
+			{
+				"index":         0,
+				"message":       map[string]string{"role": "assistant", "content": "Lorem ipsum"},
+				"finish_reason": "length",
+			},
+		},
+		"usage": usage,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// streamChatCompletion sends a completion as server-sent events, a token per chunk
+// generated at the completion rate, followed by a chunk with the usage
+func (d *MockDriver) streamChatCompletion(w http.ResponseWriter, model string, completionTokens int, usage map[string]interface{}) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher, _ := w.(http.Flusher)
+	send := func(chunk map[string]interface{}) {
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	chunk := func(choices []map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"id":      "mock",
+			"object":  "chat.completion.chunk",
+			"created": time.Now().Unix(),
+			"model":   model,
+			"choices": choices,
+		}
+	}
+
+	for i := 0; i < completionTokens; i++ {
+		time.Sleep(time.Duration(d.completionRate * float64(time.Millisecond)))
+		choice := map[string]interface{}{"index": 0, "delta": map[string]string{"content": " lorem"}}
+		if i == completionTokens-1 {
+			choice["finish_reason"] = "length"
+		}
+		send(chunk([]map[string]interface{}{choice}))
+	}
+
+	final := chunk([]map[string]interface{}{})
+	final["usage"] = usage
+	send(final)
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// mockCompletionRequest is the subset of the completions request used by the mock server;
+// the prompt is a string or an array of strings
+type mockCompletionRequest struct {
+	Model     string          `json:"model"`
+	Prompt    json.RawMessage `json:"prompt"`
+	MaxTokens int             `json:"max_tokens"`
+}
+
+// handleCompletion responds to a completions request with a choice per prompt after
+// sleeping for the synthetic duration; prompts of a batch are processed at the batch rate
+func (d *MockDriver) handleCompletion(w http.ResponseWriter, r *http.Request) {
+	var req mockCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	var prompts []string
+	if err := json.Unmarshal(req.Prompt, &prompts); err != nil {
+		var prompt string
+		if err := json.Unmarshal(req.Prompt, &prompt); err != nil {
+			http.Error(w, "invalid request: prompt must be a string or an array of strings", http.StatusBadRequest)
+			return
+		}
+		prompts = []string{prompt}
+	}
+
+	completionTokens := req.MaxTokens
+	if completionTokens <= 0 {
+		completionTokens = 16
+	}
+
+	promptRate := d.promptRate
+	if len(prompts) > 1 {
+		promptRate = d.batchPromptRate
+	}
+	promptTokens := 0
+	choices := make([]map[string]interface{}, len(prompts))
+	for i, prompt := range prompts {
+		promptTokens += len(prompt)/4 + 1
+		choices[i] = map[string]interface{}{"index": i, "text": " Lorem", "finish_reason": "length"}
+	}
+	delayMs := promptRate*float64(promptTokens) + d.completionRate*float64(completionTokens)
+	time.Sleep(time.Duration(delayMs * float64(time.Millisecond)))
+
+	response := map[string]interface{}{
+		"id":      "mock",
+		"object":  "text_completion",
+		"created": time.Now().Unix(),
+		"model":   req.Model,
+		"choices": choices,
+		"usage": map[string]interface{}{
+			"prompt_tokens":     promptTokens,
+			"completion_tokens": completionTokens * len(prompts),
+			"total_tokens":      promptTokens + completionTokens*len(prompts),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Setup starts the in-process mock server with the configured rates
+func (d *MockDriver) Setup(params map[string]interface{}) error {
+	// Extract model if provided
+	if modelName, ok := params["model"].(string); ok && modelName != "" {
+		d.model.Name = modelName
+	}
+
+	d.promptRate = floatParam(params, "prompt_rate_ms", DefaultMockPromptRate)
+	d.cachedPromptRate = floatParam(params, "cached_prompt_rate_ms", DefaultMockCachedPromptRate)
+	d.completionRate = floatParam(params, "completion_rate_ms", DefaultMockCompletionRate)
+	d.batchPromptRate = floatParam(params, "batch_prompt_rate_ms", DefaultMockBatchPromptRate)
+	d.contextSize = int(floatParam(params, "context_size", 0))
+	d.seenPrompts = make(map[string]bool)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("error starting mock server: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat/completions", d.handleChatCompletion)
+	mux.HandleFunc("/v1/completions", d.handleCompletion)
+	d.server = &http.Server{Handler: mux}
+	go d.server.Serve(listener)
+
+	d.url = fmt.Sprintf("http://%s/v1/chat/completions", listener.Addr().String())
+
+	slog.Info("Mock server started",
+		"component", "mock",
+		"url", d.url,
+		"prompt_rate_ms", d.promptRate,
+		"cached_prompt_rate_ms", d.cachedPromptRate,
+		"completion_rate_ms", d.completionRate,
+		"batch_prompt_rate_ms", d.batchPromptRate,
+		"context_size", d.contextSize)
+
+	return nil
+}
+
+// Teardown stops the mock server
+func (d *MockDriver) Teardown() error {
+	if d.server == nil {
+		return nil
+	}
+
+	err := d.server.Close()
+	d.server = nil
+
+	slog.Info("Mock server stopped", "component", "mock")
+
+	return err
+}
+
+// GetURL returns the URL of the mock server
+func (d *MockDriver) GetURL() string {
+	return d.url
+}
+
+// GetModel returns the configured model
+func (d *MockDriver) GetModel() Model {
+	return d.model
+}
+
+// RequestTimeout returns the default request timeout with the mock server
+func (d *MockDriver) RequestTimeout() time.Duration {
+	return mockRequestTimeout
+}
+
+// Describe returns the driver's description and parameters
+func (d *MockDriver) Describe() Info {
+	return Info{
+		Name:        "mock",
+		Description: "Serves a simulated endpoint in-process, for trying out the tool without an LLM",
+		Params: []ParamInfo{
+			{Name: "model", Description: "Model name reported in the results"},
+			{Name: "prompt_rate_ms", Description: "Simulated ms per prompt token", Default: fmt.Sprint(DefaultMockPromptRate)},
+			{Name: "cached_prompt_rate_ms", Description: "Simulated ms per cached prompt token", Default: fmt.Sprint(DefaultMockCachedPromptRate)},
+			{Name: "completion_rate_ms", Description: "Simulated ms per completion token", Default: fmt.Sprint(DefaultMockCompletionRate)},
+			{Name: "batch_prompt_rate_ms", Description: "Simulated ms per prompt token of a request with several prompts", Default: fmt.Sprint(DefaultMockBatchPromptRate)},
+			{Name: "context_size", Description: "Simulated context window in tokens, requests exceeding it fail (0 for unlimited)", Default: "0"},
+			{Name: "request_timeout_s", Description: "Timeout of each request in seconds", Default: fmt.Sprint(mockRequestTimeout.Seconds())},
+		},
+	}
+}
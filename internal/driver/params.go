@@ -0,0 +1,34 @@
+package driver
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+)
+
+// floatParam returns a parameter as a float, or the default if it is not set or invalid
+func floatParam(params map[string]interface{}, key string, def float64) float64 {
+	value, ok := params[key]
+	if !ok {
+		return def
+	}
+	f, err := strconv.ParseFloat(fmt.Sprintf("%v", value), 64)
+	if err != nil {
+		slog.Warn("Invalid numeric parameter, using default", "key", key, "value", value, "default", def)
+		return def
+	}
+	return f
+}
+
+// boolParam returns a parameter as a boolean, or the default if it is not set or invalid
+func boolParam(params map[string]interface{}, key string, def bool) bool {
+	switch v := params[key].(type) {
+	case bool:
+		return v
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
@@ -0,0 +1,99 @@
+package driver
+
+import (
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// serverOutputLimit is how much of a server's output is kept to show on failure
+const serverOutputLimit = 64 * 1024
+
+// tailBuffer keeps the last bytes written to it, to show server output on failure
+type tailBuffer struct {
+	mu    sync.Mutex
+	data  []byte
+	limit int
+}
+
+// Write appends to the buffer, discarding the oldest bytes beyond the limit
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = append(b.data, p...)
+	if len(b.data) > b.limit {
+		b.data = b.data[len(b.data)-b.limit:]
+	}
+	return len(p), nil
+}
+
+// String returns the buffered output
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.data)
+}
+
+// serverProcess is a server launched by a driver in its own process group
+type serverProcess struct {
+	cmd    *exec.Cmd
+	done   chan struct{}
+	output *tailBuffer
+}
+
+// startServerProcess starts the command in its own process group, capturing its output
+func startServerProcess(cmd *exec.Cmd) (*serverProcess, error) {
+	p := &serverProcess{
+		cmd:    cmd,
+		done:   make(chan struct{}),
+		output: &tailBuffer{limit: serverOutputLimit},
+	}
+	cmd.Stdout = p.output
+	cmd.Stderr = p.output
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		cmd.Wait()
+		close(p.done)
+	}()
+
+	return p, nil
+}
+
+// exited reports whether the process has exited, and if so returns its exit status
+func (p *serverProcess) exited() (bool, error) {
+	select {
+	case <-p.done:
+		return true, fmt.Errorf("%v", p.cmd.ProcessState)
+	default:
+		return false, nil
+	}
+}
+
+// stop sends SIGTERM to the process group and waits for the process to exit,
+// killing it if it does not exit within the timeout
+func (p *serverProcess) stop(component string, timeout time.Duration) error {
+	if exited, _ := p.exited(); exited {
+		return nil
+	}
+
+	if err := terminateProcessGroup(p.cmd); err != nil {
+		slog.Warn("Failed to terminate server process", "component", component, "error", err)
+	}
+
+	select {
+	case <-p.done:
+		return nil
+	case <-time.After(timeout):
+		slog.Warn("Server process did not exit, killing it", "component", component)
+		killProcessGroup(p.cmd)
+		<-p.done
+		return fmt.Errorf("server process did not exit within %v and was killed", timeout)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !unix
+
+package driver
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on platforms without process groups
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminateProcessGroup kills the process, as graceful termination is not available
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+// killProcessGroup kills the process
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,56 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// serverConfigTimeout is the timeout of server config requests
+const serverConfigTimeout = 5 * time.Second
+
+// ServerConfigReporter is implemented by drivers whose server reports the runtime
+// configuration in effect, so the results record the actual settings rather than the
+// requested ones
+type ServerConfigReporter interface {
+	// ServerConfig returns the settings reported by the running server, keyed by the
+	// server's setting names. Call it after Setup.
+	ServerConfig() (map[string]interface{}, error)
+}
+
+// getJSON fetches url and decodes the JSON response into v
+func getJSON(url string, v interface{}) error {
+	client := &http.Client{Timeout: serverConfigTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("error decoding response: %v", err)
+	}
+	return nil
+}
+
+// warnConfigMismatch warns if a requested parameter differs from the setting reported
+// by the server, e.g. because of a typo in extra_args or a server default override.
+// Any of the accepted values counts as a match.
+func warnConfigMismatch(component string, params map[string]interface{}, param string, setting string, reported int, accepted ...int) {
+	requested := int(floatParam(params, param, 0))
+	if requested <= 0 || reported <= 0 || requested == reported {
+		return
+	}
+	for _, value := range accepted {
+		if requested == value {
+			return
+		}
+	}
+	slog.Warn("Server reports a different setting than requested", "component", component,
+		"param", param, "requested", requested, "setting", setting, "reported", reported)
+}
@@ -0,0 +1,353 @@
+package driver
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHDriver implements the Driver interface for running commands on a remote machine over SSH.
+// The remote service is made reachable through a local port forward.
+type SSHDriver struct {
+	url         string
+	model       Model
+	setupCmd    string
+	teardownCmd string
+	params      map[string]interface{}
+	client      *ssh.Client
+	listener    net.Listener
+}
+
+// NewSSHDriver creates a new SSHDriver instance
+func NewSSHDriver() *SSHDriver {
+	return &SSHDriver{
+		model:  Model{Name: ""},
+		url:    "",
+		params: make(map[string]interface{}),
+	}
+}
+
+// expandHome replaces a leading "~" in a path with the user's home directory
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// clientConfig builds the SSH client configuration from driver parameters
+func (d *SSHDriver) clientConfig() (*ssh.ClientConfig, error) {
+	user, _ := d.params["user"].(string)
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+
+	keyPath, _ := d.params["key_path"].(string)
+	if keyPath == "" {
+		keyPath = "~/.ssh/id_rsa"
+	}
+
+	key, err := os.ReadFile(expandHome(keyPath))
+	if err != nil {
+		return nil, fmt.Errorf("error reading private key: %v", err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing private key: %v", err)
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	if boolParam(d.params, "insecure_host_key", false) {
+		slog.Warn("Host key verification disabled", "component", "ssh")
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	} else {
+		knownHostsPath, _ := d.params["known_hosts"].(string)
+		if knownHostsPath == "" {
+			knownHostsPath = "~/.ssh/known_hosts"
+		}
+		hostKeyCallback, err = knownhosts.New(expandHome(knownHostsPath))
+		if err != nil {
+			return nil, fmt.Errorf("error loading known hosts: %v", err)
+		}
+	}
+
+	return &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+	}, nil
+}
+
+// runRemote runs a command on the remote host and returns its combined output
+func (d *SSHDriver) runRemote(cmd string) ([]byte, error) {
+	session, err := d.client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("error creating SSH session: %v", err)
+	}
+	defer session.Close()
+
+	return session.CombinedOutput(cmd)
+}
+
+// RunCommand runs a command on the remote host and returns its standard output
+func (d *SSHDriver) RunCommand(cmd string) ([]byte, error) {
+	if d.client == nil {
+		return nil, fmt.Errorf("not connected to the remote host")
+	}
+	session, err := d.client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("error creating SSH session: %v", err)
+	}
+	defer session.Close()
+
+	return session.Output(cmd)
+}
+
+// forward accepts local connections and proxies them to the remote address through the SSH connection
+func forward(listener net.Listener, client *ssh.Client, remoteAddr string) {
+	for {
+		local, err := listener.Accept()
+		if err != nil {
+			return // Listener closed
+		}
+
+		go func(local net.Conn) {
+			defer local.Close()
+
+			remote, err := client.Dial("tcp", remoteAddr)
+			if err != nil {
+				slog.Error("Failed to dial remote service", "component", "ssh", "address", remoteAddr, "error", err)
+				return
+			}
+			defer remote.Close()
+
+			done := make(chan struct{}, 2)
+			go func() {
+				io.Copy(remote, local)
+				done <- struct{}{}
+			}()
+			go func() {
+				io.Copy(local, remote)
+				done <- struct{}{}
+			}()
+			<-done
+		}(local)
+	}
+}
+
+// Setup connects to the remote host, runs the setup command and forwards a local port to the remote service
+func (d *SSHDriver) Setup(params map[string]interface{}) error {
+	if err := d.setup(params); err != nil {
+		d.close()
+		return err
+	}
+	return nil
+}
+
+// setup performs the actual setup; on failure the caller closes any partially opened connections
+func (d *SSHDriver) setup(params map[string]interface{}) error {
+	// Store all parameters for interpolation
+	d.params = params
+
+	host, ok := params["host"].(string)
+	if !ok || host == "" {
+		return fmt.Errorf("ssh driver requires a host parameter")
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "22")
+	}
+
+	// Extract URL if provided (as seen from the remote host)
+	remoteURL, _ := params["url"].(string)
+
+	// Extract model if provided
+	if modelName, ok := params["model"].(string); ok && modelName != "" {
+		d.model.Name = modelName
+	}
+
+	config, err := d.clientConfig()
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Connecting to remote host", "component", "ssh", "host", host, "user", config.User)
+	d.client, err = ssh.Dial("tcp", host, config)
+	if err != nil {
+		return fmt.Errorf("error connecting to %s: %v", host, err)
+	}
+
+	// Extract teardown command
+	if teardownCmd, ok := params["teardown_cmd"].(string); ok {
+		d.teardownCmd = teardownCmd
+	}
+
+	// Extract and run setup command
+	if setupCmd, ok := params["setup_cmd"].(string); ok && setupCmd != "" {
+		d.setupCmd = setupCmd
+
+		cmd, err := InterpolateCommand(setupCmd, d.params)
+		if err != nil {
+			return fmt.Errorf("failed to prepare setup command: %v", err)
+		}
+
+		slog.Info("Running remote setup command", "component", "ssh", "command", cmd)
+
+		output, err := d.runRemote(cmd)
+		if err != nil {
+			slog.Error("Remote setup command failed", "component", "ssh", "error", err, "output", string(output))
+			return fmt.Errorf("remote setup command failed: %v, output: %s", err, output)
+		}
+
+		slog.Info("Remote setup command completed successfully", "component", "ssh")
+
+		// If the command output contains a URL, use it
+		outputStr := strings.TrimSpace(string(output))
+		if strings.HasPrefix(outputStr, "http://") || strings.HasPrefix(outputStr, "https://") {
+			remoteURL = outputStr
+		}
+	}
+
+	if remoteURL == "" {
+		return fmt.Errorf("ssh driver requires a url parameter")
+	}
+
+	// Forward a local port to the remote service
+	u, err := url.Parse(remoteURL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %v", err)
+	}
+	remoteAddr := u.Host
+	if u.Port() == "" {
+		if u.Scheme == "https" {
+			remoteAddr = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			remoteAddr = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+
+	d.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("error opening local port forward: %v", err)
+	}
+	go forward(d.listener, d.client, remoteAddr)
+
+	u.Host = d.listener.Addr().String()
+	d.url = u.String()
+
+	slog.Info("Port forward established", "component", "ssh", "local", d.listener.Addr().String(), "remote", remoteAddr)
+
+	return nil
+}
+
+// GetURL returns the local URL forwarded to the remote service
+func (d *SSHDriver) GetURL() string {
+	return d.url
+}
+
+// GetModel returns the model information
+func (d *SSHDriver) GetModel() Model {
+	return d.model
+}
+
+// close shuts down the local port forward and the SSH connection
+func (d *SSHDriver) close() {
+	if d.listener != nil {
+		d.listener.Close()
+		d.listener = nil
+	}
+	if d.client != nil {
+		d.client.Close()
+		d.client = nil
+	}
+}
+
+// Teardown runs the remote teardown command and closes the tunnel
+func (d *SSHDriver) Teardown() error {
+	if d.client == nil {
+		return nil // Not connected, nothing to do
+	}
+
+	defer d.close()
+
+	if d.teardownCmd == "" {
+		return nil // No teardown command, nothing to do
+	}
+
+	// Interpolate and run the teardown command
+	cmd, err := InterpolateCommand(d.teardownCmd, d.params)
+	if err != nil {
+		return fmt.Errorf("failed to prepare teardown command: %v", err)
+	}
+
+	slog.Info("Running remote teardown command", "component", "ssh", "command", cmd)
+
+	output, err := d.runRemote(cmd)
+	if err != nil {
+		slog.Error("Remote teardown command failed", "component", "ssh", "error", err, "output", string(output))
+		return fmt.Errorf("remote teardown command failed: %v, output: %s", err, output)
+	}
+
+	slog.Info("Remote teardown command completed successfully", "component", "ssh")
+
+	return nil
+}
+
+// Check checks that the private key and known hosts files exist and that the remote
+// host accepts connections
+func (d *SSHDriver) Check(params map[string]interface{}) []Check {
+	host, _ := params["host"].(string)
+	if host == "" {
+		return []Check{{Name: "host", Err: fmt.Errorf("ssh driver requires a host parameter")}}
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "22")
+	}
+
+	keyPath, _ := params["key_path"].(string)
+	if keyPath == "" {
+		keyPath = "~/.ssh/id_rsa"
+	}
+	checks := []Check{checkFile("private key", expandHome(keyPath))}
+
+	if !boolParam(params, "insecure_host_key", false) {
+		knownHostsPath, _ := params["known_hosts"].(string)
+		if knownHostsPath == "" {
+			knownHostsPath = "~/.ssh/known_hosts"
+		}
+		checks = append(checks, checkFile("known hosts", expandHome(knownHostsPath)))
+	}
+
+	return append(checks, checkDial("remote host", host))
+}
+
+// Describe returns the driver's description and parameters
+func (d *SSHDriver) Describe() Info {
+	return Info{
+		Name:        "ssh",
+		Description: "Runs commands on a remote host over SSH and forwards a local port to the server",
+		Params: []ParamInfo{
+			{Name: "host", Description: "Remote host, optionally with :port", Required: true},
+			{Name: "url", Description: "URL of the chat completions endpoint as seen from the remote host", Required: true},
+			{Name: "model", Description: "Model name sent with requests"},
+			{Name: "user", Description: "SSH user", Default: "$USER"},
+			{Name: "key_path", Description: "Private key file", Default: "~/.ssh/id_rsa"},
+			{Name: "known_hosts", Description: "Known hosts file used to verify the host key", Default: "~/.ssh/known_hosts"},
+			{Name: "insecure_host_key", Description: "Skip host key verification", Default: "false"},
+			{Name: "setup_cmd", Description: "Remote command run before the benchmark, with {{.param}} templates"},
+			{Name: "teardown_cmd", Description: "Remote command run after the benchmark, with {{.param}} templates"},
+		},
+	}
+}
@@ -0,0 +1,12 @@
+package driver
+
+import "time"
+
+// RequestTimeouter is implemented by drivers whose servers need a request timeout other
+// than the benchmark's default, e.g. a short one for a local mock server or a long one
+// for a remote server that may need minutes for a cold start. The request_timeout_s
+// parameter overrides it.
+type RequestTimeouter interface {
+	// RequestTimeout returns the default timeout of a single chat completion request
+	RequestTimeout() time.Duration
+}
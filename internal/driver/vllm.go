@@ -0,0 +1,252 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults used by the vllm driver
+const (
+	DefaultVLLMBaseURL        = "http://localhost:8000/v1"
+	DefaultVLLMStartupTimeout = 600 * time.Second
+	vllmStopTimeout           = 60 * time.Second
+)
+
+// VLLMDriver implements the Driver interface for vLLM's OpenAI compatible server.
+// Setup optionally launches the server and waits until the model is listed by
+// GET {base_url}/models, since loading weights can take minutes.
+type VLLMDriver struct {
+	url     string
+	baseURL string
+	params  map[string]interface{}
+	model   Model
+	process *serverProcess
+}
+
+// NewVLLMDriver creates a new VLLMDriver instance
+func NewVLLMDriver() *VLLMDriver {
+	return &VLLMDriver{
+		model: Model{Name: ""},
+		url:   "",
+	}
+}
+
+// vllmModelList is the response of the /models endpoint
+type vllmModelList struct {
+	Data []struct {
+		ID          string `json:"id"`
+		Root        string `json:"root"`
+		MaxModelLen int    `json:"max_model_len"`
+	} `json:"data"`
+}
+
+// Setup launches the server if launch_cmd is given and waits for the model to be ready
+func (d *VLLMDriver) Setup(params map[string]interface{}) error {
+	baseURL := DefaultVLLMBaseURL
+	if url, ok := params["base_url"].(string); ok && url != "" {
+		baseURL = strings.TrimSuffix(url, "/")
+	}
+
+	modelName, _ := params["model"].(string)
+	if modelName == "" {
+		return fmt.Errorf("model parameter is required")
+	}
+	d.model.Name = modelName
+
+	startupTimeout := time.Duration(floatParam(params, "startup_timeout_s", DefaultVLLMStartupTimeout.Seconds()) * float64(time.Second))
+
+	// Launch the server if requested
+	if launchCmd, ok := params["launch_cmd"].(string); ok && launchCmd != "" {
+		cmd, err := InterpolateCommand(launchCmd, params)
+		if err != nil {
+			return fmt.Errorf("failed to prepare launch command: %v", err)
+		}
+
+		slog.Info("Launching vLLM server", "component", "vllm", "command", cmd)
+
+		process, err := startServerProcess(exec.Command("sh", "-c", cmd))
+		if err != nil {
+			return fmt.Errorf("error launching vLLM server: %v", err)
+		}
+		d.process = process
+	}
+
+	if err := d.waitForModel(baseURL+"/models", modelName, startupTimeout); err != nil {
+		if d.process != nil {
+			d.process.stop("vllm", vllmStopTimeout)
+			output := d.process.output.String()
+			d.process = nil
+			return fmt.Errorf("%v, output: %s", err, output)
+		}
+		return err
+	}
+
+	d.url = baseURL + "/chat/completions"
+	d.baseURL = baseURL
+	d.params = params
+
+	slog.Info("vLLM model is ready", "component", "vllm", "url", d.url, "model", modelName)
+
+	return nil
+}
+
+// waitForModel polls the models endpoint until the model is listed, the launched
+// server exits or the timeout expires
+func (d *VLLMDriver) waitForModel(modelsURL string, modelName string, timeout time.Duration) error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		if d.process != nil {
+			if exited, status := d.process.exited(); exited {
+				return fmt.Errorf("vLLM server exited during startup: %v", status)
+			}
+		}
+
+		available, err := d.listModels(client, modelsURL)
+		if err == nil {
+			for _, id := range available {
+				if id == modelName {
+					return nil
+				}
+			}
+			slog.Debug("Model not loaded yet", "component", "vllm", "model", modelName, "available", available)
+		} else {
+			slog.Debug("vLLM server not ready yet", "component", "vllm", "error", err)
+		}
+
+		if time.Now().After(deadline) {
+			if err == nil {
+				return fmt.Errorf("model %s not available within %v (available: %s)", modelName, timeout, strings.Join(available, ", "))
+			}
+			return fmt.Errorf("vLLM server did not become ready within %v: %v", timeout, err)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// listModels returns the IDs of the models served
+func (d *VLLMDriver) listModels(client *http.Client, modelsURL string) ([]string, error) {
+	resp, err := client.Get(modelsURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var models vllmModelList
+	if err := json.NewDecoder(resp.Body).Decode(&models); err != nil {
+		return nil, fmt.Errorf("error decoding model list: %v", err)
+	}
+
+	var ids []string
+	for _, model := range models.Data {
+		ids = append(ids, model.ID)
+	}
+	return ids, nil
+}
+
+// ServerConfig returns the context length and model root the /models endpoint reports
+// for the model, warning if the context length differs from a max_model_len parameter
+func (d *VLLMDriver) ServerConfig() (map[string]interface{}, error) {
+	var models vllmModelList
+	if err := getJSON(d.baseURL+"/models", &models); err != nil {
+		return nil, fmt.Errorf("error listing vLLM models: %v", err)
+	}
+
+	for _, model := range models.Data {
+		if model.ID != d.model.Name {
+			continue
+		}
+		config := make(map[string]interface{})
+		if model.MaxModelLen > 0 {
+			config["max_model_len"] = model.MaxModelLen
+		}
+		if model.Root != "" {
+			config["root"] = model.Root
+		}
+		warnConfigMismatch("vllm", d.params, "max_model_len", "max_model_len", model.MaxModelLen)
+		return config, nil
+	}
+	return nil, fmt.Errorf("model %s is not listed by the server", d.model.Name)
+}
+
+// Teardown stops the server if it was launched by the driver
+func (d *VLLMDriver) Teardown() error {
+	if d.process == nil {
+		return nil
+	}
+
+	slog.Info("Stopping vLLM server", "component", "vllm")
+
+	err := d.process.stop("vllm", vllmStopTimeout)
+	d.process = nil
+	if err != nil {
+		return err
+	}
+
+	slog.Info("vLLM server stopped", "component", "vllm")
+
+	return nil
+}
+
+// GetURL returns the chat completions URL of the server
+func (d *VLLMDriver) GetURL() string {
+	return d.url
+}
+
+// GetModel returns the model information
+func (d *VLLMDriver) GetModel() Model {
+	return d.model
+}
+
+// Check checks that the launch command can be run and the server's port is free, or
+// without a launch command, that the server is reachable
+func (d *VLLMDriver) Check(params map[string]interface{}) []Check {
+	baseURL := DefaultVLLMBaseURL
+	if url, ok := params["base_url"].(string); ok && url != "" {
+		baseURL = strings.TrimSuffix(url, "/")
+	}
+
+	var checks []Check
+	if modelName, _ := params["model"].(string); modelName == "" {
+		checks = append(checks, Check{Name: "model", Err: fmt.Errorf("model parameter is required")})
+	}
+
+	launchCmd, _ := params["launch_cmd"].(string)
+	if launchCmd == "" {
+		return append(checks, checkReachable("server", baseURL+"/models"))
+	}
+
+	checks = append(checks, CheckCommand("launch_cmd", launchCmd, params)...)
+	if u, err := url.Parse(baseURL); err == nil && u.Port() != "" {
+		if port, err := strconv.Atoi(u.Port()); err == nil {
+			checks = append(checks, checkPortFree("server port", u.Hostname(), port))
+		}
+	}
+	return checks
+}
+
+// Describe returns the driver's description and parameters
+func (d *VLLMDriver) Describe() Info {
+	return Info{
+		Name:        "vllm",
+		Description: "Waits for vLLM to serve the model, optionally launching the server",
+		Params: []ParamInfo{
+			{Name: "model", Description: "Served model name to wait for", Required: true},
+			{Name: "base_url", Description: "Base URL of the OpenAI compatible API", Default: DefaultVLLMBaseURL},
+			{Name: "launch_cmd", Description: "Command starting the server, with {{.param}} templates"},
+			{Name: "startup_timeout_s", Description: "How long to wait for the model to be ready", Default: fmt.Sprint(DefaultVLLMStartupTimeout.Seconds())},
+		},
+	}
+}
@@ -0,0 +1,93 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
+	"github.com/aifoundry-org/turtlenekko/internal/terminal"
+)
+
+// Best is the combination selected by --select-best
+type Best struct {
+	Index  int     // index of the combination in the matrix results
+	Metric string  // output name of the metric, e.g. "localscore_estimate"
+	Lowest bool    // the lowest value was selected instead of the highest
+	Value  float64 // value of the metric
+}
+
+// ParseSelectMetric splits a --select-best value into the metric and whether the lowest
+// value wins, which a leading "-" selects, e.g. "-short_context_latency_p90_ms"
+func ParseSelectMetric(spec string) (string, bool) {
+	if metric, ok := strings.CutPrefix(spec, "-"); ok {
+		return metric, true
+	}
+	return spec, false
+}
+
+// SelectBest returns the successful combination with the highest value of a metric, or
+// the lowest if spec starts with "-". Ties go to the earlier combination.
+func SelectBest(matrixResults []benchmark.MatrixResult, spec string) (*Best, error) {
+	metric, lowest := ParseSelectMetric(spec)
+
+	var best *Best
+	for i, result := range BuildJSONResults(matrixResults, DefaultOptions()) {
+		if result.Error != "" {
+			continue
+		}
+
+		// Look up the metric by its output name
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding results: %v", err)
+		}
+		var values map[string]interface{}
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("error decoding results: %v", err)
+		}
+		value, ok := values[metric].(float64)
+		if !ok {
+			continue
+		}
+
+		if best == nil || (lowest && value < best.Value) || (!lowest && value > best.Value) {
+			best = &Best{Index: i, Metric: metric, Lowest: lowest, Value: value}
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no successful combination reports %s", metric)
+	}
+	return best, nil
+}
+
+// FormatBest writes the selected combination and its parameters as text
+func FormatBest(out io.Writer, matrixResults []benchmark.MatrixResult, best *Best, opts Options) error {
+	ew := &errWriter{w: out}
+	w := io.Writer(ew)
+
+	selection := "highest"
+	if best.Lowest {
+		selection = "lowest"
+	}
+	matrixResult := matrixResults[best.Index]
+	fmt.Fprintf(w, "%s\n", terminal.BoldText(terminal.CyanText(fmt.Sprintf("=== Best Combination: %d of %d (%s %s) ===",
+		best.Index+1, len(matrixResults), selection, best.Metric))))
+	fmt.Fprintf(w, "%s: %s\n", terminal.BoldText(best.Metric), terminal.GreenText(opts.formatNumber(best.Value)))
+
+	var keys []string
+	for k := range matrixResult.Params {
+		if paramOutput(matrixResult, k, "text") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	fmt.Fprintln(w, terminal.BoldText("Parameters:"))
+	for _, k := range keys {
+		fmt.Fprintf(w, "  %s: %v\n", terminal.BoldText(k), matrixResult.Params[k])
+	}
+
+	return ew.err
+}
@@ -0,0 +1,109 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
+	"github.com/aifoundry-org/turtlenekko/internal/terminal"
+)
+
+// concurrencySweep is the throughput of combinations that only differ in the concurrency
+// parameter, by increasing concurrency
+type concurrencySweep struct {
+	params string // the shown parameters the combinations share
+	points []*benchmark.ConcurrencyResult
+}
+
+// concurrencySweeps groups the concurrency results of combinations that only differ in
+// the concurrency parameter, keeping the groups that measured at least two levels
+func concurrencySweeps(matrixResults []benchmark.MatrixResult) []concurrencySweep {
+	var sweeps []concurrencySweep
+	index := make(map[string]int)
+	for _, matrixResult := range matrixResults {
+		if matrixResult.Error != nil || matrixResult.Concurrency == nil {
+			continue
+		}
+		if _, ok := matrixResult.Params["concurrency"]; !ok {
+			continue
+		}
+
+		// Group by all other parameters, shown or not
+		var key, shown []string
+		for k, v := range matrixResult.Params {
+			if k == "concurrency" {
+				continue
+			}
+			key = append(key, fmt.Sprintf("%s=%v", k, v))
+			if matrixResult.OutputFlags[k] {
+				shown = append(shown, fmt.Sprintf("%s=%v", k, v))
+			}
+		}
+		sort.Strings(key)
+		sort.Strings(shown)
+
+		i, ok := index[strings.Join(key, "\x00")]
+		if !ok {
+			i = len(sweeps)
+			index[strings.Join(key, "\x00")] = i
+			sweeps = append(sweeps, concurrencySweep{params: strings.Join(shown, ", ")})
+		}
+		sweeps[i].points = append(sweeps[i].points, matrixResult.Concurrency)
+	}
+
+	var multiLevel []concurrencySweep
+	for _, sweep := range sweeps {
+		sort.SliceStable(sweep.points, func(a, b int) bool {
+			return sweep.points[a].Concurrency < sweep.points[b].Concurrency
+		})
+		if len(sweep.points) >= 2 {
+			multiLevel = append(multiLevel, sweep)
+		}
+	}
+	return multiLevel
+}
+
+// peakThroughput returns the index of the point with the highest aggregate completion
+// throughput
+func peakThroughput(points []*benchmark.ConcurrencyResult) int {
+	peak := 0
+	for i, point := range points {
+		if point.ConcurrentCompletionTokensPerSec > points[peak].ConcurrentCompletionTokensPerSec {
+			peak = i
+		}
+	}
+	return peak
+}
+
+// writeConcurrencySweeps writes the throughput curve of each concurrency sweep, marking
+// the concurrency with the highest completion throughput; color highlights it in the
+// terminal
+func writeConcurrencySweeps(w io.Writer, matrixResults []benchmark.MatrixResult, color bool) {
+	for _, sweep := range concurrencySweeps(matrixResults) {
+		title := "Concurrency Sweep:"
+		if sweep.params != "" {
+			title = fmt.Sprintf("Concurrency Sweep (%s):", sweep.params)
+		}
+		if color {
+			title = terminal.BoldText(terminal.CyanText(title))
+		}
+		fmt.Fprintln(w, title)
+
+		peak := peakThroughput(sweep.points)
+		for i, point := range sweep.points {
+			completion := fmt.Sprintf("%.2f", point.ConcurrentCompletionTokensPerSec)
+			if color {
+				completion = terminal.GreenText(completion)
+			}
+			line := fmt.Sprintf("  %4d in-flight: %s completion tokens/sec, %.2f prompt tokens/sec, latency %.2fx",
+				point.Concurrency, completion, point.ConcurrentPromptTokensPerSec, point.LatencyDegradation)
+			if i == peak {
+				line += " (peak)"
+			}
+			fmt.Fprintln(w, line)
+		}
+		fmt.Fprintln(w)
+	}
+}
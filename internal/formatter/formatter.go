@@ -3,47 +3,480 @@ package formatter
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"math"
-	"os"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
 	"github.com/aifoundry-org/turtlenekko/internal/terminal"
 )
 
+const (
+	// DefaultPrecision is the default number of decimals
+	DefaultPrecision = 2
+	// RawPrecision keeps the full float64 precision
+	RawPrecision = -1
+)
+
+// Options configures the output of the formatters
+type Options struct {
+	// ShowLocalScore includes the estimated LocalScore
+	ShowLocalScore bool
+	// Precision is the number of decimals of the numbers in the JSON, CSV and InfluxDB
+	// output, RawPrecision to keep them unrounded. The text output always shows 2 decimals.
+	Precision int
+	// Version is the turtlenekko version recorded in the JSON results, empty to leave it out
+	Version string
+}
+
+// DefaultOptions returns the options of the default output, with the LocalScore and
+// DefaultPrecision decimals
+func DefaultOptions() Options {
+	return Options{ShowLocalScore: true, Precision: DefaultPrecision}
+}
+
 // JsonResult represents a benchmark result in JSON format
 type JsonResult struct {
-	Params                             map[string]string `json:"params"`
-	ShortContextPromptTokensPerSec     float64           `json:"short_context_prompt_tokens_per_sec"`
-	ShortContextCachedPromptTokensPerSec float64         `json:"short_context_cached_prompt_tokens_per_sec"`
-	ShortContextCompletionTokensPerSec float64           `json:"short_context_completion_tokens_per_sec"`
-	ShortContextRSquared               float64           `json:"short_context_r_squared"`
-
-	LongContextPromptTokensPerSec     float64 `json:"long_context_prompt_tokens_per_sec"`
+	SchemaVersion                        int                    `json:"schema_version"`
+	Params                               map[string]interface{} `json:"params"`
+	ShortContextPromptTokensPerSec       float64                `json:"short_context_prompt_tokens_per_sec"`
+	ShortContextCachedPromptTokensPerSec float64                `json:"short_context_cached_prompt_tokens_per_sec"`
+	ShortContextCacheSpeedup             float64                `json:"short_context_cache_speedup"`
+	ShortContextCompletionTokensPerSec   float64                `json:"short_context_completion_tokens_per_sec"`
+	ShortContextRSquared                 float64                `json:"short_context_r_squared"`
+	ShortContextAdjustedRSquared         float64                `json:"short_context_adjusted_r_squared"`
+	ShortContextNumPoints                int                    `json:"short_context_num_points"`
+	ShortContextRMSEMs                   float64                `json:"short_context_rmse_ms"`
+	ShortContextFixedOverheadMs          float64                `json:"short_context_fixed_overhead_ms"`
+	ShortContextLatencyP50Ms             float64                `json:"short_context_latency_p50_ms"`
+	ShortContextLatencyP90Ms             float64                `json:"short_context_latency_p90_ms"`
+	ShortContextLatencyP99Ms             float64                `json:"short_context_latency_p99_ms"`
+
+	LongContextPromptTokensPerSec       float64 `json:"long_context_prompt_tokens_per_sec"`
 	LongContextCachedPromptTokensPerSec float64 `json:"long_context_cached_prompt_tokens_per_sec"`
-	LongContextCompletionTokensPerSec float64 `json:"long_context_completion_tokens_per_sec"`
-	LongContextRSquared               float64 `json:"long_context_r_squared"`
+	LongContextCacheSpeedup             float64 `json:"long_context_cache_speedup"`
+	LongContextCompletionTokensPerSec   float64 `json:"long_context_completion_tokens_per_sec"`
+	LongContextRSquared                 float64 `json:"long_context_r_squared"`
+	LongContextAdjustedRSquared         float64 `json:"long_context_adjusted_r_squared"`
+	LongContextNumPoints                int     `json:"long_context_num_points"`
+	LongContextRMSEMs                   float64 `json:"long_context_rmse_ms"`
+	LongContextFixedOverheadMs          float64 `json:"long_context_fixed_overhead_ms"`
+	LongContextLatencyP50Ms             float64 `json:"long_context_latency_p50_ms"`
+	LongContextLatencyP90Ms             float64 `json:"long_context_latency_p90_ms"`
+	LongContextLatencyP99Ms             float64 `json:"long_context_latency_p99_ms"`
+
+	Concurrency                      int     `json:"concurrency,omitempty"`
+	ConcurrentPromptTokensPerSec     float64 `json:"concurrent_prompt_tokens_per_sec,omitempty"`
+	ConcurrentCompletionTokensPerSec float64 `json:"concurrent_completion_tokens_per_sec,omitempty"`
+	ConcurrentLatencyDegradation     float64 `json:"concurrent_latency_degradation,omitempty"`
+
+	BatchSize               int     `json:"batch_size,omitempty"`
+	BatchPromptTokensPerSec float64 `json:"batch_prompt_tokens_per_sec,omitempty"`
+	BatchSpeedup            float64 `json:"batch_speedup,omitempty"`
+
+	ShortContextTTFTMeanMs      float64 `json:"short_context_ttft_mean_ms,omitempty"`
+	ShortContextITLMeanMs       float64 `json:"short_context_itl_mean_ms,omitempty"`
+	ShortContextITLP99Ms        float64 `json:"short_context_itl_p99_ms,omitempty"`
+	ShortContextITLTokensPerSec float64 `json:"short_context_itl_tokens_per_sec,omitempty"`
+	LongContextTTFTMeanMs       float64 `json:"long_context_ttft_mean_ms,omitempty"`
+	LongContextITLMeanMs        float64 `json:"long_context_itl_mean_ms,omitempty"`
+	LongContextITLP99Ms         float64 `json:"long_context_itl_p99_ms,omitempty"`
+	LongContextITLTokensPerSec  float64 `json:"long_context_itl_tokens_per_sec,omitempty"`
+
+	PromptSweep []JsonSweepPoint `json:"prompt_sweep,omitempty"`
+
+	EnergyJoules   float64 `json:"energy_joules,omitempty"`
+	TokensPerJoule float64 `json:"tokens_per_joule,omitempty"`
 
 	LocalScore *float64 `json:"localscore_estimate,omitempty"`
 
+	SetupDurationMs    float64 `json:"setup_duration_ms"`
+	TeardownDurationMs float64 `json:"teardown_duration_ms"`
+
+	PromptSeed     int64 `json:"prompt_seed"`
+	CompletionSeed int   `json:"completion_seed"`
+
+	Temperature float64 `json:"temperature"`
+	TopP        float64 `json:"top_p"`
+	TopK        int     `json:"top_k,omitempty"`
+	MinP        float64 `json:"min_p,omitempty"`
+
+	MaxContextTokens    int     `json:"max_context_tokens,omitempty"`
+	PromptTokensPerByte float64 `json:"prompt_tokens_per_byte,omitempty"`
+	LongContextSkipped  string  `json:"long_context_skipped,omitempty"`
+
+	PromptReductions []JsonPromptReduction `json:"prompt_reductions,omitempty"`
+
+	PromptTokenTargets []JsonPromptTokenTarget `json:"prompt_token_targets,omitempty"`
+
+	WarmupRequests int   `json:"warmup_requests,omitempty"`
+	WarmupStable   *bool `json:"warmup_stable,omitempty"`
+
+	ShortContextModel string `json:"short_context_model,omitempty"`
+	LongContextModel  string `json:"long_context_model,omitempty"`
+
+	ShortContextRatesBelowFloor []string `json:"short_context_rates_below_floor,omitempty"`
+	LongContextRatesBelowFloor  []string `json:"long_context_rates_below_floor,omitempty"`
+
+	ShortContextIterationSlowdowns []float64 `json:"short_context_iteration_slowdowns,omitempty"`
+	LongContextIterationSlowdowns  []float64 `json:"long_context_iteration_slowdowns,omitempty"`
+	ThrottlingDetected             bool      `json:"throttling_detected,omitempty"`
+
+	TurtlenekkoVersion string `json:"turtlenekko_version,omitempty"`
+
+	ServedModel   string                 `json:"served_model,omitempty"`
+	Protocol      string                 `json:"protocol,omitempty"`
+	FinishReasons map[string]int         `json:"finish_reasons,omitempty"`
+	ServerConfig  map[string]interface{} `json:"server_config,omitempty"`
+	SystemInfo    map[string]interface{} `json:"system_info,omitempty"`
+
 	Error string `json:"error,omitempty"`
 }
 
-// FormatJSON formats benchmark results as JSON and prints to stdout
-func FormatJSON(matrixResults []benchmark.MatrixResult, showLocalScore bool) error {
-	var jsonResults []JsonResult
+// JsonSweepPoint is the prompt processing speed at one prompt length in JSON format
+type JsonSweepPoint struct {
+	PromptTokens       int     `json:"prompt_tokens"`
+	ResponseTimeMs     float64 `json:"response_time_ms"`
+	PromptTokensPerSec float64 `json:"prompt_tokens_per_sec"`
+}
+
+// JsonPromptReduction is a config whose prompt was shortened to fit the context in JSON format
+type JsonPromptReduction struct {
+	PromptLength        int `json:"prompt_length"`
+	ReducedPromptLength int `json:"reduced_prompt_length"`
+	MaxTokens           int `json:"max_tokens"`
+}
+
+// JsonPromptTokenTarget is a prompt_tokens target and the prompt length resolved for it in JSON format
+type JsonPromptTokenTarget struct {
+	PromptTokens         int `json:"prompt_tokens"`
+	PromptLength         int `json:"prompt_length"`
+	MeasuredPromptTokens int `json:"measured_prompt_tokens"`
+}
+
+// errWriter remembers the first write error, so formatters can write many lines
+// and report a failure once at the end
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+// Write writes to the underlying writer unless a previous write failed
+func (ew *errWriter) Write(p []byte) (int, error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+	n, err := ew.w.Write(p)
+	ew.err = err
+	return n, err
+}
+
+// cacheSpeedup returns how many times faster cached prompt tokens are processed than
+// uncached ones, or 0 if either rate is unknown or the model fit fell back to placeholder values
+func cacheSpeedup(modelFit *benchmark.ModelFitResult) float64 {
+	if modelFit == nil || modelFit.Fallback || modelFit.PromptRate <= 0 || modelFit.CachedPromptRate <= 0 {
+		return 0
+	}
+	return modelFit.PromptRate / modelFit.CachedPromptRate
+}
+
+// paramOutput reports whether a parameter of a combination is shown in the given output
+// format: its per-format output flag if set, otherwise its output flag
+func paramOutput(matrixResult benchmark.MatrixResult, key string, format string) bool {
+	if output, ok := matrixResult.FormatOutputFlags[format][key]; ok {
+		return output
+	}
+	outputFlag, exists := matrixResult.OutputFlags[key]
+	return exists && outputFlag
+}
+
+// round rounds x to the Precision decimals, or returns it unchanged with RawPrecision
+func (opts Options) round(x float64) float64 {
+	if opts.Precision < 0 {
+		return x
+	}
+	scale := math.Pow(10, float64(opts.Precision))
+	return math.Round(x*scale) / scale
+}
+
+// formatNumber formats x with Precision decimals, or with as many digits as needed to
+// represent it exactly with RawPrecision
+func (opts Options) formatNumber(x float64) string {
+	return strconv.FormatFloat(x, 'f', opts.Precision, 64)
+}
+
+// ratioPrecision is the number of decimals of ratios below 1 such as prompt tokens per
+// byte, which Precision would round away
+const ratioPrecision = 4
+
+// roundRatio rounds a ratio to ratioPrecision decimals, or returns it unchanged with
+// RawPrecision
+func (opts Options) roundRatio(x float64) float64 {
+	if opts.Precision < 0 {
+		return x
+	}
+	scale := math.Pow(10, ratioPrecision)
+	return math.Round(x*scale) / scale
+}
+
+// formatRatio formats a ratio with ratioPrecision decimals, or exactly with RawPrecision
+func (opts Options) formatRatio(x float64) string {
+	if opts.Precision < 0 {
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(x, 'f', ratioPrecision, 64)
+}
+
+// reducedModel reports whether a reduced model was fitted because the data didn't
+// determine all rates
+func reducedModel(modelFit *benchmark.ModelFitResult) bool {
+	return modelFit != nil && modelFit.Model != "" && modelFit.Model != benchmark.FullFitModel
+}
+
+// fitModel returns the fitted model, or empty if the context wasn't fitted
+func fitModel(modelFit *benchmark.ModelFitResult) string {
+	if modelFit == nil {
+		return ""
+	}
+	return modelFit.Model
+}
+
+// promptReductions returns the prompt lengths that were reduced to fit the context as
+// "requested->accepted" pairs joined by "+", or empty if there are none
+func promptReductions(reductions []benchmark.PromptReduction) string {
+	var pairs []string
+	for _, reduction := range reductions {
+		pairs = append(pairs, fmt.Sprintf("%d->%d", reduction.PromptLength, reduction.ReducedPromptLength))
+	}
+	return strings.Join(pairs, "+")
+}
+
+// csvField quotes a CSV value containing a comma, quote or line break, e.g. a
+// comma-separated list parameter, so it stays a single field
+func csvField(value string) string {
+	if !strings.ContainsAny(value, ",\"\r\n") {
+		return value
+	}
+	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+}
+
+// promptTokenTargets returns the prompt_tokens targets and their resolved prompt lengths
+// as "tokens=length" pairs joined by "+", or empty if there are none
+func promptTokenTargets(targets []benchmark.PromptTokenTarget) string {
+	var pairs []string
+	for _, target := range targets {
+		pairs = append(pairs, fmt.Sprintf("%d=%d", target.Tokens, target.PromptLength))
+	}
+	return strings.Join(pairs, "+")
+}
+
+// formatPromptTokenTargets describes the prompt lengths the prompt_tokens targets were
+// resolved to and the prompt tokens the server counted for them
+func formatPromptTokenTargets(targets []benchmark.PromptTokenTarget) string {
+	var pairs []string
+	for _, target := range targets {
+		pairs = append(pairs, fmt.Sprintf("%d tokens = %d characters (%d counted)", target.Tokens, target.PromptLength, target.MeasuredTokens))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// formatPromptReductions describes the prompt lengths that were reduced to fit the context
+func formatPromptReductions(reductions []benchmark.PromptReduction) string {
+	var pairs []string
+	for _, reduction := range reductions {
+		pairs = append(pairs, fmt.Sprintf("%d -> %d", reduction.PromptLength, reduction.ReducedPromptLength))
+	}
+	return strings.Join(pairs, ", ") + " characters (the server rejected the requested lengths as exceeding its context)"
+}
+
+// formatWarmup describes the warmup requests that were sent
+func formatWarmup(warmup *benchmark.WarmupResult) string {
+	requests := fmt.Sprintf("%d requests", warmup.Requests)
+	if warmup.Requests == 1 {
+		requests = "1 request"
+	}
+	if warmup.Mode != benchmark.WarmupModeStable {
+		return requests
+	}
+	if warmup.Stable {
+		return requests + " (response times stabilized)"
+	}
+	return requests + " (response times did not stabilize)"
+}
+
+// ratesBelowFloor returns the rates whose fit was below the lowest plausible rate,
+// joined like a model name, or empty if there are none
+func ratesBelowFloor(modelFit *benchmark.ModelFitResult) string {
+	if modelFit == nil {
+		return ""
+	}
+	return strings.Join(modelFit.RatesBelowFloor, "+")
+}
+
+// formatRatesBelowFloor describes the rates below the floor and whether they were clamped
+func formatRatesBelowFloor(modelFit *benchmark.ModelFitResult) string {
+	if modelFit.Clamped {
+		return ratesBelowFloor(modelFit) + " (raised to the floor, not a genuine measurement)"
+	}
+	return ratesBelowFloor(modelFit) + " (raw fit, clamp_rates is disabled)"
+}
+
+// throttlingDetected reports whether either context benchmark detected possible
+// thermal throttling
+func throttlingDetected(matrixResult benchmark.MatrixResult) bool {
+	return (matrixResult.ShortContextModelFit != nil && matrixResult.ShortContextModelFit.Throttling) ||
+		(matrixResult.LongContextModelFit != nil && matrixResult.LongContextModelFit.Throttling)
+}
+
+// streamed reports whether a context's requests were streamed
+func streamed(fit *benchmark.ModelFitResult) bool {
+	return fit != nil && fit.TTFTMean > 0
+}
+
+// formatStreaming describes the time to first token and inter-token latency of streamed
+// requests, or returns an empty string if the requests weren't streamed
+func formatStreaming(fit *benchmark.ModelFitResult) string {
+	if !streamed(fit) {
+		return ""
+	}
+	return fmt.Sprintf("TTFT %.2f ms, ITL %.2f / %.2f ms (mean/p99), %.2f tokens/sec between tokens",
+		fit.TTFTMean, fit.ITLMean, fit.ITLP99, fit.ITLTokensPerSec)
+}
+
+// formatSlowdowns formats the per-iteration slowdowns as "1.00x, 1.12x"
+func formatSlowdowns(slowdowns []float64) string {
+	var parts []string
+	for _, slowdown := range slowdowns {
+		parts = append(parts, fmt.Sprintf("%.2fx", slowdown))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatServerConfig formats the server-reported settings, or other metadata, as
+// "setting=value" pairs sorted by setting and joined with sep
+func formatServerConfig(config map[string]interface{}, sep string) string {
+	var settings []string
+	for setting := range config {
+		settings = append(settings, setting)
+	}
+	sort.Strings(settings)
+
+	var pairs []string
+	for _, setting := range settings {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", setting, config[setting]))
+	}
+	return strings.Join(pairs, sep)
+}
+
+// formatFinishReasons formats the number of requests per finish reason as
+// "reason=count" pairs sorted by reason and joined with sep
+func formatFinishReasons(counts map[string]int, sep string) string {
+	var reasons []string
+	for reason := range counts {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	var pairs []string
+	for _, reason := range reasons {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", reason, counts[reason]))
+	}
+	return strings.Join(pairs, sep)
+}
+
+// FormatJSON formats benchmark results as JSON and writes them to w
+func FormatJSON(w io.Writer, matrixResults []benchmark.MatrixResult, opts Options) error {
+	jsonResults := BuildJSONResults(matrixResults, opts)
+
+	// Marshal to JSON
+	jsonData, err := json.MarshalIndent(jsonResults, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error creating JSON output: %v", err)
+	}
+
+	if _, err := fmt.Fprintln(w, string(jsonData)); err != nil {
+		return fmt.Errorf("error writing JSON output: %v", err)
+	}
+	return nil
+}
+
+// FormatJSONLine writes one benchmark result as a single line of JSON, for streaming
+// results as JSON lines while the matrix runs
+func FormatJSONLine(w io.Writer, matrixResult benchmark.MatrixResult, opts Options) error {
+	jsonData, err := json.Marshal(BuildJSONResults([]benchmark.MatrixResult{matrixResult}, opts)[0])
+	if err != nil {
+		return fmt.Errorf("error creating JSON output: %v", err)
+	}
+
+	if _, err := fmt.Fprintln(w, string(jsonData)); err != nil {
+		return fmt.Errorf("error writing JSON output: %v", err)
+	}
+	return nil
+}
+
+// BuildJSONResults converts benchmark results to their JSON representation
+func BuildJSONResults(matrixResults []benchmark.MatrixResult, opts Options) []JsonResult {
+	return buildJSONResults(matrixResults, opts, "json")
+}
+
+// buildJSONResults converts benchmark results to their JSON representation with the
+// parameters shown in the given output format
+func buildJSONResults(matrixResults []benchmark.MatrixResult, opts Options, format string) []JsonResult {
+	jsonResults := []JsonResult{}
 
 	for _, matrixResult := range matrixResults {
 		// Filter parameters based on output flags
-		filteredParams := make(map[string]string)
+		filteredParams := make(map[string]interface{})
 		for k, v := range matrixResult.Params {
-			if outputFlag, exists := matrixResult.OutputFlags[k]; exists && outputFlag {
+			if paramOutput(matrixResult, k, format) {
 				filteredParams[k] = v
 			}
 		}
 
 		result := JsonResult{
-			Params: filteredParams,
+			SchemaVersion:       SchemaVersion,
+			Params:              filteredParams,
+			SetupDurationMs:     opts.round(float64(matrixResult.SetupDuration.Microseconds()) / 1000),
+			TeardownDurationMs:  opts.round(float64(matrixResult.TeardownDuration.Microseconds()) / 1000),
+			PromptSeed:          matrixResult.PromptSeed,
+			CompletionSeed:      matrixResult.CompletionSeed,
+			Temperature:         matrixResult.Sampling.Temperature,
+			TopP:                matrixResult.Sampling.TopP,
+			TopK:                matrixResult.Sampling.TopK,
+			MinP:                matrixResult.Sampling.MinP,
+			MaxContextTokens:    matrixResult.MaxContextTokens,
+			PromptTokensPerByte: opts.roundRatio(matrixResult.PromptTokensPerByte),
+			LongContextSkipped:  matrixResult.LongContextSkipped,
+			ServedModel:         matrixResult.ServedModel,
+			Protocol:            matrixResult.Protocol,
+			FinishReasons:       matrixResult.FinishReasons,
+			ServerConfig:        matrixResult.ServerConfig,
+			SystemInfo:          matrixResult.SystemInfo,
+			ThrottlingDetected:  throttlingDetected(matrixResult),
+			TurtlenekkoVersion:  opts.Version,
+		}
+		if matrixResult.Warmup != nil {
+			result.WarmupRequests = matrixResult.Warmup.Requests
+			if matrixResult.Warmup.Mode == benchmark.WarmupModeStable {
+				stable := matrixResult.Warmup.Stable
+				result.WarmupStable = &stable
+			}
+		}
+		for _, reduction := range matrixResult.PromptReductions {
+			result.PromptReductions = append(result.PromptReductions, JsonPromptReduction{
+				PromptLength:        reduction.PromptLength,
+				ReducedPromptLength: reduction.ReducedPromptLength,
+				MaxTokens:           reduction.MaxTokens,
+			})
+		}
+		for _, target := range matrixResult.PromptTokenTargets {
+			result.PromptTokenTargets = append(result.PromptTokenTargets, JsonPromptTokenTarget{
+				PromptTokens:         target.Tokens,
+				PromptLength:         target.PromptLength,
+				MeasuredPromptTokens: target.MeasuredTokens,
+			})
 		}
 
 		if matrixResult.Error != nil {
@@ -53,112 +486,216 @@ func FormatJSON(matrixResults []benchmark.MatrixResult, showLocalScore bool) err
 			if matrixResult.ShortContextModelFit != nil {
 				shortPromptRate := matrixResult.ShortContextModelFit.PromptRate
 				if shortPromptRate > 0 {
-					result.ShortContextPromptTokensPerSec = math.Round((1000.0/shortPromptRate)*100) / 100
+					result.ShortContextPromptTokensPerSec = opts.round(1000.0 / shortPromptRate)
 				}
-				
+
 				shortCachedPromptRate := matrixResult.ShortContextModelFit.CachedPromptRate
 				if shortCachedPromptRate > 0 {
-					result.ShortContextCachedPromptTokensPerSec = math.Round((1000.0/shortCachedPromptRate)*100) / 100
+					result.ShortContextCachedPromptTokensPerSec = opts.round(1000.0 / shortCachedPromptRate)
 				}
+				result.ShortContextCacheSpeedup = opts.round(cacheSpeedup(matrixResult.ShortContextModelFit))
 
 				shortCompletionRate := matrixResult.ShortContextModelFit.CompletionRate
 				if shortCompletionRate > 0 {
-					result.ShortContextCompletionTokensPerSec = math.Round((1000.0/shortCompletionRate)*100) / 100
+					result.ShortContextCompletionTokensPerSec = opts.round(1000.0 / shortCompletionRate)
 				}
 
-				result.ShortContextRSquared = math.Round(matrixResult.ShortContextModelFit.RSquared*100) / 100
+				result.ShortContextRSquared = opts.round(matrixResult.ShortContextModelFit.RSquared)
+				result.ShortContextAdjustedRSquared = opts.round(matrixResult.ShortContextModelFit.AdjustedRSquared)
+				result.ShortContextNumPoints = matrixResult.ShortContextModelFit.NumPoints
+				result.ShortContextRMSEMs = opts.round(matrixResult.ShortContextModelFit.RMSE)
+				result.ShortContextFixedOverheadMs = opts.round(matrixResult.ShortContextModelFit.FixedOverheadMs)
+
+				result.ShortContextLatencyP50Ms = opts.round(matrixResult.ShortContextModelFit.LatencyP50)
+				result.ShortContextLatencyP90Ms = opts.round(matrixResult.ShortContextModelFit.LatencyP90)
+				result.ShortContextLatencyP99Ms = opts.round(matrixResult.ShortContextModelFit.LatencyP99)
+
+				result.ShortContextTTFTMeanMs = opts.round(matrixResult.ShortContextModelFit.TTFTMean)
+				result.ShortContextITLMeanMs = opts.round(matrixResult.ShortContextModelFit.ITLMean)
+				result.ShortContextITLP99Ms = opts.round(matrixResult.ShortContextModelFit.ITLP99)
+				result.ShortContextITLTokensPerSec = opts.round(matrixResult.ShortContextModelFit.ITLTokensPerSec)
+
+				result.ShortContextModel = matrixResult.ShortContextModelFit.Model
+				result.ShortContextRatesBelowFloor = matrixResult.ShortContextModelFit.RatesBelowFloor
+				result.ShortContextIterationSlowdowns = matrixResult.ShortContextModelFit.IterationSlowdowns
 			}
 
 			// Long context metrics
 			if matrixResult.LongContextModelFit != nil {
 				longPromptRate := matrixResult.LongContextModelFit.PromptRate
 				if longPromptRate > 0 {
-					result.LongContextPromptTokensPerSec = math.Round((1000.0/longPromptRate)*100) / 100
+					result.LongContextPromptTokensPerSec = opts.round(1000.0 / longPromptRate)
 				}
-				
+
 				longCachedPromptRate := matrixResult.LongContextModelFit.CachedPromptRate
 				if longCachedPromptRate > 0 {
-					result.LongContextCachedPromptTokensPerSec = math.Round((1000.0/longCachedPromptRate)*100) / 100
+					result.LongContextCachedPromptTokensPerSec = opts.round(1000.0 / longCachedPromptRate)
 				}
+				result.LongContextCacheSpeedup = opts.round(cacheSpeedup(matrixResult.LongContextModelFit))
 
 				longCompletionRate := matrixResult.LongContextModelFit.CompletionRate
 				if longCompletionRate > 0 {
-					result.LongContextCompletionTokensPerSec = math.Round((1000.0/longCompletionRate)*100) / 100
+					result.LongContextCompletionTokensPerSec = opts.round(1000.0 / longCompletionRate)
 				}
 
-				result.LongContextRSquared = math.Round(matrixResult.LongContextModelFit.RSquared*100) / 100
+				result.LongContextRSquared = opts.round(matrixResult.LongContextModelFit.RSquared)
+				result.LongContextAdjustedRSquared = opts.round(matrixResult.LongContextModelFit.AdjustedRSquared)
+				result.LongContextNumPoints = matrixResult.LongContextModelFit.NumPoints
+				result.LongContextRMSEMs = opts.round(matrixResult.LongContextModelFit.RMSE)
+				result.LongContextFixedOverheadMs = opts.round(matrixResult.LongContextModelFit.FixedOverheadMs)
+
+				result.LongContextLatencyP50Ms = opts.round(matrixResult.LongContextModelFit.LatencyP50)
+				result.LongContextLatencyP90Ms = opts.round(matrixResult.LongContextModelFit.LatencyP90)
+				result.LongContextLatencyP99Ms = opts.round(matrixResult.LongContextModelFit.LatencyP99)
+
+				result.LongContextTTFTMeanMs = opts.round(matrixResult.LongContextModelFit.TTFTMean)
+				result.LongContextITLMeanMs = opts.round(matrixResult.LongContextModelFit.ITLMean)
+				result.LongContextITLP99Ms = opts.round(matrixResult.LongContextModelFit.ITLP99)
+				result.LongContextITLTokensPerSec = opts.round(matrixResult.LongContextModelFit.ITLTokensPerSec)
+
+				result.LongContextModel = matrixResult.LongContextModelFit.Model
+				result.LongContextRatesBelowFloor = matrixResult.LongContextModelFit.RatesBelowFloor
+				result.LongContextIterationSlowdowns = matrixResult.LongContextModelFit.IterationSlowdowns
+			}
+
+			// Concurrency metrics
+			if matrixResult.Concurrency != nil {
+				result.Concurrency = matrixResult.Concurrency.Concurrency
+				result.ConcurrentPromptTokensPerSec = opts.round(matrixResult.Concurrency.ConcurrentPromptTokensPerSec)
+				result.ConcurrentCompletionTokensPerSec = opts.round(matrixResult.Concurrency.ConcurrentCompletionTokensPerSec)
+				result.ConcurrentLatencyDegradation = opts.round(matrixResult.Concurrency.LatencyDegradation)
+			}
+
+			// Batch metrics
+			if matrixResult.Batch != nil {
+				result.BatchSize = matrixResult.Batch.BatchSize
+				result.BatchPromptTokensPerSec = opts.round(matrixResult.Batch.BatchPromptTokensPerSec)
+				result.BatchSpeedup = opts.round(matrixResult.Batch.BatchSpeedup)
+			}
+
+			// Prompt sweep metrics
+			for _, point := range matrixResult.PromptSweep {
+				result.PromptSweep = append(result.PromptSweep, JsonSweepPoint{
+					PromptTokens:       point.PromptTokens,
+					ResponseTimeMs:     opts.round(point.ResponseTimeMs),
+					PromptTokensPerSec: opts.round(point.PromptTokensPerSec),
+				})
 			}
 
+			// Energy metrics
+			result.EnergyJoules = opts.round(matrixResult.EnergyJoules)
+			result.TokensPerJoule = opts.round(matrixResult.TokensPerJoule)
+
 			// Include LocalScore if enabled and available
-			if showLocalScore && matrixResult.LocalScore != nil {
-				result.LocalScore = matrixResult.LocalScore
+			if opts.ShowLocalScore && matrixResult.LocalScore != nil {
+				score := opts.round(*matrixResult.LocalScore)
+				result.LocalScore = &score
 			}
 		}
 
 		jsonResults = append(jsonResults, result)
 	}
 
-	// Marshal to JSON
-	jsonData, err := json.MarshalIndent(jsonResults, "", "  ")
-	if err != nil {
-		return fmt.Errorf("error creating JSON output: %v", err)
-	}
-
-	fmt.Println(string(jsonData))
-	return nil
+	return jsonResults
 }
 
-// FormatText formats benchmark results as human-readable text and prints to stdout
-func FormatText(matrixResults []benchmark.MatrixResult, showLocalScore bool) {
+// FormatText formats benchmark results as human-readable text and writes them to w
+func FormatText(out io.Writer, matrixResults []benchmark.MatrixResult, opts Options) error {
+	ew := &errWriter{w: out}
+	w := io.Writer(ew)
+
 	for i, matrixResult := range matrixResults {
 		// Output to console
-		fmt.Printf("\n%s\n", terminal.BoldText(terminal.CyanText(fmt.Sprintf("=== Matrix Combination %d ===", i+1))))
+		fmt.Fprintf(w, "\n%s\n", terminal.BoldText(terminal.CyanText(fmt.Sprintf("=== Matrix Combination %d ===", i+1))))
 
 		// Print parameters used
-		fmt.Println(terminal.BoldText("Parameters:"))
+		fmt.Fprintln(w, terminal.BoldText("Parameters:"))
 		for k, v := range matrixResult.Params {
-			if outputFlag, exists := matrixResult.OutputFlags[k]; exists && outputFlag {
-				fmt.Printf("  %s: %s\n", terminal.BoldText(k), v)
+			if paramOutput(matrixResult, k, "text") {
+				fmt.Fprintf(w, "  %s: %v\n", terminal.BoldText(k), v)
 			}
 		}
 
+		// Print driver lifecycle timing
+		fmt.Fprintf(w, "%s: %.2f ms, %s: %.2f ms\n",
+			terminal.BoldText("Setup"), float64(matrixResult.SetupDuration.Microseconds())/1000,
+			terminal.BoldText("Teardown"), float64(matrixResult.TeardownDuration.Microseconds())/1000)
+		fmt.Fprintf(w, "%s: prompt %d, completion %d\n",
+			terminal.BoldText("Seeds"), matrixResult.PromptSeed, matrixResult.CompletionSeed)
+		fmt.Fprintf(w, "%s: %s\n", terminal.BoldText("Sampling"), matrixResult.Sampling)
+		if matrixResult.Warmup != nil {
+			warmupColor := terminal.GreenText
+			if matrixResult.Warmup.Mode == benchmark.WarmupModeStable && !matrixResult.Warmup.Stable {
+				warmupColor = terminal.YellowText
+			}
+			fmt.Fprintf(w, "%s: %s\n", terminal.BoldText("Warmup"), warmupColor(formatWarmup(matrixResult.Warmup)))
+		}
+		if matrixResult.MaxContextTokens > 0 {
+			fmt.Fprintf(w, "%s: %d tokens\n", terminal.BoldText("Max context"), matrixResult.MaxContextTokens)
+		}
+		if matrixResult.PromptTokensPerByte > 0 {
+			fmt.Fprintf(w, "%s: %.4f\n", terminal.BoldText("Prompt tokens per byte"), matrixResult.PromptTokensPerByte)
+		}
+		if len(matrixResult.PromptTokenTargets) > 0 {
+			fmt.Fprintf(w, "%s: %s\n", terminal.BoldText("Prompt token targets"), formatPromptTokenTargets(matrixResult.PromptTokenTargets))
+		}
+		if matrixResult.ServedModel != "" {
+			fmt.Fprintf(w, "%s: %s\n", terminal.BoldText("Served model"), matrixResult.ServedModel)
+		}
+		if matrixResult.Protocol != "" {
+			fmt.Fprintf(w, "%s: %s\n", terminal.BoldText("Protocol"), matrixResult.Protocol)
+		}
+		if len(matrixResult.FinishReasons) > 0 {
+			fmt.Fprintf(w, "%s: %s\n", terminal.BoldText("Finish reasons"), formatFinishReasons(matrixResult.FinishReasons, ", "))
+		}
+		if len(matrixResult.ServerConfig) > 0 {
+			fmt.Fprintf(w, "%s: %s\n", terminal.BoldText("Server config"), formatServerConfig(matrixResult.ServerConfig, ", "))
+		}
+		if len(matrixResult.SystemInfo) > 0 {
+			fmt.Fprintf(w, "%s: %s\n", terminal.BoldText("System info"), formatServerConfig(matrixResult.SystemInfo, ", "))
+		}
+
 		if matrixResult.Error != nil {
-			fmt.Printf("%s: %v\n", terminal.RedText("Error"), matrixResult.Error)
+			fmt.Fprintf(w, "%s: %v\n", terminal.RedText("Error"), matrixResult.Error)
 			continue
 		}
 
 		// Print short context results
-		fmt.Printf("\n%s\n", terminal.BoldText(terminal.BlueText("Short Context Results:")))
+		fmt.Fprintf(w, "\n%s\n", terminal.BoldText(terminal.BlueText("Short Context Results:")))
 		if matrixResult.ShortContextModelFit != nil {
 			shortPromptRate := matrixResult.ShortContextModelFit.PromptRate
 			shortCachedPromptRate := matrixResult.ShortContextModelFit.CachedPromptRate
 			shortCompletionRate := matrixResult.ShortContextModelFit.CompletionRate
 
 			if shortPromptRate > 0 {
-				fmt.Printf("  %s: %s tokens/sec\n",
+				fmt.Fprintf(w, "  %s: %s tokens/sec\n",
 					terminal.BoldText("Prompt processing"),
 					terminal.GreenText(fmt.Sprintf("%.2f", math.Round((1000.0/shortPromptRate)*100)/100)))
 			} else {
-				fmt.Printf("  %s: %s\n", terminal.BoldText("Prompt processing"), terminal.YellowText("No data"))
+				fmt.Fprintf(w, "  %s: %s\n", terminal.BoldText("Prompt processing"), terminal.YellowText("No data"))
 			}
-			
+
 			if shortCachedPromptRate > 0 {
-				fmt.Printf("  %s: %s tokens/sec\n",
+				fmt.Fprintf(w, "  %s: %s tokens/sec\n",
 					terminal.BoldText("Cached prompt processing"),
 					terminal.GreenText(fmt.Sprintf("%.2f", math.Round((1000.0/shortCachedPromptRate)*100)/100)))
 			} else {
-				fmt.Printf("  %s: %s\n", terminal.BoldText("Cached prompt processing"), terminal.YellowText("No data"))
+				fmt.Fprintf(w, "  %s: %s\n", terminal.BoldText("Cached prompt processing"), terminal.YellowText("No data"))
+			}
+
+			if speedup := cacheSpeedup(matrixResult.ShortContextModelFit); speedup > 0 {
+				fmt.Fprintf(w, "  %s: %s\n", terminal.BoldText("Cache speedup"), terminal.GreenText(fmt.Sprintf("%.2fx", speedup)))
 			}
 
 			if shortCompletionRate > 0 {
-				fmt.Printf("  %s: %s tokens/sec\n",
+				fmt.Fprintf(w, "  %s: %s tokens/sec\n",
 					terminal.BoldText("Completion generation"),
 					terminal.GreenText(fmt.Sprintf("%.2f", math.Round((1000.0/shortCompletionRate)*100)/100)))
 			} else {
-				fmt.Printf("  %s: %s\n", terminal.BoldText("Completion generation"), terminal.YellowText("No data"))
+				fmt.Fprintf(w, "  %s: %s\n", terminal.BoldText("Completion generation"), terminal.YellowText("No data"))
 			}
 
-			rSquared := math.Round(matrixResult.ShortContextModelFit.RSquared*100)/100
+			rSquared := math.Round(matrixResult.ShortContextModelFit.RSquared*100) / 100
 			rSquaredColor := terminal.GreenText
 			if rSquared < 0.9 {
 				rSquaredColor = terminal.YellowText
@@ -166,44 +703,79 @@ func FormatText(matrixResults []benchmark.MatrixResult, showLocalScore bool) {
 			if rSquared < 0.7 {
 				rSquaredColor = terminal.RedText
 			}
-			fmt.Printf("  %s: %s\n", terminal.BoldText("Model fit quality (R²)"), rSquaredColor(fmt.Sprintf("%.2f", rSquared)))
+			fmt.Fprintf(w, "  %s: %s (%d data points)\n", terminal.BoldText("Model fit quality (R²)"),
+				rSquaredColor(fmt.Sprintf("%.2f", rSquared)), matrixResult.ShortContextModelFit.NumPoints)
+			fmt.Fprintf(w, "  %s: adjusted R² %.2f, RMSE %.2f ms, fixed overhead %.2f ms\n",
+				terminal.BoldText("Fit diagnostics"),
+				matrixResult.ShortContextModelFit.AdjustedRSquared,
+				matrixResult.ShortContextModelFit.RMSE,
+				matrixResult.ShortContextModelFit.FixedOverheadMs)
+			if reducedModel(matrixResult.ShortContextModelFit) {
+				fmt.Fprintf(w, "  %s: %s\n", terminal.BoldText("Model"),
+					terminal.YellowText(matrixResult.ShortContextModelFit.Model+" (reduced, the data didn't determine all rates)"))
+			}
+			if len(matrixResult.ShortContextModelFit.RatesBelowFloor) > 0 {
+				fmt.Fprintf(w, "  %s: %s\n", terminal.BoldText("Rates below floor"),
+					terminal.YellowText(formatRatesBelowFloor(matrixResult.ShortContextModelFit)))
+			}
+
+			fmt.Fprintf(w, "  %s: %.2f / %.2f / %.2f ms\n",
+				terminal.BoldText("Latency (p50/p90/p99)"),
+				matrixResult.ShortContextModelFit.LatencyP50,
+				matrixResult.ShortContextModelFit.LatencyP90,
+				matrixResult.ShortContextModelFit.LatencyP99)
+			if streaming := formatStreaming(matrixResult.ShortContextModelFit); streaming != "" {
+				fmt.Fprintf(w, "  %s: %s\n", terminal.BoldText("Streaming"), streaming)
+			}
+			if slowdowns := matrixResult.ShortContextModelFit.IterationSlowdowns; len(slowdowns) > 0 {
+				if matrixResult.ShortContextModelFit.Throttling {
+					fmt.Fprintf(w, "  %s: %s\n", terminal.BoldText("Iteration slowdowns"),
+						terminal.RedText(formatSlowdowns(slowdowns)+" (possible thermal throttling detected)"))
+				} else {
+					fmt.Fprintf(w, "  %s: %s\n", terminal.BoldText("Iteration slowdowns"), formatSlowdowns(slowdowns))
+				}
+			}
 
 		} else {
-			fmt.Printf("  %s\n", terminal.YellowText("No short context data available"))
+			fmt.Fprintf(w, "  %s\n", terminal.YellowText("No short context data available"))
 		}
 
 		// Print long context results
-		fmt.Printf("\n%s\n", terminal.BoldText(terminal.MagentaText("Long Context Results:")))
+		fmt.Fprintf(w, "\n%s\n", terminal.BoldText(terminal.MagentaText("Long Context Results:")))
 		if matrixResult.LongContextModelFit != nil {
 			longPromptRate := matrixResult.LongContextModelFit.PromptRate
 			longCachedPromptRate := matrixResult.LongContextModelFit.CachedPromptRate
 			longCompletionRate := matrixResult.LongContextModelFit.CompletionRate
 
 			if longPromptRate > 0 {
-				fmt.Printf("  %s: %s tokens/sec\n",
+				fmt.Fprintf(w, "  %s: %s tokens/sec\n",
 					terminal.BoldText("Prompt processing"),
 					terminal.GreenText(fmt.Sprintf("%.2f", math.Round((1000.0/longPromptRate)*100)/100)))
 			} else {
-				fmt.Printf("  %s: %s\n", terminal.BoldText("Prompt processing"), terminal.YellowText("No data"))
+				fmt.Fprintf(w, "  %s: %s\n", terminal.BoldText("Prompt processing"), terminal.YellowText("No data"))
 			}
-			
+
 			if longCachedPromptRate > 0 {
-				fmt.Printf("  %s: %s tokens/sec\n",
+				fmt.Fprintf(w, "  %s: %s tokens/sec\n",
 					terminal.BoldText("Cached prompt processing"),
 					terminal.GreenText(fmt.Sprintf("%.2f", math.Round((1000.0/longCachedPromptRate)*100)/100)))
 			} else {
-				fmt.Printf("  %s: %s\n", terminal.BoldText("Cached prompt processing"), terminal.YellowText("No data"))
+				fmt.Fprintf(w, "  %s: %s\n", terminal.BoldText("Cached prompt processing"), terminal.YellowText("No data"))
+			}
+
+			if speedup := cacheSpeedup(matrixResult.LongContextModelFit); speedup > 0 {
+				fmt.Fprintf(w, "  %s: %s\n", terminal.BoldText("Cache speedup"), terminal.GreenText(fmt.Sprintf("%.2fx", speedup)))
 			}
 
 			if longCompletionRate > 0 {
-				fmt.Printf("  %s: %s tokens/sec\n",
+				fmt.Fprintf(w, "  %s: %s tokens/sec\n",
 					terminal.BoldText("Completion generation"),
 					terminal.GreenText(fmt.Sprintf("%.2f", math.Round((1000.0/longCompletionRate)*100)/100)))
 			} else {
-				fmt.Printf("  %s: %s\n", terminal.BoldText("Completion generation"), terminal.YellowText("No data"))
+				fmt.Fprintf(w, "  %s: %s\n", terminal.BoldText("Completion generation"), terminal.YellowText("No data"))
 			}
 
-			rSquared := math.Round(matrixResult.LongContextModelFit.RSquared*100)/100
+			rSquared := math.Round(matrixResult.LongContextModelFit.RSquared*100) / 100
 			rSquaredColor := terminal.GreenText
 			if rSquared < 0.9 {
 				rSquaredColor = terminal.YellowText
@@ -211,34 +783,142 @@ func FormatText(matrixResults []benchmark.MatrixResult, showLocalScore bool) {
 			if rSquared < 0.7 {
 				rSquaredColor = terminal.RedText
 			}
-			fmt.Printf("  %s: %s\n", terminal.BoldText("Model fit quality (R²)"), rSquaredColor(fmt.Sprintf("%.2f", rSquared)))
+			fmt.Fprintf(w, "  %s: %s (%d data points)\n", terminal.BoldText("Model fit quality (R²)"),
+				rSquaredColor(fmt.Sprintf("%.2f", rSquared)), matrixResult.LongContextModelFit.NumPoints)
+			fmt.Fprintf(w, "  %s: adjusted R² %.2f, RMSE %.2f ms, fixed overhead %.2f ms\n",
+				terminal.BoldText("Fit diagnostics"),
+				matrixResult.LongContextModelFit.AdjustedRSquared,
+				matrixResult.LongContextModelFit.RMSE,
+				matrixResult.LongContextModelFit.FixedOverheadMs)
+			if reducedModel(matrixResult.LongContextModelFit) {
+				fmt.Fprintf(w, "  %s: %s\n", terminal.BoldText("Model"),
+					terminal.YellowText(matrixResult.LongContextModelFit.Model+" (reduced, the data didn't determine all rates)"))
+			}
+			if len(matrixResult.LongContextModelFit.RatesBelowFloor) > 0 {
+				fmt.Fprintf(w, "  %s: %s\n", terminal.BoldText("Rates below floor"),
+					terminal.YellowText(formatRatesBelowFloor(matrixResult.LongContextModelFit)))
+			}
 
-			if showLocalScore && matrixResult.LocalScore != nil {
-				score := *matrixResult.LocalScore
-				scoreColor := terminal.GreenText
-				if score < 7.0 {
-					scoreColor = terminal.YellowText
-				}
-				if score < 5.0 {
-					scoreColor = terminal.RedText
+			fmt.Fprintf(w, "  %s: %.2f / %.2f / %.2f ms\n",
+				terminal.BoldText("Latency (p50/p90/p99)"),
+				matrixResult.LongContextModelFit.LatencyP50,
+				matrixResult.LongContextModelFit.LatencyP90,
+				matrixResult.LongContextModelFit.LatencyP99)
+			if streaming := formatStreaming(matrixResult.LongContextModelFit); streaming != "" {
+				fmt.Fprintf(w, "  %s: %s\n", terminal.BoldText("Streaming"), streaming)
+			}
+			if slowdowns := matrixResult.LongContextModelFit.IterationSlowdowns; len(slowdowns) > 0 {
+				if matrixResult.LongContextModelFit.Throttling {
+					fmt.Fprintf(w, "  %s: %s\n", terminal.BoldText("Iteration slowdowns"),
+						terminal.RedText(formatSlowdowns(slowdowns)+" (possible thermal throttling detected)"))
+				} else {
+					fmt.Fprintf(w, "  %s: %s\n", terminal.BoldText("Iteration slowdowns"), formatSlowdowns(slowdowns))
 				}
-				fmt.Printf("\n%s: %s\n", terminal.BoldText("Localscore Estimate"), scoreColor(fmt.Sprintf("%.2f", score)))
 			}
 
-			fmt.Printf("\n")
+			fmt.Fprintf(w, "\n")
+		} else if matrixResult.LongContextSkipped != "" {
+			fmt.Fprintf(w, "  %s\n\n", terminal.YellowText("Skipped: "+matrixResult.LongContextSkipped))
 		} else {
-			fmt.Printf("  %s\n\n", terminal.YellowText("No long context data available"))
+			fmt.Fprintf(w, "  %s\n\n", terminal.YellowText("No long context data available"))
 		}
+
+		if len(matrixResult.PromptReductions) > 0 {
+			fmt.Fprintf(w, "%s: %s\n\n", terminal.BoldText("Reduced prompts"),
+				terminal.YellowText(formatPromptReductions(matrixResult.PromptReductions)))
+		}
+
+		if opts.ShowLocalScore && matrixResult.LocalScore != nil {
+			score := *matrixResult.LocalScore
+			scoreColor := terminal.GreenText
+			if score < 7.0 {
+				scoreColor = terminal.YellowText
+			}
+			if score < 5.0 {
+				scoreColor = terminal.RedText
+			}
+			fmt.Fprintf(w, "%s: %s\n\n", terminal.BoldText("Localscore Estimate"), scoreColor(fmt.Sprintf("%.2f", score)))
+		}
+
+		// Print concurrency results
+		if matrixResult.Concurrency != nil {
+			fmt.Fprintf(w, "%s\n", terminal.BoldText(terminal.CyanText(fmt.Sprintf("Concurrency Results (%d in-flight):", matrixResult.Concurrency.Concurrency))))
+			fmt.Fprintf(w, "  %s: %s tokens/sec\n",
+				terminal.BoldText("Aggregate prompt processing"),
+				terminal.GreenText(fmt.Sprintf("%.2f", matrixResult.Concurrency.ConcurrentPromptTokensPerSec)))
+			fmt.Fprintf(w, "  %s: %s tokens/sec\n",
+				terminal.BoldText("Aggregate completion generation"),
+				terminal.GreenText(fmt.Sprintf("%.2f", matrixResult.Concurrency.ConcurrentCompletionTokensPerSec)))
+			fmt.Fprintf(w, "  %s: %.2f ms (single request: %.2f ms, %.2fx)\n\n",
+				terminal.BoldText("Mean request latency"),
+				matrixResult.Concurrency.MeanLatencyMs,
+				matrixResult.Concurrency.SingleLatencyMs,
+				matrixResult.Concurrency.LatencyDegradation)
+		}
+
+		// Print batch results
+		if matrixResult.Batch != nil {
+			fmt.Fprintf(w, "%s\n", terminal.BoldText(terminal.CyanText(fmt.Sprintf("Batch Results (%d prompts per request):", matrixResult.Batch.BatchSize))))
+			fmt.Fprintf(w, "  %s: %s tokens/sec (single prompt: %.2f tokens/sec, %.2fx)\n",
+				terminal.BoldText("Aggregate prompt processing"),
+				terminal.GreenText(fmt.Sprintf("%.2f", matrixResult.Batch.BatchPromptTokensPerSec)),
+				matrixResult.Batch.SinglePromptTokensPerSec,
+				matrixResult.Batch.BatchSpeedup)
+			fmt.Fprintf(w, "  %s: %.2f ms\n\n", terminal.BoldText("Batch request latency"), matrixResult.Batch.BatchLatencyMs)
+		}
+
+		// Print prompt sweep results
+		if len(matrixResult.PromptSweep) > 0 {
+			fmt.Fprintf(w, "%s\n", terminal.BoldText(terminal.CyanText("Prompt Sweep:")))
+			for _, point := range matrixResult.PromptSweep {
+				fmt.Fprintf(w, "  %6d tokens: %s tokens/sec (%.2f ms)\n",
+					point.PromptTokens,
+					terminal.GreenText(fmt.Sprintf("%.2f", point.PromptTokensPerSec)),
+					point.ResponseTimeMs)
+			}
+			fmt.Fprintf(w, "\n")
+		}
+
+		// Print energy results
+		if matrixResult.EnergyJoules > 0 {
+			fmt.Fprintf(w, "%s: %.2f J (%s tokens/J)\n\n",
+				terminal.BoldText("Energy"),
+				matrixResult.EnergyJoules,
+				terminal.GreenText(fmt.Sprintf("%.2f", matrixResult.TokensPerJoule)))
+		}
+	}
+
+	// Print the throughput curves of concurrency sweeps
+	writeConcurrencySweeps(w, matrixResults, true)
+
+	return ew.err
+}
+
+// samplingValue formats the sampling parameter with the given output name
+func samplingValue(sampling benchmark.Sampling, key string) string {
+	switch key {
+	case "temperature":
+		return fmt.Sprintf("%g", sampling.Temperature)
+	case "top_p":
+		return fmt.Sprintf("%g", sampling.TopP)
+	case "top_k":
+		return fmt.Sprintf("%d", sampling.TopK)
+	case "min_p":
+		return fmt.Sprintf("%g", sampling.MinP)
 	}
+	return ""
 }
 
-// FormatCSV formats benchmark results as CSV and prints to stdout
-func FormatCSV(matrixResults []benchmark.MatrixResult, showLocalScore bool) {
-	// Get all unique parameter keys with output:true
+// FormatCSV formats benchmark results as CSV and writes them to w
+func FormatCSV(out io.Writer, matrixResults []benchmark.MatrixResult, opts Options) error {
+	ew := &errWriter{w: out}
+	w := io.Writer(ew)
+
+	// Get all unique parameter keys shown in the CSV
 	paramKeys := make(map[string]bool)
 	for _, result := range matrixResults {
-		for k, outputFlag := range result.OutputFlags {
-			if outputFlag {
+		for k := range result.OutputFlags {
+			if paramOutput(result, k, "csv") {
 				paramKeys[k] = true
 			}
 		}
@@ -255,27 +935,261 @@ func FormatCSV(matrixResults []benchmark.MatrixResult, showLocalScore bool) {
 	// First the parameter columns
 	for i, key := range sortedParamKeys {
 		if i > 0 {
-			fmt.Print(",")
+			fmt.Fprint(w, ",")
 		}
-		fmt.Print(key)
+		fmt.Fprint(w, key)
 	}
 
 	// Then the metrics columns
 	if len(sortedParamKeys) > 0 {
-		fmt.Print(",")
+		fmt.Fprint(w, ",")
 	}
 	header := "short_context_prompt_tokens_per_sec," +
-		"short_context_cached_prompt_tokens_per_sec," +
+		"short_context_cached_prompt_tokens_per_sec,short_context_cache_speedup," +
 		"short_context_completion_tokens_per_sec,short_context_r_squared," +
+		"short_context_adjusted_r_squared,short_context_rmse_ms,short_context_fixed_overhead_ms," +
+		"short_context_latency_p50_ms,short_context_latency_p90_ms,short_context_latency_p99_ms," +
 		"long_context_prompt_tokens_per_sec," +
-		"long_context_cached_prompt_tokens_per_sec," +
-		"long_context_completion_tokens_per_sec,long_context_r_squared"
+		"long_context_cached_prompt_tokens_per_sec,long_context_cache_speedup," +
+		"long_context_completion_tokens_per_sec,long_context_r_squared," +
+		"long_context_adjusted_r_squared,long_context_rmse_ms,long_context_fixed_overhead_ms," +
+		"long_context_latency_p50_ms,long_context_latency_p90_ms,long_context_latency_p99_ms," +
+		"short_context_num_points,long_context_num_points," +
+		"prompt_seed,completion_seed"
+
+	// Sampling columns, unless the matrix already outputs them as parameters. top_k and
+	// min_p are only included if any combination set them.
+	var samplingKeys []string
+	for _, key := range []string{"temperature", "top_p", "top_k", "min_p"} {
+		if paramKeys[key] {
+			continue
+		}
+		used := key == "temperature" || key == "top_p"
+		for _, result := range matrixResults {
+			used = used || samplingValue(result.Sampling, key) != "0"
+		}
+		if used {
+			samplingKeys = append(samplingKeys, key)
+			header += "," + key
+		}
+	}
+
+	// Concurrency columns are only included if any combination measured them
+	showConcurrency := false
+	for _, result := range matrixResults {
+		if result.Concurrency != nil {
+			showConcurrency = true
+			break
+		}
+	}
+
+	// The concurrency column is left out if the matrix outputs it as a parameter
+	concurrencyColumn := !paramKeys["concurrency"]
+	if showConcurrency {
+		if concurrencyColumn {
+			header += ",concurrency"
+		}
+		header += ",concurrent_prompt_tokens_per_sec," +
+			"concurrent_completion_tokens_per_sec,concurrent_latency_degradation"
+	}
+
+	// Batch columns are only included if any combination measured them
+	showBatch := false
+	for _, result := range matrixResults {
+		if result.Batch != nil {
+			showBatch = true
+			break
+		}
+	}
+	batchSizeColumn := !paramKeys["batch_size"]
+	if showBatch {
+		if batchSizeColumn {
+			header += ",batch_size"
+		}
+		header += ",batch_prompt_tokens_per_sec,batch_speedup"
+	}
+
+	// Streaming columns are only included if any combination streamed its requests
+	showStreaming := false
+	for _, result := range matrixResults {
+		if streamed(result.ShortContextModelFit) || streamed(result.LongContextModelFit) {
+			showStreaming = true
+			break
+		}
+	}
+	if showStreaming {
+		header += ",short_context_ttft_mean_ms,short_context_itl_mean_ms,short_context_itl_p99_ms,short_context_itl_tokens_per_sec" +
+			",long_context_ttft_mean_ms,long_context_itl_mean_ms,long_context_itl_p99_ms,long_context_itl_tokens_per_sec"
+	}
+
+	// Energy columns are only included if any combination measured power draw
+	showEnergy := false
+	for _, result := range matrixResults {
+		if result.EnergyJoules > 0 {
+			showEnergy = true
+			break
+		}
+	}
+
+	if showEnergy {
+		header += ",energy_joules,tokens_per_joule"
+	}
+
+	// The warmup columns are only included if any combination warmed up until stable
+	showWarmup := false
+	for _, result := range matrixResults {
+		if result.Warmup != nil && result.Warmup.Mode == benchmark.WarmupModeStable {
+			showWarmup = true
+			break
+		}
+	}
+
+	if showWarmup {
+		header += ",warmup_requests,warmup_stable"
+	}
+
+	// The context column is only included if any combination knows the context window
+	showMaxContext := false
+	for _, result := range matrixResults {
+		if result.MaxContextTokens > 0 {
+			showMaxContext = true
+			break
+		}
+	}
+
+	if showMaxContext {
+		header += ",max_context_tokens"
+	}
+
+	// The tokenizer ratio column is only included if any server counted prompt tokens
+	showTokensPerByte := false
+	for _, result := range matrixResults {
+		if result.PromptTokensPerByte > 0 {
+			showTokensPerByte = true
+			break
+		}
+	}
+
+	if showTokensPerByte {
+		header += ",prompt_tokens_per_byte"
+	}
+
+	// The skip column is only included if any combination skipped the long context benchmark
+	showLongContextSkipped := false
+	for _, result := range matrixResults {
+		if result.LongContextSkipped != "" {
+			showLongContextSkipped = true
+			break
+		}
+	}
+
+	if showLongContextSkipped {
+		header += ",long_context_skipped"
+	}
+
+	// The reduction column is only included if any combination reduced a prompt to fit the context
+	showPromptReductions := false
+	for _, result := range matrixResults {
+		if len(result.PromptReductions) > 0 {
+			showPromptReductions = true
+			break
+		}
+	}
+
+	if showPromptReductions {
+		header += ",prompt_reductions"
+	}
+
+	// The target column is only included if any combination resolved prompt_tokens targets
+	showPromptTokenTargets := false
+	for _, result := range matrixResults {
+		if len(result.PromptTokenTargets) > 0 {
+			showPromptTokenTargets = true
+			break
+		}
+	}
+
+	if showPromptTokenTargets {
+		header += ",prompt_token_targets"
+	}
+
+	// The model columns are only included if any combination fitted a reduced model
+	showModel := false
+	for _, result := range matrixResults {
+		if reducedModel(result.ShortContextModelFit) || reducedModel(result.LongContextModelFit) {
+			showModel = true
+			break
+		}
+	}
+
+	if showModel {
+		header += ",short_context_model,long_context_model"
+	}
+
+	// The rates below floor columns are only included if any fitted rate was below its floor
+	showRatesBelowFloor := false
+	for _, result := range matrixResults {
+		if ratesBelowFloor(result.ShortContextModelFit) != "" || ratesBelowFloor(result.LongContextModelFit) != "" {
+			showRatesBelowFloor = true
+			break
+		}
+	}
+
+	if showRatesBelowFloor {
+		header += ",short_context_rates_below_floor,long_context_rates_below_floor"
+	}
+
+	// The throttling column is only included if any combination detected throttling
+	showThrottling := false
+	for _, result := range matrixResults {
+		if throttlingDetected(result) {
+			showThrottling = true
+			break
+		}
+	}
+
+	if showThrottling {
+		header += ",throttling_detected"
+	}
 
-	if showLocalScore {
+	// The metadata columns are only included if any server reported them
+	showServedModel := false
+	showProtocol := false
+	showFinishReasons := false
+	showServerConfig := false
+	for _, result := range matrixResults {
+		if result.ServedModel != "" {
+			showServedModel = true
+		}
+		if result.Protocol != "" {
+			showProtocol = true
+		}
+		if len(result.FinishReasons) > 0 {
+			showFinishReasons = true
+		}
+		if len(result.ServerConfig) > 0 {
+			showServerConfig = true
+		}
+	}
+
+	if showServedModel {
+		header += ",served_model"
+	}
+	if showProtocol {
+		header += ",protocol"
+	}
+	if showFinishReasons {
+		header += ",finish_reasons"
+	}
+	if showServerConfig {
+		header += ",server_config"
+	}
+
+	if opts.ShowLocalScore {
 		header += ",localscore_estimate"
 	}
 
-	fmt.Println(header)
+	fmt.Fprintln(w, header)
 
 	// Print each result row
 	for _, result := range matrixResults {
@@ -286,91 +1200,289 @@ func FormatCSV(matrixResults []benchmark.MatrixResult, showLocalScore bool) {
 		// Print parameter values
 		for i, key := range sortedParamKeys {
 			if i > 0 {
-				fmt.Print(",")
+				fmt.Fprint(w, ",")
 			}
 			// Get parameter value, empty string if not found
 			value := ""
 			if v, ok := result.Params[key]; ok {
-				value = v
+				value = csvField(fmt.Sprintf("%v", v))
 			}
-			fmt.Print(value)
+			fmt.Fprint(w, value)
 		}
 
 		// Print metrics
 		if len(sortedParamKeys) > 0 {
-			fmt.Print(",")
+			fmt.Fprint(w, ",")
 		}
 
 		// Short context metrics
 		shortPromptRateTokensPerSec := 0.0
 		shortCachedPromptRateTokensPerSec := 0.0
+		shortCacheSpeedup := cacheSpeedup(result.ShortContextModelFit)
 		shortCompletionRateTokensPerSec := 0.0
 		shortRSquared := 0.0
+		shortAdjustedRSquared := 0.0
+		shortRMSE := 0.0
+		shortFixedOverhead := 0.0
+		shortLatencyP50 := 0.0
+		shortLatencyP90 := 0.0
+		shortLatencyP99 := 0.0
+		shortNumPoints := 0
 
 		if result.ShortContextModelFit != nil {
 			if result.ShortContextModelFit.PromptRate > 0 {
-				shortPromptRateTokensPerSec = math.Round((1000.0/result.ShortContextModelFit.PromptRate)*100) / 100
+				shortPromptRateTokensPerSec = 1000.0 / result.ShortContextModelFit.PromptRate
 			}
-			
+
 			if result.ShortContextModelFit.CachedPromptRate > 0 {
-				shortCachedPromptRateTokensPerSec = math.Round((1000.0/result.ShortContextModelFit.CachedPromptRate)*100) / 100
+				shortCachedPromptRateTokensPerSec = 1000.0 / result.ShortContextModelFit.CachedPromptRate
 			}
 
 			if result.ShortContextModelFit.CompletionRate > 0 {
-				shortCompletionRateTokensPerSec = math.Round((1000.0/result.ShortContextModelFit.CompletionRate)*100) / 100
+				shortCompletionRateTokensPerSec = 1000.0 / result.ShortContextModelFit.CompletionRate
 			}
 
-			shortRSquared = math.Round(result.ShortContextModelFit.RSquared*100) / 100
+			shortRSquared = result.ShortContextModelFit.RSquared
+			shortAdjustedRSquared = result.ShortContextModelFit.AdjustedRSquared
+			shortRMSE = result.ShortContextModelFit.RMSE
+			shortFixedOverhead = result.ShortContextModelFit.FixedOverheadMs
+			shortNumPoints = result.ShortContextModelFit.NumPoints
+
+			shortLatencyP50 = result.ShortContextModelFit.LatencyP50
+			shortLatencyP90 = result.ShortContextModelFit.LatencyP90
+			shortLatencyP99 = result.ShortContextModelFit.LatencyP99
 		}
 
 		// Long context metrics
 		longPromptRateTokensPerSec := 0.0
 		longCachedPromptRateTokensPerSec := 0.0
+		longCacheSpeedup := cacheSpeedup(result.LongContextModelFit)
 		longCompletionRateTokensPerSec := 0.0
 		longRSquared := 0.0
+		longAdjustedRSquared := 0.0
+		longRMSE := 0.0
+		longFixedOverhead := 0.0
+		longLatencyP50 := 0.0
+		longLatencyP90 := 0.0
+		longLatencyP99 := 0.0
+		longNumPoints := 0
 
 		if result.LongContextModelFit != nil {
 			if result.LongContextModelFit.PromptRate > 0 {
-				longPromptRateTokensPerSec = math.Round((1000.0/result.LongContextModelFit.PromptRate)*100) / 100
+				longPromptRateTokensPerSec = 1000.0 / result.LongContextModelFit.PromptRate
 			}
-			
+
 			if result.LongContextModelFit.CachedPromptRate > 0 {
-				longCachedPromptRateTokensPerSec = math.Round((1000.0/result.LongContextModelFit.CachedPromptRate)*100) / 100
+				longCachedPromptRateTokensPerSec = 1000.0 / result.LongContextModelFit.CachedPromptRate
 			}
 
 			if result.LongContextModelFit.CompletionRate > 0 {
-				longCompletionRateTokensPerSec = math.Round((1000.0/result.LongContextModelFit.CompletionRate)*100) / 100
+				longCompletionRateTokensPerSec = 1000.0 / result.LongContextModelFit.CompletionRate
 			}
 
-			longRSquared = math.Round(result.LongContextModelFit.RSquared*100) / 100
+			longRSquared = result.LongContextModelFit.RSquared
+			longAdjustedRSquared = result.LongContextModelFit.AdjustedRSquared
+			longRMSE = result.LongContextModelFit.RMSE
+			longFixedOverhead = result.LongContextModelFit.FixedOverheadMs
+			longNumPoints = result.LongContextModelFit.NumPoints
+
+			longLatencyP50 = result.LongContextModelFit.LatencyP50
+			longLatencyP90 = result.LongContextModelFit.LatencyP90
+			longLatencyP99 = result.LongContextModelFit.LatencyP99
 		}
 
 		// Format the output
-		output := fmt.Sprintf("%.2f,%.2f,%.2f,%.2f,%.2f,%.2f,%.2f,%.2f",
+		numbers := []float64{
 			shortPromptRateTokensPerSec,
 			shortCachedPromptRateTokensPerSec,
+			shortCacheSpeedup,
 			shortCompletionRateTokensPerSec,
 			shortRSquared,
+			shortAdjustedRSquared,
+			shortRMSE,
+			shortFixedOverhead,
+			shortLatencyP50,
+			shortLatencyP90,
+			shortLatencyP99,
 			longPromptRateTokensPerSec,
 			longCachedPromptRateTokensPerSec,
+			longCacheSpeedup,
 			longCompletionRateTokensPerSec,
-			longRSquared)
+			longRSquared,
+			longAdjustedRSquared,
+			longRMSE,
+			longFixedOverhead,
+			longLatencyP50,
+			longLatencyP90,
+			longLatencyP99,
+		}
+		formatted := make([]string, len(numbers))
+		for i, number := range numbers {
+			formatted[i] = opts.formatNumber(number)
+		}
+		output := strings.Join(formatted, ",")
+		output += fmt.Sprintf(",%d,%d", shortNumPoints, longNumPoints)
+
+		// Add seeds so each row can be reproduced
+		output += fmt.Sprintf(",%d,%d", result.PromptSeed, result.CompletionSeed)
+
+		// Add the sampling parameters the results depend on
+		for _, key := range samplingKeys {
+			output += "," + samplingValue(result.Sampling, key)
+		}
+
+		// Add concurrency metrics if any combination measured them
+		if showConcurrency {
+			if concurrencyColumn {
+				if result.Concurrency != nil {
+					output += fmt.Sprintf(",%d", result.Concurrency.Concurrency)
+				} else {
+					output += ","
+				}
+			}
+			if result.Concurrency != nil {
+				output += fmt.Sprintf(",%s,%s,%s",
+					opts.formatNumber(result.Concurrency.ConcurrentPromptTokensPerSec),
+					opts.formatNumber(result.Concurrency.ConcurrentCompletionTokensPerSec),
+					opts.formatNumber(result.Concurrency.LatencyDegradation))
+			} else {
+				output += ",,,"
+			}
+		}
+
+		// Add batch metrics if any combination measured them
+		if showBatch {
+			if batchSizeColumn {
+				if result.Batch != nil {
+					output += fmt.Sprintf(",%d", result.Batch.BatchSize)
+				} else {
+					output += ","
+				}
+			}
+			if result.Batch != nil {
+				output += fmt.Sprintf(",%s,%s",
+					opts.formatNumber(result.Batch.BatchPromptTokensPerSec),
+					opts.formatNumber(result.Batch.BatchSpeedup))
+			} else {
+				output += ",,"
+			}
+		}
+
+		// Add streaming latencies if any combination streamed its requests
+		if showStreaming {
+			for _, fit := range []*benchmark.ModelFitResult{result.ShortContextModelFit, result.LongContextModelFit} {
+				if streamed(fit) {
+					output += fmt.Sprintf(",%s,%s,%s,%s",
+						opts.formatNumber(fit.TTFTMean),
+						opts.formatNumber(fit.ITLMean),
+						opts.formatNumber(fit.ITLP99),
+						opts.formatNumber(fit.ITLTokensPerSec))
+				} else {
+					output += ",,,,"
+				}
+			}
+		}
+
+		// Add energy metrics if any combination measured them
+		if showEnergy {
+			if result.EnergyJoules > 0 {
+				output += "," + opts.formatNumber(result.EnergyJoules) + "," + opts.formatNumber(result.TokensPerJoule)
+			} else {
+				output += ",,"
+			}
+		}
+
+		// Add the warmup requests if any combination warmed up until stable
+		if showWarmup {
+			if result.Warmup != nil {
+				output += fmt.Sprintf(",%d,%t", result.Warmup.Requests, result.Warmup.Stable)
+			} else {
+				output += ",,"
+			}
+		}
+
+		// Add the context window if any combination knows it
+		if showMaxContext {
+			if result.MaxContextTokens > 0 {
+				output += fmt.Sprintf(",%d", result.MaxContextTokens)
+			} else {
+				output += ","
+			}
+		}
+
+		// Add the tokenizer ratio if any server counted prompt tokens
+		if showTokensPerByte {
+			if result.PromptTokensPerByte > 0 {
+				output += "," + opts.formatRatio(result.PromptTokensPerByte)
+			} else {
+				output += ","
+			}
+		}
+
+		// Add the long context skip reason if any combination skipped it
+		if showLongContextSkipped {
+			output += "," + result.LongContextSkipped
+		}
+
+		// Add the reduced prompt lengths if any combination reduced a prompt
+		if showPromptReductions {
+			output += "," + promptReductions(result.PromptReductions)
+		}
+
+		// Add the resolved prompt lengths if any combination resolved prompt_tokens targets
+		if showPromptTokenTargets {
+			output += "," + promptTokenTargets(result.PromptTokenTargets)
+		}
+
+		// Add the fitted models if any combination fitted a reduced model
+		if showModel {
+			output += "," + fitModel(result.ShortContextModelFit) + "," + fitModel(result.LongContextModelFit)
+		}
+
+		// Add the rates below floor if any fitted rate was below its floor
+		if showRatesBelowFloor {
+			output += "," + ratesBelowFloor(result.ShortContextModelFit) + "," + ratesBelowFloor(result.LongContextModelFit)
+		}
+
+		// Add the throttling flag if any combination detected throttling
+		if showThrottling {
+			output += fmt.Sprintf(",%t", throttlingDetected(result))
+		}
+
+		// Add the server metadata if any server reported it
+		if showServedModel {
+			output += "," + result.ServedModel
+		}
+		if showProtocol {
+			output += "," + result.Protocol
+		}
+		if showFinishReasons {
+			output += "," + formatFinishReasons(result.FinishReasons, " ")
+		}
+		if showServerConfig {
+			output += "," + formatServerConfig(result.ServerConfig, " ")
+		}
 
 		// Add LocalScore if enabled and available
-		if showLocalScore {
+		if opts.ShowLocalScore {
 			if result.LocalScore != nil {
-				output += fmt.Sprintf(",%.2f", *result.LocalScore)
+				output += "," + opts.formatNumber(*result.LocalScore)
 			} else {
 				output += ","
 			}
 		}
 
-		fmt.Println(output)
+		fmt.Fprintln(w, output)
 	}
+
+	return ew.err
 }
 
-// WriteToFile writes detailed benchmark results to a log file
-func WriteToFile(file *os.File, matrixResults []benchmark.MatrixResult, showLocalScore bool) {
+// WriteToFile writes detailed benchmark results, including the raw data points, to a log file
+func WriteToFile(out io.Writer, matrixResults []benchmark.MatrixResult, opts Options) error {
+	ew := &errWriter{w: out}
+	file := io.Writer(ew)
+
 	for i, matrixResult := range matrixResults {
 		// Output to log file - no colors in file output
 		fmt.Fprintf(file, "\n=== Matrix Combination %d ===\n", i+1)
@@ -378,11 +1490,45 @@ func WriteToFile(file *os.File, matrixResults []benchmark.MatrixResult, showLoca
 		// Print parameters used
 		fmt.Fprintf(file, "Parameters:\n")
 		for k, v := range matrixResult.Params {
-			if outputFlag, exists := matrixResult.OutputFlags[k]; exists && outputFlag {
-				fmt.Fprintf(file, "  %s: %s\n", k, v)
+			if paramOutput(matrixResult, k, "text") {
+				fmt.Fprintf(file, "  %s: %v\n", k, v)
 			}
 		}
 
+		// Print driver lifecycle timing
+		fmt.Fprintf(file, "Setup: %.2f ms, Teardown: %.2f ms\n",
+			float64(matrixResult.SetupDuration.Microseconds())/1000,
+			float64(matrixResult.TeardownDuration.Microseconds())/1000)
+		fmt.Fprintf(file, "Seeds: prompt %d, completion %d\n", matrixResult.PromptSeed, matrixResult.CompletionSeed)
+		fmt.Fprintf(file, "Sampling: %s\n", matrixResult.Sampling)
+		if matrixResult.Warmup != nil {
+			fmt.Fprintf(file, "Warmup: %s\n", formatWarmup(matrixResult.Warmup))
+		}
+		if matrixResult.MaxContextTokens > 0 {
+			fmt.Fprintf(file, "Max context: %d tokens\n", matrixResult.MaxContextTokens)
+		}
+		if matrixResult.PromptTokensPerByte > 0 {
+			fmt.Fprintf(file, "Prompt tokens per byte: %.4f\n", matrixResult.PromptTokensPerByte)
+		}
+		if len(matrixResult.PromptTokenTargets) > 0 {
+			fmt.Fprintf(file, "Prompt token targets: %s\n", formatPromptTokenTargets(matrixResult.PromptTokenTargets))
+		}
+		if matrixResult.ServedModel != "" {
+			fmt.Fprintf(file, "Served model: %s\n", matrixResult.ServedModel)
+		}
+		if matrixResult.Protocol != "" {
+			fmt.Fprintf(file, "Protocol: %s\n", matrixResult.Protocol)
+		}
+		if len(matrixResult.FinishReasons) > 0 {
+			fmt.Fprintf(file, "Finish reasons: %s\n", formatFinishReasons(matrixResult.FinishReasons, ", "))
+		}
+		if len(matrixResult.ServerConfig) > 0 {
+			fmt.Fprintf(file, "Server config: %s\n", formatServerConfig(matrixResult.ServerConfig, ", "))
+		}
+		if len(matrixResult.SystemInfo) > 0 {
+			fmt.Fprintf(file, "System info: %s\n", formatServerConfig(matrixResult.SystemInfo, ", "))
+		}
+
 		if matrixResult.Error != nil {
 			fmt.Fprintf(file, "Error: %v\n", matrixResult.Error)
 			continue
@@ -401,7 +1547,7 @@ func WriteToFile(file *os.File, matrixResults []benchmark.MatrixResult, showLoca
 			} else {
 				fmt.Fprintf(file, "  Prompt processing: No data\n")
 			}
-			
+
 			if shortCachedPromptRate > 0 {
 				fmt.Fprintf(file, "  Cached prompt processing: %.2f tokens/sec\n",
 					math.Round((1000.0/shortCachedPromptRate)*100)/100)
@@ -409,6 +1555,10 @@ func WriteToFile(file *os.File, matrixResults []benchmark.MatrixResult, showLoca
 				fmt.Fprintf(file, "  Cached prompt processing: No data\n")
 			}
 
+			if speedup := cacheSpeedup(matrixResult.ShortContextModelFit); speedup > 0 {
+				fmt.Fprintf(file, "  Cache speedup: %.2fx\n", speedup)
+			}
+
 			if shortCompletionRate > 0 {
 				fmt.Fprintf(file, "  Completion generation: %.2f tokens/sec\n",
 					math.Round((1000.0/shortCompletionRate)*100)/100)
@@ -416,7 +1566,32 @@ func WriteToFile(file *os.File, matrixResults []benchmark.MatrixResult, showLoca
 				fmt.Fprintf(file, "  Completion generation: No data\n")
 			}
 
-			fmt.Fprintf(file, "  Model fit quality (R²): %.2f\n", math.Round(matrixResult.ShortContextModelFit.RSquared*100)/100)
+			fmt.Fprintf(file, "  Model fit quality (R²): %.2f (%d data points)\n",
+				math.Round(matrixResult.ShortContextModelFit.RSquared*100)/100, matrixResult.ShortContextModelFit.NumPoints)
+			fmt.Fprintf(file, "  Fit diagnostics: adjusted R² %.2f, RMSE %.2f ms, fixed overhead %.2f ms\n",
+				matrixResult.ShortContextModelFit.AdjustedRSquared,
+				matrixResult.ShortContextModelFit.RMSE,
+				matrixResult.ShortContextModelFit.FixedOverheadMs)
+			if reducedModel(matrixResult.ShortContextModelFit) {
+				fmt.Fprintf(file, "  Model: %s (reduced, the data didn't determine all rates)\n", matrixResult.ShortContextModelFit.Model)
+			}
+			if len(matrixResult.ShortContextModelFit.RatesBelowFloor) > 0 {
+				fmt.Fprintf(file, "  Rates below floor: %s\n", formatRatesBelowFloor(matrixResult.ShortContextModelFit))
+			}
+			fmt.Fprintf(file, "  Latency (p50/p90/p99): %.2f / %.2f / %.2f ms\n",
+				matrixResult.ShortContextModelFit.LatencyP50,
+				matrixResult.ShortContextModelFit.LatencyP90,
+				matrixResult.ShortContextModelFit.LatencyP99)
+			if streaming := formatStreaming(matrixResult.ShortContextModelFit); streaming != "" {
+				fmt.Fprintf(file, "  Streaming: %s\n", streaming)
+			}
+			if slowdowns := matrixResult.ShortContextModelFit.IterationSlowdowns; len(slowdowns) > 0 {
+				fmt.Fprintf(file, "  Iteration slowdowns: %s", formatSlowdowns(slowdowns))
+				if matrixResult.ShortContextModelFit.Throttling {
+					fmt.Fprintf(file, " (possible thermal throttling detected)")
+				}
+				fmt.Fprintln(file)
+			}
 
 		} else {
 			fmt.Fprintf(file, "  No short context data available\n")
@@ -435,7 +1610,7 @@ func WriteToFile(file *os.File, matrixResults []benchmark.MatrixResult, showLoca
 			} else {
 				fmt.Fprintf(file, "  Prompt processing: No data\n")
 			}
-			
+
 			if longCachedPromptRate > 0 {
 				fmt.Fprintf(file, "  Cached prompt processing: %.2f tokens/sec\n",
 					math.Round((1000.0/longCachedPromptRate)*100)/100)
@@ -443,6 +1618,10 @@ func WriteToFile(file *os.File, matrixResults []benchmark.MatrixResult, showLoca
 				fmt.Fprintf(file, "  Cached prompt processing: No data\n")
 			}
 
+			if speedup := cacheSpeedup(matrixResult.LongContextModelFit); speedup > 0 {
+				fmt.Fprintf(file, "  Cache speedup: %.2fx\n", speedup)
+			}
+
 			if longCompletionRate > 0 {
 				fmt.Fprintf(file, "  Completion generation: %.2f tokens/sec\n",
 					math.Round((1000.0/longCompletionRate)*100)/100)
@@ -450,15 +1629,87 @@ func WriteToFile(file *os.File, matrixResults []benchmark.MatrixResult, showLoca
 				fmt.Fprintf(file, "  Completion generation: No data\n")
 			}
 
-			fmt.Fprintf(file, "  Model fit quality (R²): %.2f\n", math.Round(matrixResult.LongContextModelFit.RSquared*100)/100)
+			fmt.Fprintf(file, "  Model fit quality (R²): %.2f (%d data points)\n",
+				math.Round(matrixResult.LongContextModelFit.RSquared*100)/100, matrixResult.LongContextModelFit.NumPoints)
+			fmt.Fprintf(file, "  Fit diagnostics: adjusted R² %.2f, RMSE %.2f ms, fixed overhead %.2f ms\n",
+				matrixResult.LongContextModelFit.AdjustedRSquared,
+				matrixResult.LongContextModelFit.RMSE,
+				matrixResult.LongContextModelFit.FixedOverheadMs)
+			if reducedModel(matrixResult.LongContextModelFit) {
+				fmt.Fprintf(file, "  Model: %s (reduced, the data didn't determine all rates)\n", matrixResult.LongContextModelFit.Model)
+			}
+			if len(matrixResult.LongContextModelFit.RatesBelowFloor) > 0 {
+				fmt.Fprintf(file, "  Rates below floor: %s\n", formatRatesBelowFloor(matrixResult.LongContextModelFit))
+			}
+			fmt.Fprintf(file, "  Latency (p50/p90/p99): %.2f / %.2f / %.2f ms\n",
+				matrixResult.LongContextModelFit.LatencyP50,
+				matrixResult.LongContextModelFit.LatencyP90,
+				matrixResult.LongContextModelFit.LatencyP99)
+			if streaming := formatStreaming(matrixResult.LongContextModelFit); streaming != "" {
+				fmt.Fprintf(file, "  Streaming: %s\n", streaming)
+			}
+			if slowdowns := matrixResult.LongContextModelFit.IterationSlowdowns; len(slowdowns) > 0 {
+				fmt.Fprintf(file, "  Iteration slowdowns: %s", formatSlowdowns(slowdowns))
+				if matrixResult.LongContextModelFit.Throttling {
+					fmt.Fprintf(file, " (possible thermal throttling detected)")
+				}
+				fmt.Fprintln(file)
+			}
 
 			fmt.Fprintf(file, "\n")
+		} else if matrixResult.LongContextSkipped != "" {
+			fmt.Fprintf(file, "  Skipped: %s\n\n", matrixResult.LongContextSkipped)
 		} else {
 			fmt.Fprintf(file, "  No long context data available\n\n")
 		}
 
+		if len(matrixResult.PromptReductions) > 0 {
+			fmt.Fprintf(file, "Reduced prompts: %s\n\n", formatPromptReductions(matrixResult.PromptReductions))
+		}
+
+		// Print concurrency results
+		if matrixResult.Concurrency != nil {
+			fmt.Fprintf(file, "Concurrency Results (%d in-flight):\n", matrixResult.Concurrency.Concurrency)
+			fmt.Fprintf(file, "  Aggregate prompt processing: %.2f tokens/sec\n", matrixResult.Concurrency.ConcurrentPromptTokensPerSec)
+			fmt.Fprintf(file, "  Aggregate completion generation: %.2f tokens/sec\n", matrixResult.Concurrency.ConcurrentCompletionTokensPerSec)
+			fmt.Fprintf(file, "  Mean request latency: %.2f ms (single request: %.2f ms, %.2fx)\n\n",
+				matrixResult.Concurrency.MeanLatencyMs,
+				matrixResult.Concurrency.SingleLatencyMs,
+				matrixResult.Concurrency.LatencyDegradation)
+		}
+
+		// Print batch results
+		if matrixResult.Batch != nil {
+			fmt.Fprintf(file, "Batch Results (%d prompts per request):\n", matrixResult.Batch.BatchSize)
+			fmt.Fprintf(file, "  Aggregate prompt processing: %.2f tokens/sec (single prompt: %.2f tokens/sec, %.2fx)\n",
+				matrixResult.Batch.BatchPromptTokensPerSec,
+				matrixResult.Batch.SinglePromptTokensPerSec,
+				matrixResult.Batch.BatchSpeedup)
+			fmt.Fprintf(file, "  Batch request latency: %.2f ms\n\n", matrixResult.Batch.BatchLatencyMs)
+		}
+
+		// Print prompt sweep results
+		if len(matrixResult.PromptSweep) > 0 {
+			fmt.Fprintf(file, "Prompt Sweep:\n")
+			for _, point := range matrixResult.PromptSweep {
+				fmt.Fprintf(file, "  %6d tokens: %.2f tokens/sec (%.2f ms)\n",
+					point.PromptTokens, point.PromptTokensPerSec, point.ResponseTimeMs)
+			}
+			fmt.Fprintf(file, "\n")
+		}
+
+		// Print energy results
+		showEnergy := matrixResult.EnergyJoules > 0
+		if showEnergy {
+			fmt.Fprintf(file, "Energy: %.2f J (%.2f tokens/J)\n\n", matrixResult.EnergyJoules, matrixResult.TokensPerJoule)
+		}
+
 		// Print CSV header
-		fmt.Fprintf(file, "context,prompt_tokens,cached_prompt_tokens,completion_tokens,response_time_ms\n")
+		if showEnergy {
+			fmt.Fprintf(file, "context,prompt_tokens,cached_prompt_tokens,completion_tokens,response_time_ms,energy_joules\n")
+		} else {
+			fmt.Fprintf(file, "context,prompt_tokens,cached_prompt_tokens,completion_tokens,response_time_ms\n")
+		}
 
 		// Print results as CSV
 		for _, result := range matrixResult.Results {
@@ -469,19 +1720,31 @@ func WriteToFile(file *os.File, matrixResults []benchmark.MatrixResult, showLoca
 			// Convert response time to milliseconds
 			responseTimeMs := result.ResponseTime.Milliseconds()
 
-			// Determine if this is a short or long context result
-			contextType := "short"
-			if result.PromptTokens > 1000 || result.CachedPromptTokens > 1000 {
-				contextType = "long"
-			}
-
 			// Output as CSV
+			if showEnergy {
+				fmt.Fprintf(file, "%s,%d,%d,%d,%d,%.3f\n",
+					result.Context,
+					result.PromptTokens,
+					result.CachedPromptTokens,
+					result.CompletionTokens,
+					responseTimeMs,
+					result.EnergyJoules)
+				continue
+			}
 			fmt.Fprintf(file, "%s,%d,%d,%d,%d\n",
-				contextType,
+				result.Context,
 				result.PromptTokens,
 				result.CachedPromptTokens,
 				result.CompletionTokens,
 				responseTimeMs)
 		}
 	}
+
+	// Print the throughput curves of concurrency sweeps
+	if len(concurrencySweeps(matrixResults)) > 0 {
+		fmt.Fprintf(file, "\n")
+		writeConcurrencySweeps(file, matrixResults, false)
+	}
+
+	return ew.err
 }
@@ -0,0 +1,283 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
+)
+
+// replayFixture fits the results captured in testdata/raw.csv
+func replayFixture(t *testing.T) []benchmark.MatrixResult {
+	t.Helper()
+	file, err := os.Open("testdata/raw.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	matrixResults, err := benchmark.ReplayRawCSV(file, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("ReplayRawCSV: %v", err)
+	}
+	if len(matrixResults) != 2 {
+		t.Fatalf("replayed %d combinations, want 2", len(matrixResults))
+	}
+	return matrixResults
+}
+
+func TestFormatRawCSVRoundTrip(t *testing.T) {
+	want, err := os.ReadFile("testdata/raw.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got bytes.Buffer
+	if err := FormatRawCSV(&got, replayFixture(t)); err != nil {
+		t.Fatalf("FormatRawCSV: %v", err)
+	}
+	if got.String() != string(want) {
+		t.Errorf("FormatRawCSV of the replayed results differs from the capture:\n%s", got.String())
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := FormatJSON(&out, replayFixture(t), Options{ShowLocalScore: true, Precision: DefaultPrecision, Version: "1.2.3"}); err != nil {
+		t.Fatalf("FormatJSON: %v", err)
+	}
+
+	var results []JsonResult
+	if err := json.Unmarshal(out.Bytes(), &results); err != nil {
+		t.Fatalf("decoding output: %v", err)
+	}
+	for i, result := range results {
+		if result.SchemaVersion != SchemaVersion {
+			t.Errorf("result %d: schema_version = %d, want %d", i, result.SchemaVersion, SchemaVersion)
+		}
+		if result.TurtlenekkoVersion != "1.2.3" {
+			t.Errorf("result %d: turtlenekko_version = %q, want 1.2.3", i, result.TurtlenekkoVersion)
+		}
+		if got := result.Params["combination"]; got != float64(i+1) {
+			t.Errorf("result %d: combination = %v, want %d", i, got, i+1)
+		}
+		if result.ShortContextPromptTokensPerSec != 19935.82 || result.ShortContextCompletionTokensPerSec != 1980 {
+			t.Errorf("result %d: short context = %v prompt, %v completion tokens/sec, want 19935.82, 1980",
+				i, result.ShortContextPromptTokensPerSec, result.ShortContextCompletionTokensPerSec)
+		}
+		if result.ShortContextNumPoints != 8 || result.LongContextNumPoints != 8 {
+			t.Errorf("result %d: fitted to %d short, %d long context points, want 8, 8",
+				i, result.ShortContextNumPoints, result.LongContextNumPoints)
+		}
+		if result.LocalScore == nil {
+			t.Errorf("result %d: LocalScore missing", i)
+		}
+	}
+
+	if version := NewerSchemaVersion(results); version != 0 {
+		t.Errorf("NewerSchemaVersion = %d for current results, want 0", version)
+	}
+	results[0].SchemaVersion = SchemaVersion + 1
+	if version := NewerSchemaVersion(results); version != SchemaVersion+1 {
+		t.Errorf("NewerSchemaVersion = %d, want %d", version, SchemaVersion+1)
+	}
+}
+
+func TestFormatCSVPrecision(t *testing.T) {
+	for _, tt := range []struct {
+		precision int
+		want      string
+	}{
+		{DefaultPrecision, "1,19935.82,"},
+		{0, "1,19936,"},
+		{RawPrecision, "1,19935.821597"},
+	} {
+		var out bytes.Buffer
+		if err := FormatCSV(&out, replayFixture(t), Options{Precision: tt.precision}); err != nil {
+			t.Fatalf("FormatCSV: %v", err)
+		}
+		rows := strings.Split(out.String(), "\n")
+		if !strings.HasPrefix(rows[1], tt.want) {
+			t.Errorf("precision %d: first row %q, want prefix %q", tt.precision, rows[1], tt.want)
+		}
+	}
+}
+
+func TestConcurrencySweeps(t *testing.T) {
+	result := func(model string, concurrency int, completionTokensPerSec float64) benchmark.MatrixResult {
+		return benchmark.MatrixResult{
+			Params:      map[string]interface{}{"model": model, "concurrency": concurrency},
+			OutputFlags: map[string]bool{"model": true, "concurrency": true},
+			Concurrency: &benchmark.ConcurrencyResult{
+				Concurrency:                      concurrency,
+				ConcurrentCompletionTokensPerSec: completionTokensPerSec,
+			},
+		}
+	}
+	matrixResults := []benchmark.MatrixResult{
+		result("a", 8, 300),
+		result("a", 1, 100),
+		result("b", 1, 90),
+		result("a", 4, 350),
+	}
+
+	sweeps := concurrencySweeps(matrixResults)
+	// b measured a single level, so it has no curve
+	if len(sweeps) != 1 {
+		t.Fatalf("got %d sweeps, want 1", len(sweeps))
+	}
+	if sweeps[0].params != "model=a" {
+		t.Errorf("params = %q, want model=a", sweeps[0].params)
+	}
+	var levels []int
+	for _, point := range sweeps[0].points {
+		levels = append(levels, point.Concurrency)
+	}
+	if fmt.Sprint(levels) != "[1 4 8]" {
+		t.Errorf("levels = %v, want [1 4 8]", levels)
+	}
+	if peak := peakThroughput(sweeps[0].points); sweeps[0].points[peak].Concurrency != 4 {
+		t.Errorf("peak at concurrency %d, want 4", sweeps[0].points[peak].Concurrency)
+	}
+
+	var out bytes.Buffer
+	if err := FormatCSV(&out, matrixResults, Options{Precision: DefaultPrecision}); err != nil {
+		t.Fatalf("FormatCSV: %v", err)
+	}
+	header := strings.Split(out.String(), "\n")[0]
+	if strings.Count(header, "concurrency,") != 1 {
+		t.Errorf("header %q repeats the concurrency column", header)
+	}
+}
+
+func TestPivotTables(t *testing.T) {
+	result := func(model string, ctxSize string) benchmark.MatrixResult {
+		return benchmark.MatrixResult{
+			Params:      map[string]interface{}{"model": model, "ctx_size": ctxSize, "max_iterations": 1},
+			OutputFlags: map[string]bool{"model": true, "ctx_size": true},
+		}
+	}
+	matrixResults := []benchmark.MatrixResult{
+		result("a", "8192"),
+		result("a", "512"),
+		result("b", "4096"),
+		result("a", "4096"),
+	}
+
+	tables, err := pivotTables(matrixResults, "ctx_size", Options{Precision: DefaultPrecision})
+	if err != nil {
+		t.Fatalf("pivotTables: %v", err)
+	}
+	if len(tables) != 2 {
+		t.Fatalf("got %d tables, want 2", len(tables))
+	}
+	// max_iterations isn't shown, so it's not in the title
+	if fmt.Sprint(tables[0].params) != "[model=a]" {
+		t.Errorf("params = %v, want [model=a]", tables[0].params)
+	}
+	// Ordered numerically, not as strings
+	if fmt.Sprint(tables[0].values) != "[512 4096 8192]" {
+		t.Errorf("values = %v, want [512 4096 8192]", tables[0].values)
+	}
+
+	if _, err := pivotTables(matrixResults, "batch_size", Options{Precision: DefaultPrecision}); err == nil {
+		t.Error("pivot by a missing parameter succeeded")
+	}
+}
+
+func TestNewNotification(t *testing.T) {
+	score := func(s float64) *float64 { return &s }
+	matrixResults := []benchmark.MatrixResult{
+		{Params: map[string]interface{}{"threads": 8}, OutputFlags: map[string]bool{"threads": true}, LocalScore: score(20)},
+		{Params: map[string]interface{}{"threads": 16}, OutputFlags: map[string]bool{"threads": true}, LocalScore: score(23)},
+		{Params: map[string]interface{}{"threads": 32}, OutputFlags: map[string]bool{"threads": true}, Error: fmt.Errorf("out of memory")},
+	}
+
+	n := NewNotification(matrixResults, DefaultOptions(), 90*time.Second, nil, []string{"#1 (threads=8): localscore_estimate = 20.00 (min 21)"})
+	if n.Status != "completed" || n.FailedCombinations != 1 || n.DurationS != 90 {
+		t.Errorf("status %q, %d failed, %v s; want completed, 1 failed, 90 s", n.Status, n.FailedCombinations, n.DurationS)
+	}
+	if n.BestLocalScore == nil || *n.BestLocalScore != 23 || n.BestParams["threads"] != 16 {
+		t.Errorf("best %v with %v, want 23 with threads=16", n.BestLocalScore, n.BestParams)
+	}
+
+	var out bytes.Buffer
+	if err := FormatNotification(&out, n, NotifyFormatSlack); err != nil {
+		t.Fatalf("FormatNotification: %v", err)
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(out.Bytes(), &payload); err != nil {
+		t.Fatalf("slack payload is not JSON: %v", err)
+	}
+	if !strings.Contains(payload["text"], "2 of 3 combinations succeeded") || !strings.Contains(payload["text"], "1 threshold check failed") {
+		t.Errorf("unexpected slack text %q", payload["text"])
+	}
+
+	if n := NewNotification(nil, DefaultOptions(), time.Second, fmt.Errorf("driver setup failed"), nil); n.Status != "failed" || n.Error == "" {
+		t.Errorf("run error gives status %q and error %q, want failed", n.Status, n.Error)
+	}
+}
+
+func TestWritePlot(t *testing.T) {
+	lines := `{"params":{"threads":8},"short_context_prompt_tokens_per_sec":1500,"short_context_completion_tokens_per_sec":40}
+{"params":{"threads":16},"error":"out of memory"}
+`
+	results, err := ReadJSONResults(strings.NewReader(lines))
+	if err != nil {
+		t.Fatalf("ReadJSONResults: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+
+	var out bytes.Buffer
+	if err := WritePlot(&out, results); err != nil {
+		t.Fatalf("WritePlot: %v", err)
+	}
+	svg := out.String()
+	if strings.Count(svg, "<svg") != 3 || !strings.Contains(svg, "Short context: 1500.00") {
+		t.Errorf("expected the two bar charts with the short context prompt rate, got:\n%s", svg)
+	}
+	if !strings.Contains(svg, "#2: threads=16: failed: out of memory") {
+		t.Error("failed combination is missing from the list of combinations")
+	}
+
+	if err := WritePlot(&out, results[1:]); err == nil {
+		t.Error("plotting only failed combinations succeeded")
+	}
+}
+
+func TestSelectBest(t *testing.T) {
+	score := func(s float64) *float64 { return &s }
+	matrixResults := []benchmark.MatrixResult{
+		{Params: map[string]interface{}{"threads": 8}, OutputFlags: map[string]bool{"threads": true}, LocalScore: score(20)},
+		{Params: map[string]interface{}{"threads": 16}, OutputFlags: map[string]bool{"threads": true}, LocalScore: score(23)},
+		{Params: map[string]interface{}{"threads": 32}, OutputFlags: map[string]bool{"threads": true}, LocalScore: score(30), Error: fmt.Errorf("out of memory")},
+	}
+
+	best, err := SelectBest(matrixResults, "localscore_estimate")
+	if err != nil || best.Index != 1 || best.Value != 23 {
+		t.Errorf("SelectBest = %+v, %v, want combination 2 with 23", best, err)
+	}
+	if best, err := SelectBest(matrixResults, "-localscore_estimate"); err != nil || best.Index != 0 || !best.Lowest {
+		t.Errorf("SelectBest lowest = %+v, %v, want combination 1", best, err)
+	}
+	if _, err := SelectBest(matrixResults[2:], "localscore_estimate"); err == nil {
+		t.Error("selecting among failed combinations succeeded")
+	}
+
+	var out bytes.Buffer
+	if err := FormatBest(&out, matrixResults, best, DefaultOptions()); err != nil {
+		t.Fatalf("FormatBest: %v", err)
+	}
+	if !strings.Contains(out.String(), "threads") || !strings.Contains(out.String(), "16") {
+		t.Errorf("FormatBest doesn't show the parameters:\n%s", out.String())
+	}
+}
@@ -0,0 +1,176 @@
+package formatter
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
+)
+
+// htmlReportTemplate is the self-contained HTML report page
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Turtlenekko Benchmark Report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child, td.params { text-align: left; }
+.error { color: #c00; }
+figure { margin: 0 0 2em 0; }
+</style>
+</head>
+<body>
+<h1>Turtlenekko Benchmark Report</h1>
+
+<h2>Summary</h2>
+<table>
+<tr><th>#</th><th>Parameters</th><th>Short prompt tok/s</th><th>Short completion tok/s</th><th>Short R²</th><th>Long prompt tok/s</th><th>Long completion tok/s</th><th>Long R²</th>{{if .ShowLocalScore}}<th>LocalScore</th>{{end}}</tr>
+{{range .Rows}}<tr><td>{{.Index}}</td><td class="params">{{.Params}}</td>{{if .Error}}<td class="error" colspan="{{if $.ShowLocalScore}}7{{else}}6{{end}}">{{.Error}}</td>{{else}}<td>{{.ShortPrompt}}</td><td>{{.ShortCompletion}}</td><td>{{.ShortRSquared}}</td><td>{{.LongPrompt}}</td><td>{{.LongCompletion}}</td><td>{{.LongRSquared}}</td>{{if $.ShowLocalScore}}<td>{{.LocalScore}}</td>{{end}}{{end}}</tr>
+{{end}}</table>
+
+<h2>Throughput</h2>
+<figure>{{.PromptChart}}</figure>
+<figure>{{.CompletionChart}}</figure>
+
+<h2>Model Fit</h2>
+<p>Each point is one request: measured response time against the response time predicted by the fitted model.
+Points on the dashed line are predicted exactly; the further they scatter, the lower R².</p>
+{{range .FitCharts}}<figure>{{.}}</figure>
+{{end}}
+</body>
+</html>
+`))
+
+// htmlReportRow is one row of the summary table
+type htmlReportRow struct {
+	Index           int
+	Params          string
+	Error           string
+	ShortPrompt     string
+	ShortCompletion string
+	ShortRSquared   string
+	LongPrompt      string
+	LongCompletion  string
+	LongRSquared    string
+	LocalScore      string
+}
+
+// formatParams renders the output parameters of a combination as "key=value" pairs. The
+// HTML report has no per-format output flag, it follows the plain output flag.
+func formatParams(matrixResult benchmark.MatrixResult) string {
+	var pairs []string
+	for k, v := range matrixResult.Params {
+		if outputFlag, exists := matrixResult.OutputFlags[k]; exists && outputFlag {
+			pairs = append(pairs, fmt.Sprintf("%s=%v", k, v))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+// tokensPerSec converts a rate in ms/token to tokens/sec, or 0 if there is no data
+func tokensPerSec(modelFit *benchmark.ModelFitResult, rate func(*benchmark.ModelFitResult) float64) float64 {
+	if modelFit == nil || rate(modelFit) <= 0 {
+		return 0
+	}
+	return 1000.0 / rate(modelFit)
+}
+
+// predictResponseTime returns the response time predicted by the fitted model (ms)
+func predictResponseTime(modelFit *benchmark.ModelFitResult, result *benchmark.CompletionResult) float64 {
+	return modelFit.PredictMs(result)
+}
+
+// renderFitChart renders the measured against the predicted response time of each
+// request of a combination as a scatter plot
+func renderFitChart(matrixResult benchmark.MatrixResult, title string) string {
+	shortPoints := scatterSeries{Name: "Short context"}
+	longPoints := scatterSeries{Name: "Long context"}
+	for _, result := range matrixResult.Results {
+		if result == nil {
+			continue
+		}
+		measured := float64(result.ResponseTime.Milliseconds())
+		if result.Context == benchmark.ContextLong {
+			if matrixResult.LongContextModelFit != nil {
+				longPoints.Points = append(longPoints.Points, scatterPoint{X: predictResponseTime(matrixResult.LongContextModelFit, result), Y: measured})
+			}
+		} else if matrixResult.ShortContextModelFit != nil {
+			shortPoints.Points = append(shortPoints.Points, scatterPoint{X: predictResponseTime(matrixResult.ShortContextModelFit, result), Y: measured})
+		}
+	}
+
+	return renderScatter(title, "Predicted response time (ms)", "Measured response time (ms)",
+		[]scatterSeries{shortPoints, longPoints}, true)
+}
+
+// WriteHTMLReport writes a self-contained HTML report with charts of the benchmark results
+func WriteHTMLReport(w io.Writer, matrixResults []benchmark.MatrixResult, opts Options) error {
+	promptRate := func(m *benchmark.ModelFitResult) float64 { return m.PromptRate }
+	completionRate := func(m *benchmark.ModelFitResult) float64 { return m.CompletionRate }
+
+	var rows []htmlReportRow
+	var labels []string
+	shortPrompt := barSeries{Name: "Short context"}
+	longPrompt := barSeries{Name: "Long context"}
+	shortCompletion := barSeries{Name: "Short context"}
+	longCompletion := barSeries{Name: "Long context"}
+	var fitCharts []template.HTML
+
+	for i, matrixResult := range matrixResults {
+		row := htmlReportRow{
+			Index:  i + 1,
+			Params: formatParams(matrixResult),
+		}
+
+		if matrixResult.Error != nil {
+			row.Error = matrixResult.Error.Error()
+			rows = append(rows, row)
+			continue
+		}
+
+		row.ShortPrompt = fmt.Sprintf("%.2f", tokensPerSec(matrixResult.ShortContextModelFit, promptRate))
+		row.ShortCompletion = fmt.Sprintf("%.2f", tokensPerSec(matrixResult.ShortContextModelFit, completionRate))
+		row.LongPrompt = fmt.Sprintf("%.2f", tokensPerSec(matrixResult.LongContextModelFit, promptRate))
+		row.LongCompletion = fmt.Sprintf("%.2f", tokensPerSec(matrixResult.LongContextModelFit, completionRate))
+		if matrixResult.ShortContextModelFit != nil {
+			row.ShortRSquared = fmt.Sprintf("%.2f", matrixResult.ShortContextModelFit.RSquared)
+		}
+		if matrixResult.LongContextModelFit != nil {
+			row.LongRSquared = fmt.Sprintf("%.2f", matrixResult.LongContextModelFit.RSquared)
+		}
+		if matrixResult.LocalScore != nil {
+			row.LocalScore = fmt.Sprintf("%.2f", *matrixResult.LocalScore)
+		}
+		rows = append(rows, row)
+
+		label := fmt.Sprintf("#%d", i+1)
+		labels = append(labels, label)
+		shortPrompt.Values = append(shortPrompt.Values, tokensPerSec(matrixResult.ShortContextModelFit, promptRate))
+		longPrompt.Values = append(longPrompt.Values, tokensPerSec(matrixResult.LongContextModelFit, promptRate))
+		shortCompletion.Values = append(shortCompletion.Values, tokensPerSec(matrixResult.ShortContextModelFit, completionRate))
+		longCompletion.Values = append(longCompletion.Values, tokensPerSec(matrixResult.LongContextModelFit, completionRate))
+
+		fitCharts = append(fitCharts, template.HTML(renderFitChart(matrixResult, fmt.Sprintf("Combination %s (%s)", label, row.Params))))
+	}
+
+	return htmlReportTemplate.Execute(w, struct {
+		ShowLocalScore  bool
+		Rows            []htmlReportRow
+		PromptChart     template.HTML
+		CompletionChart template.HTML
+		FitCharts       []template.HTML
+	}{
+		ShowLocalScore:  opts.ShowLocalScore,
+		Rows:            rows,
+		PromptChart:     template.HTML(renderBarChart("Prompt processing", "tokens/sec", labels, []barSeries{shortPrompt, longPrompt})),
+		CompletionChart: template.HTML(renderBarChart("Completion generation", "tokens/sec", labels, []barSeries{shortCompletion, longCompletion})),
+		FitCharts:       fitCharts,
+	})
+}
@@ -0,0 +1,88 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
+)
+
+// InfluxMeasurement is the measurement name of the InfluxDB line protocol points
+const InfluxMeasurement = "turtlenekko"
+
+// influxTagEscaper escapes tag keys and values in line protocol
+var influxTagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// influxField is a field of a line protocol point
+type influxField struct {
+	name  string
+	value float64
+}
+
+// FormatInflux formats benchmark results as InfluxDB line protocol and writes them to w.
+// Each successful combination is one point tagged with its output parameters and
+// timestamped with the start of the run.
+func FormatInflux(out io.Writer, matrixResults []benchmark.MatrixResult, opts Options, timestamp time.Time) error {
+	ew := &errWriter{w: out}
+	w := io.Writer(ew)
+
+	for _, result := range buildJSONResults(matrixResults, opts, "influx") {
+		if result.Error != "" {
+			continue // Skip combinations with errors
+		}
+
+		// Tags from the output parameters, sorted as recommended for write performance
+		var keys []string
+		for k := range result.Params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		line := InfluxMeasurement
+		for _, k := range keys {
+			value := fmt.Sprintf("%v", result.Params[k])
+			if value == "" {
+				continue // Empty tag values are not allowed
+			}
+			line += "," + influxTagEscaper.Replace(k) + "=" + influxTagEscaper.Replace(value)
+		}
+
+		fields := []influxField{
+			{"short_context_prompt_tokens_per_sec", result.ShortContextPromptTokensPerSec},
+			{"short_context_cached_prompt_tokens_per_sec", result.ShortContextCachedPromptTokensPerSec},
+			{"short_context_completion_tokens_per_sec", result.ShortContextCompletionTokensPerSec},
+			{"short_context_r_squared", result.ShortContextRSquared},
+			{"short_context_adjusted_r_squared", result.ShortContextAdjustedRSquared},
+			{"short_context_num_points", float64(result.ShortContextNumPoints)},
+			{"short_context_rmse_ms", result.ShortContextRMSEMs},
+			{"short_context_fixed_overhead_ms", result.ShortContextFixedOverheadMs},
+			{"long_context_prompt_tokens_per_sec", result.LongContextPromptTokensPerSec},
+			{"long_context_cached_prompt_tokens_per_sec", result.LongContextCachedPromptTokensPerSec},
+			{"long_context_completion_tokens_per_sec", result.LongContextCompletionTokensPerSec},
+			{"long_context_r_squared", result.LongContextRSquared},
+			{"long_context_adjusted_r_squared", result.LongContextAdjustedRSquared},
+			{"long_context_num_points", float64(result.LongContextNumPoints)},
+			{"long_context_rmse_ms", result.LongContextRMSEMs},
+			{"long_context_fixed_overhead_ms", result.LongContextFixedOverheadMs},
+		}
+		if result.PromptTokensPerByte > 0 {
+			fields = append(fields, influxField{"prompt_tokens_per_byte", result.PromptTokensPerByte})
+		}
+		if result.LocalScore != nil {
+			fields = append(fields, influxField{"localscore_estimate", *result.LocalScore})
+		}
+
+		var fieldSet []string
+		for _, field := range fields {
+			fieldSet = append(fieldSet, field.name+"="+strconv.FormatFloat(field.value, 'f', -1, 64))
+		}
+
+		fmt.Fprintf(w, "%s %s %d\n", line, strings.Join(fieldSet, ","), timestamp.UnixNano())
+	}
+
+	return ew.err
+}
@@ -0,0 +1,95 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
+)
+
+// Notification payload formats
+const (
+	NotifyFormatJSON  = "json"  // the Notification as JSON
+	NotifyFormatSlack = "slack" // a Slack incoming webhook message
+)
+
+// Notification summarizes a finished or failed matrix run for a webhook
+type Notification struct {
+	Status             string                 `json:"status"` // "completed", or "failed" if no combination succeeded
+	Error              string                 `json:"error,omitempty"`
+	DurationS          float64                `json:"duration_s"`
+	Combinations       int                    `json:"combinations"`
+	FailedCombinations int                    `json:"failed_combinations"`
+	BestLocalScore     *float64               `json:"best_localscore_estimate,omitempty"`
+	BestParams         map[string]interface{} `json:"best_params,omitempty"`
+	ThresholdFailures  []string               `json:"threshold_failures,omitempty"`
+	Results            []JsonResult           `json:"results,omitempty"`
+}
+
+// NewNotification summarizes a matrix run that took duration; runErr is the error that
+// stopped the matrix, and thresholdFailures describe the failed threshold checks
+func NewNotification(matrixResults []benchmark.MatrixResult, opts Options, duration time.Duration, runErr error, thresholdFailures []string) Notification {
+	n := Notification{
+		Status:            "completed",
+		DurationS:         opts.round(duration.Seconds()),
+		Combinations:      len(matrixResults),
+		ThresholdFailures: thresholdFailures,
+		Results:           BuildJSONResults(matrixResults, opts),
+	}
+	for _, result := range n.Results {
+		if result.Error != "" {
+			n.FailedCombinations++
+			continue
+		}
+		if result.LocalScore != nil && (n.BestLocalScore == nil || *result.LocalScore > *n.BestLocalScore) {
+			n.BestLocalScore = result.LocalScore
+			n.BestParams = result.Params
+		}
+	}
+	if runErr != nil {
+		n.Error = runErr.Error()
+	}
+	if runErr != nil || n.FailedCombinations == n.Combinations {
+		n.Status = "failed"
+	}
+	return n
+}
+
+// slackText describes the notification as a Slack message
+func (n Notification) slackText() string {
+	duration := (time.Duration(n.DurationS) * time.Second).String()
+	if n.Error != "" {
+		return fmt.Sprintf("Turtlenekko benchmark failed after %s: %s", duration, n.Error)
+	}
+
+	text := fmt.Sprintf("Turtlenekko benchmark %s in %s: %d of %d combinations succeeded.",
+		n.Status, duration, n.Combinations-n.FailedCombinations, n.Combinations)
+	if n.BestLocalScore != nil {
+		text += fmt.Sprintf(" Best LocalScore %.2f (%s).", *n.BestLocalScore, joinParams(n.BestParams))
+	}
+	if len(n.ThresholdFailures) > 0 {
+		checks := "checks"
+		if len(n.ThresholdFailures) == 1 {
+			checks = "check"
+		}
+		text += fmt.Sprintf(" %d threshold %s failed:", len(n.ThresholdFailures), checks)
+		for _, failure := range n.ThresholdFailures {
+			text += "\n• " + failure
+		}
+	}
+	return text
+}
+
+// FormatNotification writes the notification payload in the given format
+func FormatNotification(w io.Writer, n Notification, format string) error {
+	switch format {
+	case NotifyFormatJSON:
+		return json.NewEncoder(w).Encode(n)
+	case NotifyFormatSlack:
+		return json.NewEncoder(w).Encode(map[string]string{"text": n.slackText()})
+	default:
+		return fmt.Errorf("invalid notification format %q, must be %s or %s", format, NotifyFormatJSON, NotifyFormatSlack)
+	}
+}
@@ -0,0 +1,229 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
+)
+
+// pivotMetric is a column of a pivot table
+type pivotMetric struct {
+	header string // column header in text output
+	name   string // JSON key, the column header in CSV output
+	value  func(JsonResult) float64
+}
+
+// pivotMetrics are the metrics compared in pivot tables, those of the Markdown summary
+var pivotMetrics = []pivotMetric{
+	{"Prompt t/s", "short_context_prompt_tokens_per_sec", func(r JsonResult) float64 { return r.ShortContextPromptTokensPerSec }},
+	{"Cached prompt t/s", "short_context_cached_prompt_tokens_per_sec", func(r JsonResult) float64 { return r.ShortContextCachedPromptTokensPerSec }},
+	{"Completion t/s", "short_context_completion_tokens_per_sec", func(r JsonResult) float64 { return r.ShortContextCompletionTokensPerSec }},
+	{"Long prompt t/s", "long_context_prompt_tokens_per_sec", func(r JsonResult) float64 { return r.LongContextPromptTokensPerSec }},
+	{"Long completion t/s", "long_context_completion_tokens_per_sec", func(r JsonResult) float64 { return r.LongContextCompletionTokensPerSec }},
+}
+
+// pivotTable holds the results of combinations that only differ in the pivot parameter,
+// one row per value
+type pivotTable struct {
+	params  []string      // the shown parameters the combinations share, as key=value
+	values  []interface{} // the pivot parameter's values
+	results []JsonResult  // the result of each value
+}
+
+// pivotTables groups the results by all parameters except param, shown or not, with the
+// rows of each group ordered by param: numerically if all of its values are numbers,
+// otherwise in matrix order
+func pivotTables(matrixResults []benchmark.MatrixResult, param string, opts Options) ([]pivotTable, error) {
+	results := BuildJSONResults(matrixResults, opts)
+
+	var tables []pivotTable
+	index := make(map[string]int)
+	for i, matrixResult := range matrixResults {
+		value, ok := matrixResult.Params[param]
+		if !ok {
+			continue
+		}
+
+		var key, shown []string
+		for k, v := range matrixResult.Params {
+			if k == param {
+				continue
+			}
+			key = append(key, fmt.Sprintf("%s=%v", k, v))
+			if matrixResult.OutputFlags[k] {
+				shown = append(shown, fmt.Sprintf("%s=%v", k, v))
+			}
+		}
+		sort.Strings(key)
+		sort.Strings(shown)
+
+		t, ok := index[strings.Join(key, "\x00")]
+		if !ok {
+			t = len(tables)
+			index[strings.Join(key, "\x00")] = t
+			tables = append(tables, pivotTable{params: shown})
+		}
+		tables[t].values = append(tables[t].values, value)
+		tables[t].results = append(tables[t].results, results[i])
+	}
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("no combination has the pivot parameter %s", param)
+	}
+
+	for _, table := range tables {
+		if numbers, ok := pivotNumbers(table.values); ok {
+			sort.Stable(pivotRows{table, numbers})
+		}
+	}
+	return tables, nil
+}
+
+// pivotNumbers returns the pivot values as numbers, or false if any isn't one
+func pivotNumbers(values []interface{}) ([]float64, bool) {
+	numbers := make([]float64, len(values))
+	for i, value := range values {
+		number, err := strconv.ParseFloat(fmt.Sprintf("%v", value), 64)
+		if err != nil {
+			return nil, false
+		}
+		numbers[i] = number
+	}
+	return numbers, true
+}
+
+// pivotRows sorts the rows of a pivot table by their numeric pivot values
+type pivotRows struct {
+	table   pivotTable
+	numbers []float64
+}
+
+func (r pivotRows) Len() int           { return len(r.numbers) }
+func (r pivotRows) Less(i, j int) bool { return r.numbers[i] < r.numbers[j] }
+func (r pivotRows) Swap(i, j int) {
+	r.numbers[i], r.numbers[j] = r.numbers[j], r.numbers[i]
+	r.table.values[i], r.table.values[j] = r.table.values[j], r.table.values[i]
+	r.table.results[i], r.table.results[j] = r.table.results[j], r.table.results[i]
+}
+
+// FormatPivot writes a table per group of combinations that only differ in param, with
+// a row per value of param and the main metrics as columns
+func FormatPivot(out io.Writer, matrixResults []benchmark.MatrixResult, param string, opts Options) error {
+	tables, err := pivotTables(matrixResults, param, opts)
+	if err != nil {
+		return err
+	}
+
+	ew := &errWriter{w: out}
+	for i, table := range tables {
+		if i > 0 {
+			fmt.Fprintln(ew)
+		}
+		if len(table.params) > 0 {
+			fmt.Fprintf(ew, "Pivot by %s (%s):\n", param, strings.Join(table.params, ", "))
+		} else {
+			fmt.Fprintf(ew, "Pivot by %s:\n", param)
+		}
+
+		tw := tabwriter.NewWriter(ew, 0, 4, 2, ' ', tabwriter.AlignRight)
+		header := param + "\t"
+		for _, metric := range pivotMetrics {
+			header += metric.header + "\t"
+		}
+		if opts.ShowLocalScore {
+			header += "LocalScore\t"
+		}
+		fmt.Fprintln(tw, header)
+
+		for row, result := range table.results {
+			line := fmt.Sprintf("%v\t", table.values[row])
+			if result.Error != "" {
+				fmt.Fprintln(tw, line+"failed: "+result.Error)
+				continue
+			}
+			for _, metric := range pivotMetrics {
+				line += fmt.Sprintf("%.2f\t", metric.value(result))
+			}
+			if opts.ShowLocalScore {
+				if result.LocalScore != nil {
+					line += fmt.Sprintf("%.2f\t", *result.LocalScore)
+				} else {
+					line += "-\t"
+				}
+			}
+			fmt.Fprintln(tw, line)
+		}
+		tw.Flush()
+	}
+	return ew.err
+}
+
+// FormatPivotCSV writes the pivot tables as CSV: the shared parameters of each group,
+// the pivot parameter and the main metrics, one row per successful combination, grouped
+// and ordered like FormatPivot
+func FormatPivotCSV(out io.Writer, matrixResults []benchmark.MatrixResult, param string, opts Options) error {
+	tables, err := pivotTables(matrixResults, param, opts)
+	if err != nil {
+		return err
+	}
+
+	// The shared parameters of any group, except the pivot parameter
+	var paramNames []string
+	seen := make(map[string]bool)
+	for _, table := range tables {
+		for _, pair := range table.params {
+			name, _, _ := strings.Cut(pair, "=")
+			if !seen[name] {
+				seen[name] = true
+				paramNames = append(paramNames, name)
+			}
+		}
+	}
+	sort.Strings(paramNames)
+
+	ew := &errWriter{w: out}
+	header := append(append([]string{}, paramNames...), param)
+	for _, metric := range pivotMetrics {
+		header = append(header, metric.name)
+	}
+	if opts.ShowLocalScore {
+		header = append(header, "localscore_estimate")
+	}
+	fmt.Fprintln(ew, strings.Join(header, ","))
+
+	for _, table := range tables {
+		shared := make(map[string]string)
+		for _, pair := range table.params {
+			name, value, _ := strings.Cut(pair, "=")
+			shared[name] = value
+		}
+
+		for row, result := range table.results {
+			if result.Error != "" {
+				continue // Skip rows with errors
+			}
+
+			var fields []string
+			for _, name := range paramNames {
+				fields = append(fields, csvField(shared[name]))
+			}
+			fields = append(fields, csvField(fmt.Sprintf("%v", table.values[row])))
+			for _, metric := range pivotMetrics {
+				fields = append(fields, opts.formatNumber(metric.value(result)))
+			}
+			if opts.ShowLocalScore {
+				if result.LocalScore != nil {
+					fields = append(fields, opts.formatNumber(*result.LocalScore))
+				} else {
+					fields = append(fields, "")
+				}
+			}
+			fmt.Fprintln(ew, strings.Join(fields, ","))
+		}
+	}
+	return ew.err
+}
@@ -0,0 +1,120 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
+)
+
+// plotCaptionLineHeight is the height of a line of the combination list below the charts
+const plotCaptionLineHeight = 16
+
+// ReadJSONResults reads results saved with -f json, or the JSON lines of --stream-output
+func ReadJSONResults(r io.Reader) ([]JsonResult, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading results: %v", err)
+	}
+
+	var results []JsonResult
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		if err := json.Unmarshal(data, &results); err != nil {
+			return nil, fmt.Errorf("error parsing results: %v", err)
+		}
+		return results, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var result JsonResult
+		if err := decoder.Decode(&result); err != nil {
+			return nil, fmt.Errorf("error parsing results: %v", err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// joinParams renders parameters as sorted "key=value" pairs
+func joinParams(params map[string]interface{}) string {
+	var pairs []string
+	for k, v := range params {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", k, v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+// WritePlot writes an SVG with the tokens/sec of each combination of saved results
+func WritePlot(w io.Writer, results []JsonResult) error {
+	return writePlot(w, results, nil)
+}
+
+// WriteRawPlot writes an SVG with the tokens/sec of each combination and, as the raw
+// requests are known, the measured against the predicted response time of each request
+func WriteRawPlot(w io.Writer, matrixResults []benchmark.MatrixResult) error {
+	var fitCharts []string
+	for i, matrixResult := range matrixResults {
+		if matrixResult.Error == nil {
+			fitCharts = append(fitCharts, renderFitChart(matrixResult, fmt.Sprintf("Combination #%d (%s)", i+1, formatParams(matrixResult))))
+		}
+	}
+	return writePlot(w, BuildJSONResults(matrixResults, Options{Precision: DefaultPrecision}), fitCharts)
+}
+
+// writePlot stacks the throughput charts of results, the given extra charts and a list
+// of the combinations into one SVG document
+func writePlot(w io.Writer, results []JsonResult, extraCharts []string) error {
+	var labels []string
+	var captions []string
+	shortPrompt := barSeries{Name: "Short context"}
+	longPrompt := barSeries{Name: "Long context"}
+	shortCompletion := barSeries{Name: "Short context"}
+	longCompletion := barSeries{Name: "Long context"}
+
+	for i, result := range results {
+		label := fmt.Sprintf("#%d", i+1)
+		if result.Error != "" {
+			captions = append(captions, fmt.Sprintf("%s: %s: failed: %s", label, joinParams(result.Params), result.Error))
+			continue
+		}
+		captions = append(captions, fmt.Sprintf("%s: %s", label, joinParams(result.Params)))
+
+		labels = append(labels, label)
+		shortPrompt.Values = append(shortPrompt.Values, result.ShortContextPromptTokensPerSec)
+		longPrompt.Values = append(longPrompt.Values, result.LongContextPromptTokensPerSec)
+		shortCompletion.Values = append(shortCompletion.Values, result.ShortContextCompletionTokensPerSec)
+		longCompletion.Values = append(longCompletion.Values, result.LongContextCompletionTokensPerSec)
+	}
+	if len(labels) == 0 {
+		return fmt.Errorf("no successful combinations to plot")
+	}
+
+	charts := append([]string{
+		renderBarChart("Prompt processing", "tokens/sec", labels, []barSeries{shortPrompt, longPrompt}),
+		renderBarChart("Completion generation", "tokens/sec", labels, []barSeries{shortCompletion, longCompletion}),
+	}, extraCharts...)
+
+	height := len(charts)*chartHeight + (len(captions)+1)*plotCaptionLineHeight
+	ew := &errWriter{w: w}
+	fmt.Fprintf(ew, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif">`+"\n",
+		chartWidth, height)
+	for i, chart := range charts {
+		fmt.Fprintf(ew, `<g transform="translate(0,%d)">`+"\n%s</g>\n", i*chartHeight, chart)
+	}
+	for i, caption := range captions {
+		fmt.Fprintf(ew, `<text x="%d" y="%d" font-size="12">%s</text>`+"\n",
+			chartMarginLeft, len(charts)*chartHeight+(i+1)*plotCaptionLineHeight, html.EscapeString(caption))
+	}
+	fmt.Fprintln(ew, "</svg>")
+	if ew.err != nil {
+		return fmt.Errorf("error writing plot: %v", ew.err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
+	"github.com/aifoundry-org/turtlenekko/internal/terminal"
+)
+
+// JsonQuickResult is a quick measurement in JSON format
+type JsonQuickResult struct {
+	URL                    string  `json:"url"`
+	PromptTokens           int     `json:"prompt_tokens"`
+	CompletionTokens       int     `json:"completion_tokens"`
+	TTFTMs                 float64 `json:"ttft_ms"`
+	ResponseTimeMs         float64 `json:"response_time_ms"`
+	PromptTokensPerSec     float64 `json:"prompt_tokens_per_sec"`
+	CompletionTokensPerSec float64 `json:"completion_tokens_per_sec"`
+}
+
+// FormatQuickJSON writes a quick measurement of the endpoint at url as JSON
+func FormatQuickJSON(w io.Writer, url string, result *benchmark.QuickResult) error {
+	jsonData, err := json.MarshalIndent(JsonQuickResult{
+		URL:                    url,
+		PromptTokens:           result.PromptTokens,
+		CompletionTokens:       result.CompletionTokens,
+		TTFTMs:                 math.Round(result.TTFTMs*100) / 100,
+		ResponseTimeMs:         math.Round(result.ResponseTimeMs*100) / 100,
+		PromptTokensPerSec:     math.Round(result.PromptTokensPerSec*100) / 100,
+		CompletionTokensPerSec: math.Round(result.CompletionTokensPerSec*100) / 100,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error creating JSON output: %v", err)
+	}
+
+	if _, err := fmt.Fprintln(w, string(jsonData)); err != nil {
+		return fmt.Errorf("error writing JSON output: %v", err)
+	}
+	return nil
+}
+
+// FormatQuickText writes a quick measurement of the endpoint at url as text
+func FormatQuickText(out io.Writer, url string, result *benchmark.QuickResult) error {
+	ew := &errWriter{w: out}
+	w := io.Writer(ew)
+
+	fmt.Fprintf(w, "%s: %s\n", terminal.BoldText("Endpoint"), url)
+	fmt.Fprintf(w, "%s: %.2f ms (%s prompt tokens/sec, %d prompt tokens)\n",
+		terminal.BoldText("Time to first token"),
+		result.TTFTMs,
+		terminal.GreenText(fmt.Sprintf("%.2f", result.PromptTokensPerSec)),
+		result.PromptTokens)
+	if result.CompletionTokensPerSec > 0 {
+		fmt.Fprintf(w, "%s: %s tokens/sec (%d tokens in %.2f ms)\n",
+			terminal.BoldText("Completion generation"),
+			terminal.GreenText(fmt.Sprintf("%.2f", result.CompletionTokensPerSec)),
+			result.CompletionTokens,
+			result.ResponseTimeMs)
+	} else {
+		fmt.Fprintf(w, "%s: %s\n", terminal.BoldText("Completion generation"), terminal.YellowText("No data"))
+	}
+
+	return ew.err
+}
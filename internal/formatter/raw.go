@@ -0,0 +1,68 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
+)
+
+// FormatRawCSV writes one CSV row per request across all combinations, the data the
+// completion time models are fitted to. Combinations are numbered from 1 in run order.
+func FormatRawCSV(out io.Writer, matrixResults []benchmark.MatrixResult) error {
+	ew := &errWriter{w: out}
+	w := io.Writer(ew)
+
+	// The energy column is only included if any combination measured power draw
+	showEnergy := false
+	for _, matrixResult := range matrixResults {
+		if matrixResult.EnergyJoules > 0 {
+			showEnergy = true
+			break
+		}
+	}
+
+	header := "combination,context,prompt_tokens,cached_prompt_tokens,completion_tokens,response_time_ms"
+	if showEnergy {
+		header += ",energy_joules"
+	}
+	fmt.Fprintln(w, header)
+
+	for i, matrixResult := range matrixResults {
+		for _, result := range matrixResult.Results {
+			if result == nil {
+				continue // Skip failed requests
+			}
+
+			row := fmt.Sprintf("%d,%s,%d,%d,%d,%.3f",
+				i+1,
+				result.Context,
+				result.PromptTokens,
+				result.CachedPromptTokens,
+				result.CompletionTokens,
+				float64(result.ResponseTime.Microseconds())/1000)
+			if showEnergy {
+				row += fmt.Sprintf(",%.3f", result.EnergyJoules)
+			}
+			fmt.Fprintln(w, row)
+		}
+	}
+
+	return ew.err
+}
+
+// FormatSweepCSV writes one CSV row per prompt sweep point across all combinations.
+// Combinations are numbered from 1 in run order.
+func FormatSweepCSV(out io.Writer, matrixResults []benchmark.MatrixResult) error {
+	ew := &errWriter{w: out}
+	w := io.Writer(ew)
+
+	fmt.Fprintln(w, "combination,prompt_tokens,response_time_ms,prompt_tokens_per_sec")
+	for i, matrixResult := range matrixResults {
+		for _, point := range matrixResult.PromptSweep {
+			fmt.Fprintf(w, "%d,%d,%.3f,%.2f\n", i+1, point.PromptTokens, point.ResponseTimeMs, point.PromptTokensPerSec)
+		}
+	}
+
+	return ew.err
+}
@@ -0,0 +1,27 @@
+package formatter
+
+// SchemaVersion is the version of the JsonResult shape, recorded as schema_version in
+// every JSON result. Bump it and add a changelog entry whenever a field is added,
+// renamed or changes meaning, so consumers of archived results can tell them apart.
+//
+// Changelog:
+//
+//	0: results written before schema_version was added; read like version 1
+//	1: schema_version added
+//	2: warmup_requests and warmup_stable added
+//	3: short/long_context_ttft_mean_ms, _itl_mean_ms, _itl_p99_ms and _itl_tokens_per_sec added
+//	4: prompt_token_targets added
+const SchemaVersion = 4
+
+// NewerSchemaVersion returns the highest schema version of results written by a newer
+// turtlenekko than this one, or 0 if all results have a known schema. Fields added
+// since are ignored when reading such results.
+func NewerSchemaVersion(results []JsonResult) int {
+	newest := 0
+	for _, result := range results {
+		if result.SchemaVersion > SchemaVersion && result.SchemaVersion > newest {
+			newest = result.SchemaVersion
+		}
+	}
+	return newest
+}
@@ -0,0 +1,92 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
+)
+
+// markdownEscaper escapes text in Markdown table cells
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\n", " ")
+
+// WriteMarkdownSummary writes a compact Markdown summary of the results to w, suitable
+// for a CI job summary such as GitHub Actions' $GITHUB_STEP_SUMMARY: the number of
+// failed combinations, the best LocalScore and a table of tokens/sec per combination
+func WriteMarkdownSummary(out io.Writer, matrixResults []benchmark.MatrixResult, opts Options) error {
+	ew := &errWriter{w: out}
+	w := io.Writer(ew)
+
+	results := BuildJSONResults(matrixResults, opts)
+
+	fmt.Fprintln(w, "## Turtlenekko benchmark")
+	fmt.Fprintln(w)
+
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	if failed == 0 {
+		fmt.Fprintf(w, "All %d combinations succeeded.\n", len(results))
+	} else {
+		fmt.Fprintf(w, "**%d of %d combinations failed.**\n", failed, len(results))
+	}
+	fmt.Fprintln(w)
+
+	// The best LocalScore and the combination that reached it
+	best := -1
+	for i, result := range results {
+		if result.LocalScore != nil && (best < 0 || *result.LocalScore > *results[best].LocalScore) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		fmt.Fprintf(w, "Best LocalScore: **%.2f** (%s)\n", *results[best].LocalScore, markdownEscaper.Replace(formatParams(matrixResults[best])))
+		fmt.Fprintln(w)
+	}
+
+	header := "| Parameters | Prompt t/s | Cached prompt t/s | Completion t/s | Long prompt t/s | Long completion t/s |"
+	separator := "|---|---:|---:|---:|---:|---:|"
+	if opts.ShowLocalScore {
+		header += " LocalScore |"
+		separator += "---:|"
+	}
+	fmt.Fprintln(w, header)
+	fmt.Fprintln(w, separator)
+
+	for i, result := range results {
+		params := markdownEscaper.Replace(formatParams(matrixResults[i]))
+		if params == "" {
+			params = fmt.Sprintf("#%d", i+1)
+		}
+
+		if result.Error != "" {
+			columns := 5
+			if opts.ShowLocalScore {
+				columns++
+			}
+			fmt.Fprintf(w, "| %s | failed: %s |%s\n", params, markdownEscaper.Replace(result.Error), strings.Repeat(" |", columns-1))
+			continue
+		}
+
+		row := fmt.Sprintf("| %s | %.2f | %.2f | %.2f | %.2f | %.2f |", params,
+			result.ShortContextPromptTokensPerSec,
+			result.ShortContextCachedPromptTokensPerSec,
+			result.ShortContextCompletionTokensPerSec,
+			result.LongContextPromptTokensPerSec,
+			result.LongContextCompletionTokensPerSec)
+		if opts.ShowLocalScore {
+			if result.LocalScore != nil {
+				row += fmt.Sprintf(" %.2f |", *result.LocalScore)
+			} else {
+				row += " - |"
+			}
+		}
+		fmt.Fprintln(w, row)
+	}
+
+	return ew.err
+}
@@ -0,0 +1,198 @@
+package formatter
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strings"
+)
+
+// Chart dimensions and colors shared by all SVG charts
+const (
+	chartWidth        = 800
+	chartHeight       = 360
+	chartMarginLeft   = 80
+	chartMarginRight  = 20
+	chartMarginTop    = 40
+	chartMarginBottom = 60
+)
+
+// chartColors is the palette used for chart series
+var chartColors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b"}
+
+// barSeries is a named set of values, one per bar group
+type barSeries struct {
+	Name   string
+	Values []float64
+}
+
+// scatterPoint is a single point of a scatter plot
+type scatterPoint struct {
+	X float64
+	Y float64
+}
+
+// scatterSeries is a named set of scatter plot points
+type scatterSeries struct {
+	Name   string
+	Points []scatterPoint
+}
+
+// niceMax rounds a maximum axis value up to a readable number
+func niceMax(value float64) float64 {
+	if value <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(value)))
+	for _, step := range []float64{1, 2, 2.5, 5, 10} {
+		if step*magnitude >= value {
+			return step * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// writeAxes draws the chart title, axes, grid lines and y-axis tick labels
+func writeAxes(sb *strings.Builder, title string, xLabel string, yLabel string, yMax float64) {
+	plotWidth := chartWidth - chartMarginLeft - chartMarginRight
+	plotHeight := chartHeight - chartMarginTop - chartMarginBottom
+
+	fmt.Fprintf(sb, `<text x="%d" y="20" text-anchor="middle" font-weight="bold">%s</text>`+"\n",
+		chartWidth/2, html.EscapeString(title))
+
+	for i := 0; i <= 5; i++ {
+		y := float64(chartMarginTop) + float64(plotHeight)*(1-float64(i)/5)
+		fmt.Fprintf(sb, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ddd"/>`+"\n",
+			chartMarginLeft, y, chartMarginLeft+plotWidth, y)
+		fmt.Fprintf(sb, `<text x="%d" y="%.1f" text-anchor="end" font-size="11">%.6g</text>`+"\n",
+			chartMarginLeft-5, y+4, yMax*float64(i)/5)
+	}
+
+	fmt.Fprintf(sb, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#333"/>`+"\n",
+		chartMarginLeft, chartMarginTop, chartMarginLeft, chartMarginTop+plotHeight)
+	fmt.Fprintf(sb, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#333"/>`+"\n",
+		chartMarginLeft, chartMarginTop+plotHeight, chartMarginLeft+plotWidth, chartMarginTop+plotHeight)
+
+	fmt.Fprintf(sb, `<text x="%d" y="%d" text-anchor="middle" font-size="12">%s</text>`+"\n",
+		chartMarginLeft+plotWidth/2, chartHeight-10, html.EscapeString(xLabel))
+	fmt.Fprintf(sb, `<text x="15" y="%d" text-anchor="middle" font-size="12" transform="rotate(-90 15 %d)">%s</text>`+"\n",
+		chartMarginTop+plotHeight/2, chartMarginTop+plotHeight/2, html.EscapeString(yLabel))
+}
+
+// writeLegend draws a legend for the named series in the top right corner
+func writeLegend(sb *strings.Builder, names []string) {
+	for i, name := range names {
+		x := chartWidth - chartMarginRight - 180
+		y := chartMarginTop + 5 + i*16
+		fmt.Fprintf(sb, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`+"\n",
+			x, y, chartColors[i%len(chartColors)])
+		fmt.Fprintf(sb, `<text x="%d" y="%d" font-size="11">%s</text>`+"\n",
+			x+15, y+9, html.EscapeString(name))
+	}
+}
+
+// renderBarChart renders a grouped bar chart as an SVG document
+func renderBarChart(title string, yLabel string, labels []string, series []barSeries) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif">`+"\n",
+		chartWidth, chartHeight)
+
+	yMax := 0.0
+	for _, s := range series {
+		for _, v := range s.Values {
+			yMax = math.Max(yMax, v)
+		}
+	}
+	yMax = niceMax(yMax)
+
+	writeAxes(&sb, title, "", yLabel, yMax)
+
+	plotWidth := float64(chartWidth - chartMarginLeft - chartMarginRight)
+	plotHeight := float64(chartHeight - chartMarginTop - chartMarginBottom)
+
+	if len(labels) > 0 && len(series) > 0 {
+		groupWidth := plotWidth / float64(len(labels))
+		barWidth := groupWidth * 0.8 / float64(len(series))
+
+		for i, label := range labels {
+			groupX := float64(chartMarginLeft) + groupWidth*float64(i) + groupWidth*0.1
+			for j, s := range series {
+				if i >= len(s.Values) {
+					continue
+				}
+				barHeight := plotHeight * s.Values[i] / yMax
+				fmt.Fprintf(&sb, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %.2f</title></rect>`+"\n",
+					groupX+barWidth*float64(j), float64(chartMarginTop)+plotHeight-barHeight, barWidth, barHeight,
+					chartColors[j%len(chartColors)], html.EscapeString(s.Name), s.Values[i])
+			}
+			fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" text-anchor="middle" font-size="11">%s</text>`+"\n",
+				float64(chartMarginLeft)+groupWidth*(float64(i)+0.5), float64(chartMarginTop)+plotHeight+15, html.EscapeString(label))
+		}
+	}
+
+	var names []string
+	for _, s := range series {
+		names = append(names, s.Name)
+	}
+	writeLegend(&sb, names)
+
+	sb.WriteString("</svg>\n")
+	return sb.String()
+}
+
+// renderScatter renders a scatter plot as an SVG document. If diagonal is true, the
+// y = x line is drawn, which is where points lie when the fitted model predicts them exactly.
+func renderScatter(title string, xLabel string, yLabel string, series []scatterSeries, diagonal bool) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif">`+"\n",
+		chartWidth, chartHeight)
+
+	xMax, yMax := 0.0, 0.0
+	for _, s := range series {
+		for _, p := range s.Points {
+			xMax = math.Max(xMax, p.X)
+			yMax = math.Max(yMax, p.Y)
+		}
+	}
+	if diagonal {
+		xMax = math.Max(xMax, yMax)
+		yMax = xMax
+	}
+	xMax = niceMax(xMax)
+	yMax = niceMax(yMax)
+
+	writeAxes(&sb, title, xLabel, yLabel, yMax)
+
+	plotWidth := float64(chartWidth - chartMarginLeft - chartMarginRight)
+	plotHeight := float64(chartHeight - chartMarginTop - chartMarginBottom)
+
+	// x-axis tick labels
+	for i := 0; i <= 5; i++ {
+		x := float64(chartMarginLeft) + plotWidth*float64(i)/5
+		fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" text-anchor="middle" font-size="11">%.6g</text>`+"\n",
+			x, float64(chartMarginTop)+plotHeight+15, xMax*float64(i)/5)
+	}
+
+	if diagonal {
+		fmt.Fprintf(&sb, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%d" stroke="#999" stroke-dasharray="4"/>`+"\n",
+			chartMarginLeft, float64(chartMarginTop)+plotHeight,
+			float64(chartMarginLeft)+plotWidth*math.Min(1, yMax/xMax), chartMarginTop)
+	}
+
+	for i, s := range series {
+		for _, p := range s.Points {
+			fmt.Fprintf(&sb, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s" fill-opacity="0.7"><title>%s: %.2f, %.2f</title></circle>`+"\n",
+				float64(chartMarginLeft)+plotWidth*p.X/xMax, float64(chartMarginTop)+plotHeight*(1-p.Y/yMax),
+				chartColors[i%len(chartColors)], html.EscapeString(s.Name), p.X, p.Y)
+		}
+	}
+
+	var names []string
+	for _, s := range series {
+		names = append(names, s.Name)
+	}
+	writeLegend(&sb, names)
+
+	sb.WriteString("</svg>\n")
+	return sb.String()
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
+	"github.com/aifoundry-org/turtlenekko/internal/config"
+	"github.com/aifoundry-org/turtlenekko/internal/formatter"
+)
+
+// maxConfigSize limits the size of a configuration posted to the server
+const maxConfigSize = 1 << 20
+
+// DefaultAddr is the address the server listens on by default, only reachable locally
+const DefaultAddr = "127.0.0.1:9000"
+
+// commandDrivers are the drivers that run shell commands from the configuration
+var commandDrivers = []string{"local_cmd", "ssh"}
+
+// commandParams are the parameters that run commands or programs from the configuration,
+// or write files to a path it names
+var commandParams = []string{
+	"setup_cmd", "teardown_cmd", "launch_cmd", "binary_path", "extra_args",
+	"power_cmd", "token_cmd", "post_cmd", "info_cmd", "capture_dir",
+}
+
+// Server exposes benchmarks over HTTP. Only one benchmark runs at a time, since
+// drivers start servers on fixed ports; concurrent requests are rejected.
+// The API has no authentication, so configurations running commands are rejected
+// unless AllowCommands is set.
+type Server struct {
+	// MaxCombinations limits the number of combinations of a posted matrix, 0 for no limit
+	MaxCombinations int
+	// AllowCommands accepts configurations whose driver or parameters run commands
+	AllowCommands bool
+	// Version is the turtlenekko version recorded in the results, empty to leave it out
+	Version string
+
+	running sync.Mutex
+}
+
+// New creates a new benchmark server
+func New() *Server {
+	return &Server{MaxCombinations: benchmark.DefaultMaxCombinations}
+}
+
+// Handler returns the HTTP handler serving the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/benchmark", s.handleBenchmark)
+	return mux
+}
+
+// ListenAndServe serves the API on the given address
+func (s *Server) ListenAndServe(addr string) error {
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	slog.Info("Benchmark server listening", "component", "server", "addr", addr)
+	return httpServer.ListenAndServe()
+}
+
+// handleHealth reports that the server is up
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintln(w, "ok")
+}
+
+// handleBenchmark runs the matrix benchmark for the posted configuration and returns the JSON results
+func (s *Server) handleBenchmark(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	opts := formatter.DefaultOptions()
+	opts.Version = s.Version
+	if value := r.URL.Query().Get("localscore"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid localscore value: %s", value))
+			return
+		}
+		opts.ShowLocalScore = parsed
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxConfigSize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("error reading configuration: %v", err))
+		return
+	}
+
+	if validationErrors := config.Validate(data); len(validationErrors) > 0 {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid configuration: %v", validationErrors[0]))
+		return
+	}
+
+	cfg, err := config.Parse(data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !s.AllowCommands {
+		if err := checkNoCommands(cfg); err != nil {
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+	}
+
+	if !s.running.TryLock() {
+		writeError(w, http.StatusConflict, "a benchmark is already running")
+		return
+	}
+	defer s.running.Unlock()
+
+	slog.Info("Starting benchmark", "component", "server", "driver", cfg.Driver, "remote_addr", r.RemoteAddr)
+
+	baseParams := map[string]interface{}{"max_combinations": s.MaxCombinations}
+	if cfg.Warmup != nil {
+		for k, v := range cfg.Warmup.Params() {
+			baseParams[k] = v
+		}
+	}
+	matrixResults, err := benchmark.RunMatrix(cfg.Driver, baseParams, cfg.Matrix, cfg.Combinations, benchmark.CombinationFilter{}, nil, benchmark.Handlers{})
+	if err != nil {
+		slog.Error("Matrix benchmark failed", "component", "server", "error", err)
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("matrix benchmark failed: %v", err))
+		return
+	}
+
+	slog.Info("Benchmark finished", "component", "server", "combinations", len(matrixResults))
+
+	writeJSON(w, http.StatusOK, formatter.BuildJSONResults(matrixResults, opts))
+}
+
+// checkNoCommands returns an error if the configuration's driver or parameters run commands,
+// or read local files: a prompt_corpus that isn't built in is read from a file and sent to
+// the configured URL
+func checkNoCommands(cfg *config.Config) error {
+	if slices.Contains(commandDrivers, cfg.Driver) {
+		return fmt.Errorf("driver %s runs commands, start the server with --allow-commands to accept it", cfg.Driver)
+	}
+	for _, key := range commandParams {
+		_, inMatrix := cfg.Matrix[key]
+		inCombinations := slices.ContainsFunc(cfg.Combinations, func(combination map[string]interface{}) bool {
+			_, ok := combination[key]
+			return ok
+		})
+		if inMatrix || inCombinations {
+			return fmt.Errorf("parameter %s runs commands, start the server with --allow-commands to accept it", key)
+		}
+	}
+	for _, value := range paramValues(cfg, "prompt_corpus") {
+		if corpus := fmt.Sprint(value); !benchmark.IsBuiltinCorpus(corpus) {
+			return fmt.Errorf("prompt_corpus %s reads a local file, start the server with --allow-commands to accept it", corpus)
+		}
+	}
+	return nil
+}
+
+// paramValues returns the values a parameter takes in the matrix and the combinations
+func paramValues(cfg *config.Config, key string) []interface{} {
+	values := append([]interface{}{}, cfg.Matrix[key].Values...)
+	for _, combination := range cfg.Combinations {
+		if value, ok := combination[key]; ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		slog.Error("Error writing response", "component", "server", "error", err)
+	}
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aifoundry-org/turtlenekko/internal/config"
+)
+
+func TestCheckNoCommands(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		config string
+		want   string // part of the error, empty if the configuration is accepted
+	}{
+		{"mock driver", "driver: mock\nmatrix:\n  model: [a]\n", ""},
+		{"built-in corpus", "driver: mock\nmatrix:\n  prompt_corpus: [lorem, code]\n", ""},
+		{"command driver", "driver: local_cmd\nmatrix:\n  url: [http://localhost:8000]\n", "driver local_cmd"},
+		{"ssh driver", "driver: ssh\nmatrix:\n  host: [gpu-box]\n", "driver ssh"},
+		{"command parameter", "driver: mock\nmatrix:\n  post_cmd: [id]\n", "parameter post_cmd"},
+		{"llama.cpp binary", "driver: llamacpp\nmatrix:\n  binary_path: [/bin/sh]\n", "parameter binary_path"},
+		{"capture_dir in a combination", "driver: mock\ncombinations:\n  - capture_dir: /tmp\n", "parameter capture_dir"},
+		{"corpus file", "driver: mock\nmatrix:\n  prompt_corpus: [lorem, /etc/passwd]\n", "prompt_corpus /etc/passwd"},
+		{"corpus file in a combination", "driver: mock\ncombinations:\n  - prompt_corpus: secrets.txt\n", "prompt_corpus secrets.txt"},
+	} {
+		cfg, err := config.Parse([]byte(tt.config))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		err = checkNoCommands(cfg)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: rejected: %v", tt.name, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: error %v, want one naming %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestHandleBenchmarkRejectsCommands(t *testing.T) {
+	srv := New()
+	body := "driver: local_cmd\nmatrix:\n  url: [http://localhost:8000]\n  setup_cmd: [touch /tmp/x]\n"
+
+	recorder := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/benchmark", strings.NewReader(body)))
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d: %s", recorder.Code, http.StatusForbidden, recorder.Body)
+	}
+}
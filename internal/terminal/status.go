@@ -0,0 +1,81 @@
+package terminal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// clearLine returns the cursor to the start of the line and erases it
+const clearLine = "\r\033[K"
+
+// IsTerminal reports whether f is connected to a terminal
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// Width returns the width of the terminal f is connected to, or 80 if it is unknown
+func Width(f *os.File) int {
+	width, _, err := term.GetSize(int(f.Fd()))
+	if err != nil || width <= 0 {
+		return 80
+	}
+	return width
+}
+
+// StatusLine is an io.Writer that keeps a status line below everything written through
+// it: the status is erased before each write and redrawn after it. Use it as the log
+// output so log lines don't get mixed up with the status. The status may span several
+// lines, each of which must fit the terminal width.
+type StatusLine struct {
+	mu     sync.Mutex
+	out    io.Writer
+	status string
+}
+
+// NewStatusLine creates a StatusLine writing to out, which should be a terminal
+func NewStatusLine(out io.Writer) *StatusLine {
+	return &StatusLine{out: out}
+}
+
+// Write writes p above the status line
+func (s *StatusLine) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.status == "" {
+		return s.out.Write(p)
+	}
+
+	io.WriteString(s.out, s.clear())
+	n, err := s.out.Write(p)
+	io.WriteString(s.out, s.status)
+	return n, err
+}
+
+// Set replaces the status line
+func (s *StatusLine) Set(status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	io.WriteString(s.out, s.clear()+status)
+	s.status = status
+}
+
+// clear returns the escape sequence that erases the current status
+func (s *StatusLine) clear() string {
+	if lines := strings.Count(s.status, "\n"); lines > 0 {
+		// Move up to the first line of the status and erase to the end of the screen
+		return fmt.Sprintf("\r\033[%dA\033[J", lines)
+	}
+	return clearLine
+}
+
+// Clear erases the status line
+func (s *StatusLine) Clear() {
+	s.Set("")
+}
@@ -38,8 +38,20 @@ const (
 	BgWhite   = "\033[47m"
 )
 
+// colorOverride forces color output on or off when set
+var colorOverride *bool
+
+// SetColor forces color output on or off, regardless of terminal detection
+func SetColor(enabled bool) {
+	colorOverride = &enabled
+}
+
 // SupportsColor determines if the terminal supports color output
 func SupportsColor() bool {
+	if colorOverride != nil {
+		return *colorOverride
+	}
+
 	// Check if NO_COLOR environment variable is set (standard for disabling color)
 	if _, exists := os.LookupEnv("NO_COLOR"); exists {
 		return false
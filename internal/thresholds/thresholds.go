@@ -0,0 +1,126 @@
+package thresholds
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
+	"github.com/aifoundry-org/turtlenekko/internal/formatter"
+	"github.com/aifoundry-org/turtlenekko/internal/types"
+)
+
+// Result is the outcome of checking one threshold against one combination
+type Result struct {
+	Combination int    // 1-based index of the matrix combination
+	Params      string // output parameters of the combination
+	Metric      string
+	Value       float64
+	Missing     bool // the combination failed or did not report the metric
+	Threshold   types.Threshold
+	Passed      bool
+}
+
+// String describes the check, e.g. "#1 (model=llama3): localscore_estimate = 7.10 (min 8)"
+func (r Result) String() string {
+	var bounds []string
+	if r.Threshold.Min != nil {
+		bounds = append(bounds, fmt.Sprintf("min %g", *r.Threshold.Min))
+	}
+	if r.Threshold.Max != nil {
+		bounds = append(bounds, fmt.Sprintf("max %g", *r.Threshold.Max))
+	}
+
+	value := fmt.Sprintf("%.2f", r.Value)
+	if r.Missing {
+		value = "no value"
+	}
+
+	return fmt.Sprintf("#%d (%s): %s = %s (%s)", r.Combination, r.Params, r.Metric, value, strings.Join(bounds, ", "))
+}
+
+// Metrics returns the names of the numeric result metrics thresholds can refer to
+func Metrics() []string {
+	var metrics []string
+	t := reflect.TypeOf(formatter.JsonResult{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		switch field.Type.Kind() {
+		case reflect.Float64, reflect.Int, reflect.Int64, reflect.Pointer:
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			metrics = append(metrics, name)
+		}
+	}
+	return metrics
+}
+
+// Validate checks that every threshold refers to a known metric
+func Validate(thresholds map[string]types.Threshold) error {
+	known := make(map[string]bool)
+	for _, metric := range Metrics() {
+		known[metric] = true
+	}
+	for metric, threshold := range thresholds {
+		if !known[metric] {
+			return fmt.Errorf("threshold on unknown metric %q (available: %s)", metric, strings.Join(Metrics(), ", "))
+		}
+		if threshold.Min == nil && threshold.Max == nil {
+			return fmt.Errorf("threshold on %q sets neither min nor max", metric)
+		}
+	}
+	return nil
+}
+
+// Check evaluates the thresholds against every combination. A combination that failed
+// or did not report a metric fails its thresholds.
+func Check(matrixResults []benchmark.MatrixResult, thresholds map[string]types.Threshold) ([]Result, error) {
+	var metrics []string
+	for metric := range thresholds {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+
+	var results []Result
+	for i, jsonResult := range formatter.BuildJSONResults(matrixResults, formatter.DefaultOptions()) {
+		// Look up metrics by their output name
+		data, err := json.Marshal(jsonResult)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding results: %v", err)
+		}
+		var values map[string]interface{}
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("error decoding results: %v", err)
+		}
+
+		var pairs []string
+		for k, v := range jsonResult.Params {
+			pairs = append(pairs, fmt.Sprintf("%s=%v", k, v))
+		}
+		sort.Strings(pairs)
+
+		for _, metric := range metrics {
+			threshold := thresholds[metric]
+			result := Result{
+				Combination: i + 1,
+				Params:      strings.Join(pairs, ", "),
+				Metric:      metric,
+				Threshold:   threshold,
+			}
+
+			value, ok := values[metric].(float64)
+			if !ok || jsonResult.Error != "" {
+				result.Missing = true
+			} else {
+				result.Value = value
+				result.Passed = (threshold.Min == nil || value >= *threshold.Min) &&
+					(threshold.Max == nil || value <= *threshold.Max)
+			}
+
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
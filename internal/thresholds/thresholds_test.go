@@ -0,0 +1,94 @@
+package thresholds
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
+	"github.com/aifoundry-org/turtlenekko/internal/types"
+)
+
+// bound returns a pointer to a threshold bound
+func bound(v float64) *float64 {
+	return &v
+}
+
+func TestCheck(t *testing.T) {
+	matrixResults := []benchmark.MatrixResult{
+		{Params: map[string]interface{}{"threads": 8}, OutputFlags: map[string]bool{"threads": true}, LocalScore: bound(20),
+			ShortContextModelFit: &benchmark.ModelFitResult{RSquared: 0.99, AdjustedRSquared: 0.98}},
+		{Params: map[string]interface{}{"threads": 16}, OutputFlags: map[string]bool{"threads": true}, LocalScore: bound(25),
+			ShortContextModelFit: &benchmark.ModelFitResult{RSquared: 0.5, AdjustedRSquared: 0.4}},
+		{Params: map[string]interface{}{"threads": 32}, OutputFlags: map[string]bool{"threads": true}},
+		{Params: map[string]interface{}{"threads": 64}, OutputFlags: map[string]bool{"threads": true}, LocalScore: bound(30),
+			Error: fmt.Errorf("out of memory")},
+	}
+	limits := map[string]types.Threshold{
+		"localscore_estimate":     {Min: bound(21)},
+		"short_context_r_squared": {Min: bound(0.9), Max: bound(1)},
+	}
+
+	results, err := Check(matrixResults, limits)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	for _, tt := range []struct {
+		combination int
+		metric      string
+		passed      bool
+		missing     bool
+	}{
+		{1, "localscore_estimate", false, false}, // below the min
+		{1, "short_context_r_squared", true, false},
+		{2, "localscore_estimate", true, false},
+		{2, "short_context_r_squared", false, false},
+		{3, "localscore_estimate", false, true}, // not reported
+		{4, "localscore_estimate", false, true}, // the combination failed
+	} {
+		var found *Result
+		for i := range results {
+			if results[i].Combination == tt.combination && results[i].Metric == tt.metric {
+				found = &results[i]
+			}
+		}
+		switch {
+		case found == nil:
+			t.Errorf("#%d %s: not checked", tt.combination, tt.metric)
+		case found.Passed != tt.passed || found.Missing != tt.missing:
+			t.Errorf("#%d %s: passed = %v, missing = %v, want %v, %v", tt.combination, tt.metric, found.Passed, found.Missing, tt.passed, tt.missing)
+		}
+	}
+	if len(results) != len(matrixResults)*len(limits) {
+		t.Errorf("got %d checks, want %d", len(results), len(matrixResults)*len(limits))
+	}
+
+	if got := results[0].String(); got != "#1 (threads=8): localscore_estimate = 20.00 (min 21)" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := results[4].String(); !strings.Contains(got, "no value") {
+		t.Errorf("String() = %q, want the missing value", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		thresholds map[string]types.Threshold
+		want       string // part of the error, empty if the thresholds are valid
+	}{
+		{"known metric", map[string]types.Threshold{"localscore_estimate": {Min: bound(10)}}, ""},
+		{"max only", map[string]types.Threshold{"short_context_latency_p90_ms": {Max: bound(500)}}, ""},
+		{"unknown metric", map[string]types.Threshold{"localscore": {Min: bound(10)}}, `unknown metric "localscore"`},
+		{"no bounds", map[string]types.Threshold{"localscore_estimate": {}}, "neither min nor max"},
+	} {
+		err := Validate(tt.thresholds)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: error %v, want one containing %q", tt.name, err, tt.want)
+		}
+	}
+}
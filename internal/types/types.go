@@ -1,7 +1,50 @@
 package types
 
-// ParameterConfig represents a parameter configuration with attributes
+// OutputFormats are the output formats a parameter can be shown in or hidden from
+// individually with the object form of its output flag
+var OutputFormats = []string{"text", "csv", "json", "influx"}
+
+// ParameterConfig represents a parameter configuration with attributes.
+// Values keep their native YAML types (string, int, float64 or bool).
 type ParameterConfig struct {
-	Values []string `json:"values" yaml:"values"`
-	Output bool     `json:"output,omitempty" yaml:"output,omitempty"`
+	Values []interface{} `json:"values" yaml:"values"`
+	Output bool          `json:"output,omitempty" yaml:"output,omitempty"`
+	// FormatOutput overrides Output for individual output formats, e.g. to hide a wide
+	// parameter from the CSV but keep it in the JSON
+	FormatOutput map[string]bool `json:"format_output,omitempty" yaml:"format_output,omitempty"`
+	// Driver is false for parameters that only affect the benchmark, not the driver setup
+	Driver bool `json:"driver,omitempty" yaml:"driver,omitempty"`
+}
+
+// WarmupConfig configures the warmup requests sent before the measurements. Unset
+// fields keep the parameter defaults.
+type WarmupConfig struct {
+	Mode      string   `json:"mode,omitempty" yaml:"mode,omitempty"`           // "fixed" or "stable"
+	Count     *int     `json:"count,omitempty" yaml:"count,omitempty"`         // warmup requests in fixed mode
+	Tolerance *float64 `json:"tolerance,omitempty" yaml:"tolerance,omitempty"` // relative difference considered stable
+	Max       *int     `json:"max,omitempty" yaml:"max,omitempty"`             // most warmup requests in stable mode
+}
+
+// Params returns the benchmark parameters the warmup configuration sets
+func (w WarmupConfig) Params() map[string]interface{} {
+	params := make(map[string]interface{})
+	if w.Mode != "" {
+		params["warmup_mode"] = w.Mode
+	}
+	if w.Count != nil {
+		params["warmup_count"] = *w.Count
+	}
+	if w.Tolerance != nil {
+		params["warmup_tolerance"] = *w.Tolerance
+	}
+	if w.Max != nil {
+		params["warmup_max"] = *w.Max
+	}
+	return params
+}
+
+// Threshold is the accepted range of a result metric. Unset bounds are not checked.
+type Threshold struct {
+	Min *float64 `json:"min,omitempty" yaml:"min,omitempty"`
+	Max *float64 `json:"max,omitempty" yaml:"max,omitempty"`
 }
@@ -0,0 +1,27 @@
+// Package benchmark runs Turtlenekko measurements from other Go programs
+package benchmark
+
+import (
+	"context"
+
+	"github.com/aifoundry-org/turtlenekko/internal/benchmark"
+)
+
+// Options configures BenchmarkURL
+type Options = benchmark.Options
+
+// MatrixResult holds the measurements, model fits and estimated LocalScore of a run
+type MatrixResult = benchmark.MatrixResult
+
+// ModelFitResult holds the fitted rates of one context
+type ModelFitResult = benchmark.ModelFitResult
+
+// CompletionResult holds the token counts and timing of one request
+type CompletionResult = benchmark.CompletionResult
+
+// BenchmarkURL runs the scaling benchmark against an already running chat completion
+// endpoint, without a driver or parameter matrix. The result holds the short and long
+// context fits and the estimated LocalScore. Cancelling ctx cancels the requests.
+func BenchmarkURL(ctx context.Context, url string, model string, opts Options) (*MatrixResult, error) {
+	return benchmark.BenchmarkURL(ctx, url, model, opts)
+}